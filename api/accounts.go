@@ -1,38 +1,67 @@
 package api
 
 import (
-	"context"
-	"database/sql"
-	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/julkar-naim/simple-bank/apperr"
 	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/money"
+	"github.com/julkar-naim/simple-bank/token"
 	"net/http"
+	"time"
 )
 
+type accountResponse struct {
+	ID        int64        `json:"id"`
+	Owner     string       `json:"owner"`
+	Balance   money.Amount `json:"balance"`
+	Currency  string       `json:"currency"`
+	CreatedAt time.Time    `json:"created_at"`
+	IsFrozen  bool         `json:"is_frozen"`
+}
+
+func newAccountResponse(account db.Account) accountResponse {
+	return accountResponse{
+		ID:        account.ID,
+		Owner:     account.Owner,
+		Balance:   money.New(account.Balance, account.Currency),
+		Currency:  account.Currency,
+		CreatedAt: account.CreatedAt,
+		IsFrozen:  account.IsFrozen,
+	}
+}
+
+func newAccountResponses(accounts []db.Account) []accountResponse {
+	rsp := make([]accountResponse, 0, len(accounts))
+	for _, account := range accounts {
+		rsp = append(rsp, newAccountResponse(account))
+	}
+	return rsp
+}
+
 type createAccountRequest struct {
-	Owner    string `json:"owner" binding:"required"`
-	Currency string `json:"currency" binding:"required,oneof=USD EUR CAD"`
+	Currency string `json:"currency" binding:"required,currency"`
 }
 
 func (server *Server) createAccount(ctx *gin.Context) {
 	var req createAccountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		renderError(ctx, bindingError(err))
 		return
 	}
 
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	arg := db.CreateAccountParams{
-		Owner:    req.Owner,
+		Owner:    authPayload.Username,
 		Currency: req.Currency,
 		Balance:  0,
 	}
-	account, err := server.store.CreateAccount(context.Background(), arg)
+	account, err := server.store.CreateAccountTx(ctx.Request.Context(), arg)
 
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		renderError(ctx, apperr.Translate(err, "account"))
 		return
 	}
-	ctx.JSON(http.StatusOK, account)
+	renderAmounts(ctx, newAccountResponse(account), account)
 }
 
 type getAccountRequest struct {
@@ -42,20 +71,15 @@ type getAccountRequest struct {
 func (server *Server) getAccount(ctx *gin.Context) {
 	var req getAccountRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		renderError(ctx, bindingError(err))
 		return
 	}
 
-	account, err := server.store.GetAccount(context.Background(), req.ID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	account, ok := server.ownedAccount(ctx, req.ID)
+	if !ok {
 		return
 	}
-	ctx.JSON(http.StatusOK, account)
+	renderAmounts(ctx, newAccountResponse(account), account)
 }
 
 type getAccountListRequest struct {
@@ -66,50 +90,70 @@ type getAccountListRequest struct {
 func (server *Server) getAccountList(ctx *gin.Context) {
 	var req getAccountListRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		renderError(ctx, bindingError(err))
 		return
 	}
 
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	arg := db.ListAccountsParams{
+		Owner:  authPayload.Username,
 		Limit:  req.PageSize,
 		Offset: (req.PageID - 1) * req.PageSize,
 	}
 
-	accounts, err := server.store.ListAccounts(context.Background(), arg)
+	accounts, err := server.store.ListAccounts(ctx.Request.Context(), arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		renderError(ctx, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, accounts)
+	renderAmounts(ctx, newAccountResponses(accounts), accounts)
 }
 
 type updateAccountRequest struct {
-	ID       int64  `json:"id" binding:"required"`
-	Owner    string `json:"owner" binding:"required,min=4"`
-	Balance  int64  `json:"balance" binding:"required"`
-	Currency string `json:"currency" binding:"required,oneof=USD EUR CAD"`
+	ID    int64  `json:"id" binding:"required"`
+	Owner string `json:"owner" binding:"required,min=4"`
+	// Balance is a money object, or a legacy integer in minor units of Currency
+	Balance  amountParam `json:"balance"`
+	Currency string      `json:"currency" binding:"omitempty,currency"`
 }
 
 func (server *Server) updateAccount(ctx *gin.Context) {
 	var req updateAccountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		renderError(ctx, bindingError(err))
 		return
 	}
 
-	arg := db.UpdateAccountParams{
-		ID:       req.ID,
-		Owner:    req.Owner,
-		Balance:  req.Balance,
-		Currency: req.Currency,
+	balance, err := req.Balance.resolve("balance", req.Currency)
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	// an account's currency never changes once it has been created
+	account, err := server.store.GetAccount(ctx.Request.Context(), req.ID)
+	if err != nil {
+		renderError(ctx, apperr.Translate(err, "account"))
+		return
+	}
+	if account.Currency != balance.Currency() {
+		renderError(ctx, apperr.Newf(apperr.CodeInvalidArgument, "account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, balance.Currency()).
+			WithDetail("account_id", account.ID))
+		return
+	}
+
+	arg := db.UpdateAccountTxParams{
+		ID:      req.ID,
+		Owner:   req.Owner,
+		Balance: balance.Value(),
 	}
 
-	account, err := server.store.UpdateAccount(context.Background(), arg)
+	account, err = server.store.UpdateAccountTx(ctx.Request.Context(), arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		renderError(ctx, apperr.Translate(err, "account"))
 		return
 	}
-	ctx.JSON(http.StatusOK, account)
+	renderAmounts(ctx, newAccountResponse(account), account)
 }
 
 type deleteAccountRequest struct {
@@ -119,13 +163,35 @@ type deleteAccountRequest struct {
 func (server *Server) deleteAccount(ctx *gin.Context) {
 	var req deleteAccountRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	if _, ok := server.ownedAccount(ctx, req.ID); !ok {
 		return
 	}
-	err := server.store.DeleteAccount(context.Background(), req.ID)
+
+	err := server.store.DeleteAccountTx(ctx.Request.Context(), req.ID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		renderError(ctx, apperr.Translate(err, "account"))
 		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{"message": "account deleted!"})
 }
+
+// ownedAccount loads the account and checks it belongs to the authenticated user,
+// writing the error response itself when it doesn't
+func (server *Server) ownedAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	account, err := server.store.GetAccount(ctx.Request.Context(), accountID)
+	if err != nil {
+		renderError(ctx, apperr.Translate(err, "account"))
+		return account, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		renderError(ctx, apperr.New(apperr.CodePermissionDenied, "account doesn't belong to the authenticated user"))
+		return account, false
+	}
+	return account, true
+}
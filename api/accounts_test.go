@@ -5,30 +5,45 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"github.com/julkar-naim/simple-bank/apperr"
 	mockdb "github.com/julkar-naim/simple-bank/db/mock"
 	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/money"
+	"github.com/julkar-naim/simple-bank/token"
 	"github.com/julkar-naim/simple-bank/util"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestCreateAccountAPI(t *testing.T) {
+	user, _ := randomUser(t)
 
 	testCases := []struct {
 		name          string
 		RequestBody   createAccountRequest
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 		buildStub     func(store mockdb.MockStore, ctrl *gomock.Controller, account db.Account)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, account db.Account)
 	}{
 		{
 			"OK",
 			randomCreateAccountData(),
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
+			},
 			func(store mockdb.MockStore, ctrl *gomock.Controller, account db.Account) {
-				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
+				arg := db.CreateAccountParams{
+					Owner:    account.Owner,
+					Currency: account.Currency,
+					Balance:  0,
+				}
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Eq(arg)).
 					Times(1).
 					Return(account, nil)
 			},
@@ -37,25 +52,76 @@ func TestCreateAccountAPI(t *testing.T) {
 				requireBodyMatchAccount(t, recorder.Body, account)
 			},
 		},
+		{
+			"NoAuthorization",
+			randomCreateAccountData(),
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			func(store mockdb.MockStore, ctrl *gomock.Controller, account db.Account) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder, account db.Account) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
 		{
 			"BadRequest",
 			createAccountRequest{
-				Owner:    "",
 				Currency: "xyz",
 			},
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
+			},
 			func(store mockdb.MockStore, ctrl *gomock.Controller, account db.Account) {
-				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			func(t *testing.T, recorder *httptest.ResponseRecorder, account db.Account) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
 			},
 		},
+		{
+			"OwnerNotFound",
+			randomCreateAccountData(),
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
+			},
+			func(store mockdb.MockStore, ctrl *gomock.Controller, account db.Account) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, &pq.Error{Code: "23503"})
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder, account db.Account) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				requireBodyMatchError(t, recorder, apperr.CodeFailedPrecondition)
+			},
+		},
+		{
+			"DuplicateCurrency",
+			randomCreateAccountData(),
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
+			},
+			func(store mockdb.MockStore, ctrl *gomock.Controller, account db.Account) {
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, &pq.Error{Code: "23505"})
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder, account db.Account) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				rsp := requireBodyMatchError(t, recorder, apperr.CodeAlreadyExists)
+				require.Equal(t, "account already exists", rsp.Message)
+			},
+		},
 		{
 			"InternalServer",
 			randomCreateAccountData(),
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
+			},
 			func(store mockdb.MockStore, ctrl *gomock.Controller, account db.Account) {
-				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
+				store.EXPECT().CreateAccountTx(gomock.Any(), gomock.Any()).
 					Times(1).
 					Return(db.Account{}, sql.ErrConnDone)
 			},
@@ -72,7 +138,7 @@ func TestCreateAccountAPI(t *testing.T) {
 
 			account := db.Account{
 				ID:       util.RandomInt(1000),
-				Owner:    tc.RequestBody.Owner,
+				Owner:    user.Username,
 				Balance:  0,
 				Currency: tc.RequestBody.Currency,
 			}
@@ -81,11 +147,12 @@ func TestCreateAccountAPI(t *testing.T) {
 			tc.buildStub(*store, ctrl, account)
 
 			// configure test server
-			server := NewServer(store)
+			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
 
 			request, err := http.NewRequest(http.MethodPost, "/accounts", buildRequestBody(tc.RequestBody))
 			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
 
 			// start test server
 			server.router.ServeHTTP(recorder, request)
@@ -103,10 +170,9 @@ func TestUpdateAccountAPI(t *testing.T) {
 	account2 := randomAccount()
 
 	updateAccountReq := updateAccountRequest{
-		ID:       account1.ID,
-		Owner:    account2.Owner,
-		Balance:  account2.Balance,
-		Currency: account2.Currency,
+		ID:      account1.ID,
+		Owner:   account2.Owner,
+		Balance: amountParam{amount: money.New(account2.Balance, account2.Currency)},
 	}
 
 	updatedAccount := db.Account{
@@ -119,17 +185,46 @@ func TestUpdateAccountAPI(t *testing.T) {
 	invalidRequest.ID = -1
 	invalidRequest.Currency = "xyz"
 
+	legacyRequest := updateAccountReq
+	legacyRequest.Balance = amountParam{minorUnits: &account2.Balance}
+	legacyRequest.Currency = account2.Currency
+
+	mismatchRequest := updateAccountReq
+	mismatchRequest.Currency = util.USD
+	if account2.Currency == util.USD {
+		mismatchRequest.Currency = util.EUR
+	}
+
+	// the account as stored, in the currency of the requested balance
+	currentAccount := account1
+	currentAccount.Currency = account2.Currency
+
+	otherCurrencyAccount := account1
+	otherCurrencyAccount.Currency = mismatchRequest.Currency
+
 	testCases := []struct {
 		name          string
 		RequestBody   updateAccountRequest
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 		buildStub     func(store mockdb.MockStore, ctrl *gomock.Controller)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		{
 			"OK",
 			updateAccountReq,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "admin", util.AdminRole, time.Minute)
+			},
 			func(store mockdb.MockStore, ctrl *gomock.Controller) {
-				store.EXPECT().UpdateAccount(gomock.Any(), gomock.Any()).
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).
+					Return(currentAccount, nil)
+				arg := db.UpdateAccountTxParams{
+					ID:      account1.ID,
+					Owner:   account2.Owner,
+					Balance: account2.Balance,
+				}
+				store.EXPECT().UpdateAccountTx(gomock.Any(), gomock.Eq(arg)).
 					Times(1).
 					Return(updatedAccount, nil)
 			},
@@ -138,22 +233,179 @@ func TestUpdateAccountAPI(t *testing.T) {
 				requireBodyMatchAccount(t, recorder.Body, updatedAccount)
 			},
 		},
+		{
+			"LegacyBalance",
+			legacyRequest,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "admin", util.AdminRole, time.Minute)
+			},
+			func(store mockdb.MockStore, ctrl *gomock.Controller) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).
+					Return(currentAccount, nil)
+				arg := db.UpdateAccountTxParams{
+					ID:      account1.ID,
+					Owner:   account2.Owner,
+					Balance: account2.Balance,
+				}
+				store.EXPECT().UpdateAccountTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(updatedAccount, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, updatedAccount)
+			},
+		},
+		{
+			"CurrencyMismatch",
+			mismatchRequest,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "admin", util.AdminRole, time.Minute)
+			},
+			func(store mockdb.MockStore, ctrl *gomock.Controller) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().UpdateAccountTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder, apperr.CodeInvalidArgument)
+			},
+		},
+		{
+			"AccountCurrencyMismatch",
+			updateAccountReq,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "admin", util.AdminRole, time.Minute)
+			},
+			func(store mockdb.MockStore, ctrl *gomock.Controller) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).
+					Return(otherCurrencyAccount, nil)
+				store.EXPECT().UpdateAccountTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				rsp := requireBodyMatchError(t, recorder, apperr.CodeInvalidArgument)
+				require.EqualValues(t, account1.ID, rsp.Details["account_id"])
+			},
+		},
+		{
+			"CustomerForbidden",
+			updateAccountReq,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account1.Owner, util.CustomerRole, time.Minute)
+			},
+			func(store mockdb.MockStore, ctrl *gomock.Controller) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().UpdateAccountTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			"OperatorForbidden",
+			updateAccountReq,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "operator", util.OperatorRole, time.Minute)
+			},
+			func(store mockdb.MockStore, ctrl *gomock.Controller) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().UpdateAccountTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			"NoAuthorization",
+			updateAccountReq,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			func(store mockdb.MockStore, ctrl *gomock.Controller) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().UpdateAccountTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
 		{
 			"BadRequest",
 			invalidRequest,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "admin", util.AdminRole, time.Minute)
+			},
 			func(store mockdb.MockStore, ctrl *gomock.Controller) {
-				store.EXPECT().UpdateAccount(gomock.Any(), gomock.Any()).
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().UpdateAccountTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
 			},
 		},
+		{
+			"NotFound",
+			updateAccountReq,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "admin", util.AdminRole, time.Minute)
+			},
+			func(store mockdb.MockStore, ctrl *gomock.Controller) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().UpdateAccountTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				rsp := requireBodyMatchError(t, recorder, apperr.CodeNotFound)
+				require.Equal(t, "account not found", rsp.Message)
+			},
+		},
+		{
+			"OwnerNotFound",
+			updateAccountReq,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "admin", util.AdminRole, time.Minute)
+			},
+			func(store mockdb.MockStore, ctrl *gomock.Controller) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(currentAccount, nil)
+				store.EXPECT().UpdateAccountTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, &pq.Error{Code: "23503", Constraint: "accounts_owner_fkey"})
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				rsp := requireBodyMatchError(t, recorder, apperr.CodeFailedPrecondition)
+				require.Equal(t, "accounts_owner_fkey", rsp.Details["constraint"])
+			},
+		},
 		{
 			"InternalServerError",
 			updateAccountReq,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "admin", util.AdminRole, time.Minute)
+			},
 			func(store mockdb.MockStore, ctrl *gomock.Controller) {
-				store.EXPECT().UpdateAccount(gomock.Any(), gomock.Any()).
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(currentAccount, nil)
+				store.EXPECT().UpdateAccountTx(gomock.Any(), gomock.Any()).
 					Times(1).
 					Return(updatedAccount, sql.ErrConnDone)
 			},
@@ -172,11 +424,12 @@ func TestUpdateAccountAPI(t *testing.T) {
 			tc.buildStub(*store, ctrl)
 
 			// configure test server
-			server := NewServer(store)
+			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
 
 			request, err := http.NewRequest(http.MethodPost, "/accounts/update", buildRequestBody(tc.RequestBody))
 			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
 
 			// start test server
 			server.router.ServeHTTP(recorder, request)
@@ -194,12 +447,16 @@ func TestGetAccountAPI(t *testing.T) {
 	testCases := []struct {
 		name          string
 		ID            int64
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 		buildStub     func(store mockdb.MockStore, ctrl *gomock.Controller)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		{
 			"OK",
 			account.ID,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			func(store mockdb.MockStore, ctrl *gomock.Controller) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
@@ -210,9 +467,40 @@ func TestGetAccountAPI(t *testing.T) {
 				requireBodyMatchAccount(t, recorder.Body, account)
 			},
 		},
+		{
+			"UnauthorizedUser",
+			account.ID,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", util.CustomerRole, time.Minute)
+			},
+			func(store mockdb.MockStore, ctrl *gomock.Controller) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			"NoAuthorization",
+			account.ID,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			func(store mockdb.MockStore, ctrl *gomock.Controller) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
 		{
 			"NotFound",
 			account.ID,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			func(store mockdb.MockStore, ctrl *gomock.Controller) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
@@ -225,6 +513,9 @@ func TestGetAccountAPI(t *testing.T) {
 		{
 			"InternalServerError",
 			account.ID,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			func(store mockdb.MockStore, ctrl *gomock.Controller) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
@@ -237,6 +528,9 @@ func TestGetAccountAPI(t *testing.T) {
 		{
 			"BadRequest",
 			0,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			func(store mockdb.MockStore, ctrl *gomock.Controller) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
 					Times(0)
@@ -256,12 +550,13 @@ func TestGetAccountAPI(t *testing.T) {
 			tc.buildStub(*store, ctrl)
 
 			// configure test server
-			server := NewServer(store)
+			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
 
 			url := fmt.Sprintf("/accounts/%d", tc.ID)
 			request, err := http.NewRequest(http.MethodGet, url, nil)
 			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
 
 			// start test server
 			server.router.ServeHTTP(recorder, request)
@@ -274,17 +569,22 @@ func TestGetAccountAPI(t *testing.T) {
 }
 
 func TestGetAccountListAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
 	var accounts []db.Account
 	n := 5
 
 	for i := 0; i < n; i++ {
-		accounts = append(accounts, randomAccount())
+		account := randomAccount()
+		account.Owner = user.Username
+		accounts = append(accounts, account)
 	}
 
 	testCases := []struct {
 		name          string
 		PageID        int32
 		PageSize      int32
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 		buildStub     func(store mockdb.MockStore, ctrl *gomock.Controller)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
@@ -292,8 +592,16 @@ func TestGetAccountListAPI(t *testing.T) {
 			"OK",
 			1,
 			5,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
+			},
 			func(store mockdb.MockStore, ctrl *gomock.Controller) {
-				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).
+				arg := db.ListAccountsParams{
+					Owner:  user.Username,
+					Limit:  5,
+					Offset: 0,
+				}
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(arg)).
 					Times(1).
 					Return(accounts, nil)
 			},
@@ -302,10 +610,27 @@ func TestGetAccountListAPI(t *testing.T) {
 				requireBodyMatchAccounts(t, recorder.Body, accounts)
 			},
 		},
+		{
+			"NoAuthorization",
+			1,
+			5,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			func(store mockdb.MockStore, ctrl *gomock.Controller) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
 		{
 			"BadRequest",
 			0,
 			4,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
+			},
 			func(store mockdb.MockStore, ctrl *gomock.Controller) {
 				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).
 					Times(0)
@@ -318,6 +643,9 @@ func TestGetAccountListAPI(t *testing.T) {
 			"InternalServerError",
 			1,
 			5,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
+			},
 			func(store mockdb.MockStore, ctrl *gomock.Controller) {
 				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).
 					Times(1).
@@ -338,12 +666,13 @@ func TestGetAccountListAPI(t *testing.T) {
 			tc.buildStub(*store, ctrl)
 
 			// configure test server
-			server := NewServer(store)
+			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
 
 			url := fmt.Sprintf("/accounts?page_id=%d&page_size=%d", tc.PageID, tc.PageSize)
 			request, err := http.NewRequest(http.MethodGet, url, nil)
 			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
 
 			// start test server
 			server.router.ServeHTTP(recorder, request)
@@ -361,14 +690,21 @@ func TestDeleteAccountAPI(t *testing.T) {
 	testCases := []struct {
 		name          string
 		ID            int64
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 		buildStub     func(store mockdb.MockStore, ctrl *gomock.Controller)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		{
 			"OK",
 			account.ID,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			func(store mockdb.MockStore, ctrl *gomock.Controller) {
-				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Eq(account.ID)).
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().DeleteAccountTx(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
 					Return(nil)
 			},
@@ -376,23 +712,85 @@ func TestDeleteAccountAPI(t *testing.T) {
 				require.Equal(t, http.StatusOK, recorder.Code)
 			},
 		},
+		{
+			"UnauthorizedUser",
+			account.ID,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", util.CustomerRole, time.Minute)
+			},
+			func(store mockdb.MockStore, ctrl *gomock.Controller) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().DeleteAccountTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			"NoAuthorization",
+			account.ID,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			func(store mockdb.MockStore, ctrl *gomock.Controller) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().DeleteAccountTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
 		{
 			"InternalServerError",
 			account.ID,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			func(store mockdb.MockStore, ctrl *gomock.Controller) {
-				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Eq(account.ID)).
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().DeleteAccountTx(gomock.Any(), gomock.Eq(account.ID)).
 					Times(1).
 					Return(sql.ErrConnDone)
 			},
 			func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				rsp := requireBodyMatchError(t, recorder, apperr.CodeInternal)
+				require.Equal(t, "internal error", rsp.Message)
+			},
+		},
+		{
+			"StillReferenced",
+			account.ID,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
+			func(store mockdb.MockStore, ctrl *gomock.Controller) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().DeleteAccountTx(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(&pq.Error{Code: "23503"})
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				requireBodyMatchError(t, recorder, apperr.CodeFailedPrecondition)
 			},
 		},
 		{
 			"BadRequest",
 			0,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
 			func(store mockdb.MockStore, ctrl *gomock.Controller) {
-				store.EXPECT().DeleteAccount(gomock.Any(), gomock.Eq(account.ID)).
+				store.EXPECT().DeleteAccountTx(gomock.Any(), gomock.Eq(account.ID)).
 					Times(0)
 			},
 			func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -410,12 +808,13 @@ func TestDeleteAccountAPI(t *testing.T) {
 			tc.buildStub(*store, ctrl)
 
 			// configure test server
-			server := NewServer(store)
+			server := newTestServer(t, store)
 			recorder := httptest.NewRecorder()
 
 			url := fmt.Sprintf("/accounts/delete/%d", tc.ID)
 			request, err := http.NewRequest(http.MethodGet, url, nil)
 			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
 
 			// start test server
 			server.router.ServeHTTP(recorder, request)
@@ -429,7 +828,7 @@ func TestDeleteAccountAPI(t *testing.T) {
 
 func randomAccount() db.Account {
 	return db.Account{
-		ID:       util.RandomInt(1000),
+		ID:       util.RandomInt(1000) + 1,
 		Owner:    util.RandomOwner(),
 		Balance:  util.RandomMoney(),
 		Currency: util.RandomCurrency(),
@@ -438,7 +837,6 @@ func randomAccount() db.Account {
 
 func randomCreateAccountData() createAccountRequest {
 	return createAccountRequest{
-		Owner:    util.RandomOwner(),
 		Currency: util.RandomCurrency(),
 	}
 }
@@ -447,10 +845,10 @@ func requireBodyMatchAccount(t *testing.T, body *bytes.Buffer, account db.Accoun
 	data, err := io.ReadAll(body)
 	require.NoError(t, err)
 
-	var bodyAccount db.Account
+	var bodyAccount accountResponse
 	err = json.Unmarshal(data, &bodyAccount)
 	require.NoError(t, err)
-	require.Equal(t, bodyAccount, account)
+	require.Equal(t, newAccountResponse(account), bodyAccount)
 }
 
 func buildRequestBody(data any) io.Reader {
@@ -462,8 +860,8 @@ func requireBodyMatchAccounts(t *testing.T, body *bytes.Buffer, accounts []db.Ac
 	data, err := io.ReadAll(body)
 	require.NoError(t, err)
 
-	var bodyAccount []db.Account
+	var bodyAccount []accountResponse
 	err = json.Unmarshal(data, &bodyAccount)
 	require.NoError(t, err)
-	require.Equal(t, bodyAccount, accounts)
+	require.Equal(t, newAccountResponses(accounts), bodyAccount)
 }
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/julkar-naim/simple-bank/apperr"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/money"
+	"net/http"
+)
+
+type listAllAccountsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listAllAccounts lists the accounts of every user
+func (server *Server) listAllAccounts(ctx *gin.Context) {
+	var req listAllAccountsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	arg := db.ListAllAccountsParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	accounts, err := server.store.ListAllAccounts(ctx.Request.Context(), arg)
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+	renderAmounts(ctx, newAccountResponses(accounts), accounts)
+}
+
+type freezeAccountRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) freezeAccount(ctx *gin.Context) {
+	server.setAccountFrozen(ctx, true)
+}
+
+func (server *Server) unfreezeAccount(ctx *gin.Context) {
+	server.setAccountFrozen(ctx, false)
+}
+
+func (server *Server) setAccountFrozen(ctx *gin.Context, frozen bool) {
+	var req freezeAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	arg := db.SetAccountFrozenParams{
+		ID:       req.ID,
+		IsFrozen: frozen,
+	}
+
+	account, err := server.store.SetAccountFrozenTx(ctx.Request.Context(), arg)
+	if err != nil {
+		renderError(ctx, apperr.Translate(err, "account"))
+		return
+	}
+	renderAmounts(ctx, newAccountResponse(account), account)
+}
+
+type unreconciledAccountResponse struct {
+	ID           int64        `json:"id"`
+	Owner        string       `json:"owner"`
+	Currency     string       `json:"currency"`
+	Balance      money.Amount `json:"balance"`
+	EntriesTotal money.Amount `json:"entries_total"`
+}
+
+type reconciliationRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listUnreconciledAccounts reports accounts whose balance differs from the sum of their entries
+func (server *Server) listUnreconciledAccounts(ctx *gin.Context) {
+	var req reconciliationRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	arg := db.ListUnreconciledAccountsParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	rows, err := server.store.ListUnreconciledAccounts(ctx.Request.Context(), arg)
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+	rsp := make([]unreconciledAccountResponse, 0, len(rows))
+	for _, row := range rows {
+		rsp = append(rsp, unreconciledAccountResponse{
+			ID:           row.ID,
+			Owner:        row.Owner,
+			Currency:     row.Currency,
+			Balance:      money.New(row.Balance, row.Currency),
+			EntriesTotal: money.New(row.EntriesTotal, row.Currency),
+		})
+	}
+	renderAmounts(ctx, rsp, rows)
+}
+
+type updateUserRoleRequest struct {
+	Username string `json:"username" binding:"required,alphanum"`
+	Role     string `json:"role" binding:"required,role"`
+}
+
+func (server *Server) updateUserRole(ctx *gin.Context) {
+	var req updateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	arg := db.UpdateUserRoleTxParams{
+		Username: req.Username,
+		Role:     req.Role,
+	}
+
+	result, err := server.store.UpdateUserRoleTx(ctx.Request.Context(), arg)
+	if err != nil {
+		renderError(ctx, apperr.Translate(err, "user"))
+		return
+	}
+	ctx.JSON(http.StatusOK, newUserResponse(result.User))
+}
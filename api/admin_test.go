@@ -0,0 +1,283 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/julkar-naim/simple-bank/apperr"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/token"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func authAs(role string) func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+	return func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+		addAuthorization(t, request, tokenMaker, authorizationTypeBearer, role+"user", role, time.Minute)
+	}
+}
+
+func TestAdminAPI(t *testing.T) {
+	account := randomAccount()
+	frozenAccount := account
+	frozenAccount.IsFrozen = true
+
+	user, _ := randomUser(t)
+	promotedUser := user
+	promotedUser.Role = util.OperatorRole
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStub     func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			"ListAllAccountsOK",
+			http.MethodGet,
+			"/admin/accounts?page_id=2&page_size=5",
+			nil,
+			authAs(util.OperatorRole),
+			func(store *mockdb.MockStore) {
+				arg := db.ListAllAccountsParams{Limit: 5, Offset: 5}
+				store.EXPECT().ListAllAccounts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.Account{account}, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccounts(t, recorder.Body, []db.Account{account})
+			},
+		},
+		{
+			"ListAllAccountsCustomerForbidden",
+			http.MethodGet,
+			"/admin/accounts?page_id=1&page_size=5",
+			nil,
+			authAs(util.CustomerRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().ListAllAccounts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			"ListAllAccountsNoAuthorization",
+			http.MethodGet,
+			"/admin/accounts?page_id=1&page_size=5",
+			nil,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			func(store *mockdb.MockStore) {
+				store.EXPECT().ListAllAccounts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			"FreezeAccountOK",
+			http.MethodPost,
+			fmt.Sprintf("/admin/accounts/%d/freeze", account.ID),
+			nil,
+			authAs(util.OperatorRole),
+			func(store *mockdb.MockStore) {
+				arg := db.SetAccountFrozenParams{ID: account.ID, IsFrozen: true}
+				store.EXPECT().SetAccountFrozenTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(frozenAccount, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, frozenAccount)
+			},
+		},
+		{
+			"UnfreezeAccountOK",
+			http.MethodPost,
+			fmt.Sprintf("/admin/accounts/%d/unfreeze", account.ID),
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				arg := db.SetAccountFrozenParams{ID: account.ID, IsFrozen: false}
+				store.EXPECT().SetAccountFrozenTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(account, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			"FreezeAccountNotFound",
+			http.MethodPost,
+			fmt.Sprintf("/admin/accounts/%d/freeze", account.ID),
+			nil,
+			authAs(util.OperatorRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().SetAccountFrozenTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			"FreezeAccountCustomerForbidden",
+			http.MethodPost,
+			fmt.Sprintf("/admin/accounts/%d/freeze", account.ID),
+			nil,
+			authAs(util.CustomerRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().SetAccountFrozenTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			"ReconciliationOK",
+			http.MethodGet,
+			"/admin/reconciliation?page_id=1&page_size=10",
+			nil,
+			authAs(util.OperatorRole),
+			func(store *mockdb.MockStore) {
+				arg := db.ListUnreconciledAccountsParams{Limit: 10, Offset: 0}
+				store.EXPECT().ListUnreconciledAccounts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.ListUnreconciledAccountsRow{{ID: account.ID, Currency: util.USD, Balance: 10, EntriesTotal: 5}}, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rows []unreconciledAccountResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rows))
+				require.Len(t, rows, 1)
+				require.Equal(t, account.ID, rows[0].ID)
+				require.Equal(t, "0.10", rows[0].Balance.Decimal())
+				require.Equal(t, "0.05", rows[0].EntriesTotal.Decimal())
+			},
+		},
+		{
+			"ReconciliationInternalServerError",
+			http.MethodGet,
+			"/admin/reconciliation?page_id=1&page_size=10",
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().ListUnreconciledAccounts(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			"UpdateUserRoleOK",
+			http.MethodPost,
+			"/admin/users/role",
+			gin.H{"username": user.Username, "role": util.OperatorRole},
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				arg := db.UpdateUserRoleTxParams{
+					Username: user.Username,
+					Role:     util.OperatorRole,
+				}
+				store.EXPECT().UpdateUserRoleTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.UpdateUserRoleTxResult{User: promotedUser}, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp userResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, util.OperatorRole, rsp.Role)
+			},
+		},
+		{
+			"UpdateUserRoleOperatorForbidden",
+			http.MethodPost,
+			"/admin/users/role",
+			gin.H{"username": user.Username, "role": util.AdminRole},
+			authAs(util.OperatorRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserRoleTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			"UpdateUserRoleUnsupportedRole",
+			http.MethodPost,
+			"/admin/users/role",
+			gin.H{"username": user.Username, "role": "root"},
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserRoleTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				rsp := requireBodyMatchError(t, recorder, apperr.CodeInvalidArgument)
+				require.Equal(t, map[string]any{"fields": map[string]any{"role": "role"}}, rsp.Details)
+			},
+		},
+		{
+			"UpdateUserRoleUserNotFound",
+			http.MethodPost,
+			"/admin/users/role",
+			gin.H{"username": user.Username, "role": util.OperatorRole},
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserRoleTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UpdateUserRoleTxResult{}, sql.ErrNoRows)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+
+			// configure test server
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, buildRequestBody(tc.body))
+			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
+
+			// start test server
+			server.router.ServeHTTP(recorder, request)
+
+			// check response
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/julkar-naim/simple-bank/apperr"
+	"github.com/julkar-naim/simple-bank/money"
+	"github.com/julkar-naim/simple-bank/util"
+	"net/http"
+)
+
+// Responses encode amounts as money objects such as
+// {"value": "12.34", "currency": "USD"}. Clients that still expect bare
+// integers in minor units send "Amount-Format: minor_units" until they
+// migrate.
+const (
+	amountFormatHeader     = "Amount-Format"
+	amountFormatMinorUnits = "minor_units"
+)
+
+// renderAmounts writes rsp, or legacy when the client asked for amounts in
+// minor units
+func renderAmounts(ctx *gin.Context, rsp any, legacy any) {
+	if ctx.GetHeader(amountFormatHeader) == amountFormatMinorUnits {
+		ctx.JSON(http.StatusOK, legacy)
+		return
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// amountParam is an amount in a request body: a money object, or in the
+// legacy format an integer in minor units of the request's currency field
+type amountParam struct {
+	amount     money.Amount
+	minorUnits *int64
+}
+
+func (param *amountParam) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return json.Unmarshal(data, &param.amount)
+	}
+
+	var minorUnits int64
+	if err := json.Unmarshal(data, &minorUnits); err != nil {
+		return err
+	}
+	param.minorUnits = &minorUnits
+	return nil
+}
+
+func (param amountParam) MarshalJSON() ([]byte, error) {
+	if param.minorUnits != nil {
+		return json.Marshal(*param.minorUnits)
+	}
+	return json.Marshal(param.amount)
+}
+
+// resolve returns the amount of the field named field. A legacy integer
+// takes its currency from currency; a money object must agree with currency
+// when both are given. Either way the currency must be enabled.
+func (param amountParam) resolve(field string, currency string) (money.Amount, error) {
+	amount := param.amount
+	switch {
+	case param.minorUnits != nil:
+		if currency == "" {
+			return money.Amount{}, fieldError("currency", "required")
+		}
+		amount = money.New(*param.minorUnits, currency)
+	case amount.Currency() == "":
+		return money.Amount{}, fieldError(field, "required")
+	case currency != "" && currency != amount.Currency():
+		return money.Amount{}, apperr.Newf(apperr.CodeInvalidArgument, "%s is in %s but currency is %s", field, amount.Currency(), currency).
+			WithDetail("field", field)
+	}
+
+	if !util.IsSupportedCurrency(amount.Currency()) {
+		return money.Amount{}, fieldError(field, "currency")
+	}
+	return amount, nil
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/julkar-naim/simple-bank/apperr"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/token"
+	"github.com/julkar-naim/simple-bank/util"
+	"net/http"
+	"time"
+)
+
+type apiKeyResponse struct {
+	KeyID     string    `json:"key_id"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	CreatedBy string    `json:"created_by"`
+	IsRevoked bool      `json:"is_revoked"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newAPIKeyResponse(apiKey db.ApiKey) apiKeyResponse {
+	return apiKeyResponse{
+		KeyID:     apiKey.KeyID,
+		Name:      apiKey.Name,
+		Scopes:    apiKey.Scopes,
+		CreatedBy: apiKey.CreatedBy,
+		IsRevoked: apiKey.IsRevoked,
+		ExpiresAt: apiKey.ExpiresAt,
+		CreatedAt: apiKey.CreatedAt,
+	}
+}
+
+type createAPIKeyRequest struct {
+	Name      string   `json:"name" binding:"required"`
+	Scopes    []string `json:"scopes" binding:"required,min=1"`
+	ValidDays int      `json:"valid_days" binding:"required,min=1,max=365"`
+}
+
+type createAPIKeyResponse struct {
+	// Secret is only returned once; the server keeps it encrypted
+	Secret string         `json:"secret"`
+	APIKey apiKeyResponse `json:"api_key"`
+}
+
+func (server *Server) createAPIKey(ctx *gin.Context) {
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !util.IsSupportedScope(scope) {
+			renderError(ctx, apperr.Newf(apperr.CodeInvalidArgument, "unsupported scope %s", scope).
+				WithDetail("scope", scope))
+			return
+		}
+	}
+
+	keyID, err := util.RandomToken(8)
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	secret, err := util.RandomToken(32)
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	// the key id is bound to the ciphertext so it cannot be moved to another key
+	secretCiphertext, err := server.secretBox.Seal(secret, keyID)
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.CreateAPIKeyParams{
+		KeyID:            keyID,
+		Name:             req.Name,
+		SecretCiphertext: secretCiphertext,
+		Scopes:           req.Scopes,
+		CreatedBy:        authPayload.Username,
+		ExpiresAt:        time.Now().AddDate(0, 0, req.ValidDays),
+	}
+
+	apiKey, err := server.store.CreateAPIKeyTx(ctx.Request.Context(), arg)
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	rsp := createAPIKeyResponse{
+		Secret: secret,
+		APIKey: newAPIKeyResponse(apiKey),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type listAPIKeysRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+func (server *Server) listAPIKeys(ctx *gin.Context) {
+	var req listAPIKeysRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	arg := db.ListAPIKeysParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	apiKeys, err := server.store.ListAPIKeys(ctx.Request.Context(), arg)
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	rsp := make([]apiKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		rsp = append(rsp, newAPIKeyResponse(apiKey))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type revokeAPIKeyRequest struct {
+	KeyID string `uri:"key_id" binding:"required,hexadecimal"`
+}
+
+func (server *Server) revokeAPIKey(ctx *gin.Context) {
+	var req revokeAPIKeyRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	apiKey, err := server.store.RevokeAPIKeyTx(ctx.Request.Context(), req.KeyID)
+	if err != nil {
+		renderError(ctx, apperr.Translate(err, "api key"))
+		return
+	}
+	ctx.JSON(http.StatusOK, newAPIKeyResponse(apiKey))
+}
@@ -0,0 +1,662 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/token"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func randomAPIKey(t *testing.T, scopes ...string) (apiKey db.ApiKey, secret string) {
+	keyID, err := util.RandomToken(8)
+	require.NoError(t, err)
+	secret, err = util.RandomToken(32)
+	require.NoError(t, err)
+
+	secretBox, err := util.NewSecretBox(testAPIKeyEncryptionKey)
+	require.NoError(t, err)
+	secretCiphertext, err := secretBox.Seal(secret, keyID)
+	require.NoError(t, err)
+
+	apiKey = db.ApiKey{
+		ID:               util.RandomInt(1000) + 1,
+		KeyID:            keyID,
+		Name:             util.RandomString(8),
+		SecretCiphertext: secretCiphertext,
+		Scopes:           scopes,
+		CreatedBy:        util.RandomOwner(),
+		ExpiresAt:        time.Now().Add(time.Hour),
+	}
+	return
+}
+
+func addSignature(
+	t *testing.T,
+	request *http.Request,
+	keyID string,
+	secret string,
+	body []byte,
+	timestamp time.Time,
+	nonce string,
+) {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	signature := util.SignRequest(secret, request.Method, request.URL.RequestURI(), ts, nonce, body)
+
+	request.Header.Set(apiKeyHeaderKey, keyID)
+	request.Header.Set(timestampHeaderKey, ts)
+	request.Header.Set(nonceHeaderKey, nonce)
+	request.Header.Set(signatureHeaderKey, signature)
+}
+
+func TestSignatureMiddleware(t *testing.T) {
+	apiKey, secret := randomAPIKey(t, string(util.PermFreezeAccounts))
+	revokedKey := apiKey
+	revokedKey.IsRevoked = true
+	expiredKey := apiKey
+	expiredKey.ExpiresAt = time.Now().Add(-time.Minute)
+	// a ciphertext sealed for another key id does not open
+	movedKey := apiKey
+	movedKey.KeyID = apiKey.KeyID + "x"
+
+	body := []byte(`{"reason":"test"}`)
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request)
+		buildStub     func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			"OK",
+			func(t *testing.T, request *http.Request) {
+				addSignature(t, request, apiKey.KeyID, secret, body, time.Now(), "nonce1")
+			},
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(apiKey.KeyID)).
+					Times(1).
+					Return(apiKey, nil)
+				arg := db.CreateAPIKeyNonceParams{KeyID: apiKey.KeyID, Nonce: "nonce1"}
+				store.EXPECT().CreateAPIKeyNonce(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp struct {
+					KeyID  string   `json:"key_id"`
+					Scopes []string `json:"scopes"`
+					Body   string   `json:"body"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, apiKey.KeyID, rsp.KeyID)
+				require.Equal(t, apiKey.Scopes, rsp.Scopes)
+				require.Equal(t, string(body), rsp.Body)
+			},
+		},
+		{
+			"NoSignature",
+			func(t *testing.T, request *http.Request) {
+				request.Header.Set(apiKeyHeaderKey, apiKey.KeyID)
+			},
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			"ClockSkew",
+			func(t *testing.T, request *http.Request) {
+				addSignature(t, request, apiKey.KeyID, secret, body, time.Now().Add(-10*time.Minute), "nonce1")
+			},
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			"UnknownKey",
+			func(t *testing.T, request *http.Request) {
+				addSignature(t, request, apiKey.KeyID, secret, body, time.Now(), "nonce1")
+			},
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(apiKey.KeyID)).
+					Times(1).
+					Return(db.ApiKey{}, sql.ErrNoRows)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			"RevokedKey",
+			func(t *testing.T, request *http.Request) {
+				addSignature(t, request, apiKey.KeyID, secret, body, time.Now(), "nonce1")
+			},
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(apiKey.KeyID)).
+					Times(1).
+					Return(revokedKey, nil)
+				store.EXPECT().CreateAPIKeyNonce(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			"ExpiredKey",
+			func(t *testing.T, request *http.Request) {
+				addSignature(t, request, apiKey.KeyID, secret, body, time.Now(), "nonce1")
+			},
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(apiKey.KeyID)).
+					Times(1).
+					Return(expiredKey, nil)
+				store.EXPECT().CreateAPIKeyNonce(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			"WrongSecret",
+			func(t *testing.T, request *http.Request) {
+				addSignature(t, request, apiKey.KeyID, "wrong-secret", body, time.Now(), "nonce1")
+			},
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(apiKey.KeyID)).
+					Times(1).
+					Return(apiKey, nil)
+				store.EXPECT().CreateAPIKeyNonce(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			"SignedWithStoredColumn",
+			func(t *testing.T, request *http.Request) {
+				// a reader of the api_keys table cannot sign requests
+				addSignature(t, request, apiKey.KeyID, apiKey.SecretCiphertext, body, time.Now(), "nonce1")
+			},
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(apiKey.KeyID)).
+					Times(1).
+					Return(apiKey, nil)
+				store.EXPECT().CreateAPIKeyNonce(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			"MovedCiphertext",
+			func(t *testing.T, request *http.Request) {
+				addSignature(t, request, movedKey.KeyID, secret, body, time.Now(), "nonce1")
+			},
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(movedKey.KeyID)).
+					Times(1).
+					Return(movedKey, nil)
+				store.EXPECT().CreateAPIKeyNonce(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			"TamperedBody",
+			func(t *testing.T, request *http.Request) {
+				addSignature(t, request, apiKey.KeyID, secret, []byte(`{"reason":"other"}`), time.Now(), "nonce1")
+			},
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(apiKey.KeyID)).
+					Times(1).
+					Return(apiKey, nil)
+				store.EXPECT().CreateAPIKeyNonce(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			"BodyTooLarge",
+			func(t *testing.T, request *http.Request) {
+				largeBody := bytes.Repeat([]byte("a"), maxSignedBodyBytes+1)
+				request.Body = io.NopCloser(bytes.NewReader(largeBody))
+				request.ContentLength = int64(len(largeBody))
+				addSignature(t, request, apiKey.KeyID, secret, largeBody, time.Now(), "nonce1")
+			},
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(apiKey.KeyID)).
+					Times(1).
+					Return(apiKey, nil)
+				store.EXPECT().CreateAPIKeyNonce(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			"ReplayedNonce",
+			func(t *testing.T, request *http.Request) {
+				addSignature(t, request, apiKey.KeyID, secret, body, time.Now(), "nonce1")
+			},
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(apiKey.KeyID)).
+					Times(1).
+					Return(apiKey, nil)
+				store.EXPECT().CreateAPIKeyNonce(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&pq.Error{Code: "23505"})
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+
+			server := newTestServer(t, store)
+
+			signedPath := "/signed"
+			server.router.POST(
+				signedPath,
+				signatureMiddleware(server.store, server.secretBox, server.config.APISignatureMaxSkew),
+				func(ctx *gin.Context) {
+					identity := ctx.MustGet(serviceIdentityKey).(*ServiceIdentity)
+					data, err := io.ReadAll(ctx.Request.Body)
+					require.NoError(t, err)
+					ctx.JSON(http.StatusOK, gin.H{
+						"key_id": identity.KeyID,
+						"scopes": identity.Scopes,
+						"body":   string(data),
+					})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, signedPath, bytes.NewReader(body))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestServiceAuthorization(t *testing.T) {
+	account := randomAccount()
+	frozenAccount := account
+	frozenAccount.IsFrozen = true
+
+	freezeKey, freezeSecret := randomAPIKey(t, string(util.PermFreezeAccounts))
+	reconcileKey, reconcileSecret := randomAPIKey(t, string(util.PermReconcile))
+	adjustKey, adjustSecret := randomAPIKey(t, string(util.PermAdjustAccounts))
+
+	adjustedAccount := account
+	adjustedAccount.Owner = util.RandomOwner()
+	adjustBody, err := json.Marshal(gin.H{
+		"id":       account.ID,
+		"owner":    adjustedAccount.Owner,
+		"balance":  account.Balance,
+		"currency": account.Currency,
+	})
+	require.NoError(t, err)
+
+	stubSignature := func(store *mockdb.MockStore, apiKey db.ApiKey) {
+		store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(apiKey.KeyID)).
+			Times(1).
+			Return(apiKey, nil)
+		store.EXPECT().CreateAPIKeyNonce(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(nil)
+	}
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		apiKey        db.ApiKey
+		secret        string
+		body          []byte
+		buildStub     func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			"FreezeWithScope",
+			http.MethodPost,
+			fmt.Sprintf("/admin/accounts/%d/freeze", account.ID),
+			freezeKey,
+			freezeSecret,
+			nil,
+			func(store *mockdb.MockStore) {
+				stubSignature(store, freezeKey)
+				arg := db.SetAccountFrozenParams{ID: account.ID, IsFrozen: true}
+				store.EXPECT().SetAccountFrozenTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(frozenAccount, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, frozenAccount)
+			},
+		},
+		{
+			"FreezeWithoutScope",
+			http.MethodPost,
+			fmt.Sprintf("/admin/accounts/%d/freeze", account.ID),
+			reconcileKey,
+			reconcileSecret,
+			nil,
+			func(store *mockdb.MockStore) {
+				stubSignature(store, reconcileKey)
+				store.EXPECT().SetAccountFrozenTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			"ManageAPIKeysForbidden",
+			http.MethodGet,
+			"/admin/api_keys?page_id=1&page_size=5",
+			freezeKey,
+			freezeSecret,
+			nil,
+			func(store *mockdb.MockStore) {
+				stubSignature(store, freezeKey)
+				store.EXPECT().ListAPIKeys(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			"AdjustWithScope",
+			http.MethodPost,
+			"/accounts/update",
+			adjustKey,
+			adjustSecret,
+			adjustBody,
+			func(store *mockdb.MockStore) {
+				stubSignature(store, adjustKey)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				arg := db.UpdateAccountTxParams{ID: account.ID, Owner: adjustedAccount.Owner, Balance: account.Balance}
+				store.EXPECT().UpdateAccountTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(adjustedAccount, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, adjustedAccount)
+			},
+		},
+		{
+			"AdjustWithoutScope",
+			http.MethodPost,
+			"/accounts/update",
+			freezeKey,
+			freezeSecret,
+			adjustBody,
+			func(store *mockdb.MockStore) {
+				stubSignature(store, freezeKey)
+				store.EXPECT().UpdateAccountTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader(tc.body))
+			require.NoError(t, err)
+			addSignature(t, request, tc.apiKey.KeyID, tc.secret, tc.body, time.Now(), util.RandomString(16))
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+type eqCreateAPIKeyParamsMatcher struct {
+	arg db.CreateAPIKeyParams
+}
+
+func (e eqCreateAPIKeyParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateAPIKeyParams)
+	if !ok {
+		return false
+	}
+
+	return arg.KeyID != "" &&
+		arg.SecretCiphertext != "" &&
+		arg.Name == e.arg.Name &&
+		arg.CreatedBy == e.arg.CreatedBy &&
+		reflect.DeepEqual(arg.Scopes, e.arg.Scopes) &&
+		arg.ExpiresAt.After(time.Now())
+}
+
+func (e eqCreateAPIKeyParamsMatcher) String() string {
+	return fmt.Sprintf("matches arg %v", e.arg)
+}
+
+func TestAPIKeyAPI(t *testing.T) {
+	apiKey, _ := randomAPIKey(t, string(util.PermReconcile))
+	revokedKey := apiKey
+	revokedKey.IsRevoked = true
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStub     func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			"CreateOK",
+			http.MethodPost,
+			"/admin/api_keys",
+			gin.H{"name": apiKey.Name, "scopes": apiKey.Scopes, "valid_days": 30},
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				arg := db.CreateAPIKeyParams{
+					Name:      apiKey.Name,
+					Scopes:    apiKey.Scopes,
+					CreatedBy: util.AdminRole + "user",
+				}
+				store.EXPECT().CreateAPIKeyTx(gomock.Any(), eqCreateAPIKeyParamsMatcher{arg}).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+						created := apiKey
+						created.KeyID = arg.KeyID
+						created.SecretCiphertext = arg.SecretCiphertext
+						return created, nil
+					})
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp createAPIKeyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.NotEmpty(t, rsp.Secret)
+				require.NotEmpty(t, rsp.APIKey.KeyID)
+				require.Equal(t, apiKey.Name, rsp.APIKey.Name)
+				require.Equal(t, apiKey.Scopes, rsp.APIKey.Scopes)
+				require.NotContains(t, recorder.Body.String(), "secret_ciphertext")
+			},
+		},
+		{
+			"CreateUnsupportedScope",
+			http.MethodPost,
+			"/admin/api_keys",
+			gin.H{"name": apiKey.Name, "scopes": []string{string(util.PermManageRoles)}, "valid_days": 30},
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKeyTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			"CreateInvalidValidDays",
+			http.MethodPost,
+			"/admin/api_keys",
+			gin.H{"name": apiKey.Name, "scopes": apiKey.Scopes, "valid_days": 1000},
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKeyTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			"CreateOperatorForbidden",
+			http.MethodPost,
+			"/admin/api_keys",
+			gin.H{"name": apiKey.Name, "scopes": apiKey.Scopes, "valid_days": 30},
+			authAs(util.OperatorRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKeyTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			"ListOK",
+			http.MethodGet,
+			"/admin/api_keys?page_id=1&page_size=5",
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				arg := db.ListAPIKeysParams{Limit: 5, Offset: 0}
+				store.EXPECT().ListAPIKeys(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.ApiKey{apiKey}, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), apiKey.SecretCiphertext)
+
+				var rsp []apiKeyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp, 1)
+				require.Equal(t, apiKey.KeyID, rsp[0].KeyID)
+			},
+		},
+		{
+			"RevokeOK",
+			http.MethodPost,
+			fmt.Sprintf("/admin/api_keys/%s/revoke", apiKey.KeyID),
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().RevokeAPIKeyTx(gomock.Any(), gomock.Eq(apiKey.KeyID)).
+					Times(1).
+					Return(revokedKey, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp apiKeyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.True(t, rsp.IsRevoked)
+			},
+		},
+		{
+			"RevokeNotFound",
+			http.MethodPost,
+			fmt.Sprintf("/admin/api_keys/%s/revoke", apiKey.KeyID),
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().RevokeAPIKeyTx(gomock.Any(), gomock.Eq(apiKey.KeyID)).
+					Times(1).
+					Return(db.ApiKey{}, sql.ErrNoRows)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, buildRequestBody(tc.body))
+			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
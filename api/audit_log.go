@@ -0,0 +1,82 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"net/http"
+	"time"
+)
+
+type auditLogResponse struct {
+	ID         int64           `json:"id"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	RequestID  string          `json:"request_id"`
+	IP         string          `json:"ip"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+func newAuditLogResponse(auditLog db.AuditLog) auditLogResponse {
+	return auditLogResponse{
+		ID:         auditLog.ID,
+		Actor:      auditLog.Actor,
+		Action:     auditLog.Action,
+		TargetType: auditLog.TargetType,
+		TargetID:   auditLog.TargetID,
+		Before:     auditLog.Before,
+		After:      auditLog.After,
+		RequestID:  auditLog.RequestID,
+		IP:         auditLog.Ip,
+		CreatedAt:  auditLog.CreatedAt,
+	}
+}
+
+type listAuditLogsRequest struct {
+	Actor      string    `form:"actor"`
+	Action     string    `form:"action"`
+	TargetType string    `form:"target_type"`
+	TargetID   string    `form:"target_id"`
+	From       time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00" binding:"omitempty,gtfield=From"`
+	PageID     int32     `form:"page_id" binding:"required,min=1"`
+	PageSize   int32     `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listAuditLogs returns the audit log entries matching every given filter,
+// newest first. From is inclusive and To exclusive.
+func (server *Server) listAuditLogs(ctx *gin.Context) {
+	var req listAuditLogsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	arg := db.ListAuditLogsParams{
+		Actor:       sql.NullString{String: req.Actor, Valid: req.Actor != ""},
+		Action:      sql.NullString{String: req.Action, Valid: req.Action != ""},
+		TargetType:  sql.NullString{String: req.TargetType, Valid: req.TargetType != ""},
+		TargetID:    sql.NullString{String: req.TargetID, Valid: req.TargetID != ""},
+		CreatedFrom: sql.NullTime{Time: req.From, Valid: !req.From.IsZero()},
+		CreatedTo:   sql.NullTime{Time: req.To, Valid: !req.To.IsZero()},
+		Limit:       req.PageSize,
+		Offset:      (req.PageID - 1) * req.PageSize,
+	}
+
+	auditLogs, err := server.store.ListAuditLogs(ctx.Request.Context(), arg)
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	rsp := make([]auditLogResponse, 0, len(auditLogs))
+	for _, auditLog := range auditLogs {
+		rsp = append(rsp, newAuditLogResponse(auditLog))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
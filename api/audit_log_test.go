@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/token"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func randomAuditLog() db.AuditLog {
+	return db.AuditLog{
+		ID:         util.RandomInt(1000) + 1,
+		Actor:      util.AdminRole + "user",
+		Action:     db.AuditActionUpdateAccount,
+		TargetType: db.AuditTargetAccount,
+		TargetID:   "42",
+		Before:     json.RawMessage(`{"balance":100}`),
+		After:      json.RawMessage(`{"balance":500}`),
+		RequestID:  "req-1",
+		Ip:         "10.0.0.1",
+		CreatedAt:  time.Now(),
+	}
+}
+
+func TestListAuditLogsAPI(t *testing.T) {
+	auditLog := randomAuditLog()
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		query         string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStub     func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			"OK",
+			"?actor=adminuser&action=account.update&target_type=account&target_id=42&from=2026-01-01T00:00:00Z&to=2026-02-01T00:00:00Z&page_id=2&page_size=10",
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ListAuditLogsParams) ([]db.AuditLog, error) {
+						require.Equal(t, sql.NullString{String: "adminuser", Valid: true}, arg.Actor)
+						require.Equal(t, sql.NullString{String: db.AuditActionUpdateAccount, Valid: true}, arg.Action)
+						require.Equal(t, sql.NullString{String: db.AuditTargetAccount, Valid: true}, arg.TargetType)
+						require.Equal(t, sql.NullString{String: "42", Valid: true}, arg.TargetID)
+						require.True(t, arg.CreatedFrom.Valid)
+						require.True(t, from.Equal(arg.CreatedFrom.Time))
+						require.True(t, arg.CreatedTo.Valid)
+						require.True(t, to.Equal(arg.CreatedTo.Time))
+						require.Equal(t, int32(10), arg.Limit)
+						require.Equal(t, int32(10), arg.Offset)
+						return []db.AuditLog{auditLog}, nil
+					})
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp []auditLogResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp, 1)
+				require.Equal(t, auditLog.ID, rsp[0].ID)
+				require.Equal(t, auditLog.RequestID, rsp[0].RequestID)
+				require.Equal(t, auditLog.Ip, rsp[0].IP)
+				require.JSONEq(t, string(auditLog.Before), string(rsp[0].Before))
+				require.JSONEq(t, string(auditLog.After), string(rsp[0].After))
+			},
+		},
+		{
+			"NoFilters",
+			"?page_id=1&page_size=5",
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				arg := db.ListAuditLogsParams{Limit: 5, Offset: 0}
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.AuditLog{}, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `[]`, recorder.Body.String())
+			},
+		},
+		{
+			"InvalidRange",
+			"?from=2026-02-01T00:00:00Z&to=2026-01-01T00:00:00Z&page_id=1&page_size=5",
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			"InvalidTime",
+			"?from=yesterday&page_id=1&page_size=5",
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			"Forbidden",
+			"?page_id=1&page_size=5",
+			authAs(util.OperatorRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			"InternalError",
+			"?page_id=1&page_size=5",
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditLogs(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/admin/audit_logs"+tc.query, nil)
+			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAuditActorReachesStore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	account := randomAccount()
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(account, nil)
+	store.EXPECT().UpdateAccountTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.UpdateAccountTxParams) (db.Account, error) {
+			actor := db.AuditActorFromContext(ctx)
+			require.Equal(t, util.AdminRole+"user", actor.Name)
+			require.Equal(t, "203.0.113.7", actor.IP)
+			require.Equal(t, "req-audit", util.RequestIDFromContext(ctx))
+			return account, nil
+		})
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodPost, "/accounts/update", buildRequestBody(gin.H{
+		"id":       account.ID,
+		"owner":    account.Owner,
+		"balance":  account.Balance,
+		"currency": account.Currency,
+	}))
+	require.NoError(t, err)
+	request.RemoteAddr = "203.0.113.7:4321"
+	request.Header.Set(requestIDHeaderKey, "req-audit")
+	authAs(util.AdminRole)(t, request, server.tokenMaker)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
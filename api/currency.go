@@ -0,0 +1,124 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/julkar-naim/simple-bank/apperr"
+	"github.com/julkar-naim/simple-bank/currency"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/util"
+	"net/http"
+	"time"
+)
+
+type currencyResponse struct {
+	Code      string    `json:"code"`
+	Exponent  int32     `json:"exponent"`
+	Symbol    string    `json:"symbol"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newCurrencyResponse(currency db.Currency) currencyResponse {
+	return currencyResponse{
+		Code:      currency.Code,
+		Exponent:  currency.Exponent,
+		Symbol:    currency.Symbol,
+		Enabled:   currency.Enabled,
+		CreatedAt: currency.CreatedAt,
+	}
+}
+
+// listCurrencies returns the enabled currencies from the cache that backs
+// the currency validator
+func (server *Server) listCurrencies(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, util.SupportedCurrencies())
+}
+
+func (server *Server) listAllCurrencies(ctx *gin.Context) {
+	currencies, err := server.store.ListCurrencies(ctx.Request.Context())
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	rsp := make([]currencyResponse, 0, len(currencies))
+	for _, currency := range currencies {
+		rsp = append(rsp, newCurrencyResponse(currency))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type createCurrencyRequest struct {
+	Code     string `json:"code" binding:"required,iso4217"`
+	Exponent *int32 `json:"exponent" binding:"required,min=0,max=4"`
+	Symbol   string `json:"symbol" binding:"required,max=8"`
+}
+
+func (server *Server) createCurrency(ctx *gin.Context) {
+	var req createCurrencyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	arg := db.CreateCurrencyParams{
+		Code:     req.Code,
+		Exponent: *req.Exponent,
+		Symbol:   req.Symbol,
+	}
+
+	created, err := server.store.CreateCurrencyTx(ctx.Request.Context(), arg)
+	if err != nil {
+		renderError(ctx, apperr.Translate(err, "currency"))
+		return
+	}
+
+	server.refreshCurrencies(ctx)
+	ctx.JSON(http.StatusOK, newCurrencyResponse(created))
+}
+
+type currencyCodeRequest struct {
+	Code string `uri:"code" binding:"required,iso4217"`
+}
+
+func (server *Server) enableCurrency(ctx *gin.Context) {
+	server.setCurrencyEnabled(ctx, true)
+}
+
+func (server *Server) disableCurrency(ctx *gin.Context) {
+	server.setCurrencyEnabled(ctx, false)
+}
+
+// setCurrencyEnabled only affects new accounts and transfers: accounts
+// already holding a disabled currency keep it
+func (server *Server) setCurrencyEnabled(ctx *gin.Context, enabled bool) {
+	var req currencyCodeRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	arg := db.SetCurrencyEnabledParams{
+		Code:    req.Code,
+		Enabled: enabled,
+	}
+
+	updated, err := server.store.SetCurrencyEnabledTx(ctx.Request.Context(), arg)
+	if err != nil {
+		renderError(ctx, apperr.Translate(err, "currency"))
+		return
+	}
+
+	server.refreshCurrencies(ctx)
+	ctx.JSON(http.StatusOK, newCurrencyResponse(updated))
+}
+
+// refreshCurrencies reloads the cache after a change so that this instance
+// sees it immediately. A failure is not the client's: the change is saved
+// and the periodic refresh will pick it up.
+func (server *Server) refreshCurrencies(ctx *gin.Context) {
+	err := currency.Refresh(ctx.Request.Context(), server.store)
+	if err != nil {
+		server.logger.Warn("cannot refresh currencies", "error", err)
+	}
+}
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/token"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCurrencyAPI(t *testing.T) {
+	defaults := util.SupportedCurrencies()
+	t.Cleanup(func() { util.SetCurrencies(defaults) })
+
+	jpy := db.Currency{Code: "JPY", Exponent: 0, Symbol: "¥", Enabled: true, CreatedAt: time.Now()}
+	disabledJPY := jpy
+	disabledJPY.Enabled = false
+	usd := db.Currency{Code: util.USD, Exponent: 2, Symbol: "$", Enabled: true, CreatedAt: time.Now()}
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStub     func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			"PublicList",
+			http.MethodGet,
+			"/currencies",
+			nil,
+			func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			func(store *mockdb.MockStore) {
+				store.EXPECT().ListCurrencies(gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp []util.Currency
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp, len(util.SupportedCurrencies()))
+				for i, currency := range util.SupportedCurrencies() {
+					require.Equal(t, currency.Code, rsp[i].Code)
+					require.Equal(t, currency.Exponent, rsp[i].Exponent)
+					require.Equal(t, currency.Symbol, rsp[i].Symbol)
+				}
+			},
+		},
+		{
+			"AdminList",
+			http.MethodGet,
+			"/admin/currencies",
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().ListCurrencies(gomock.Any()).
+					Times(1).
+					Return([]db.Currency{disabledJPY, usd}, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp []currencyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp, 2)
+				require.Equal(t, disabledJPY.Code, rsp[0].Code)
+				require.False(t, rsp[0].Enabled)
+			},
+		},
+		{
+			"CreateOK",
+			http.MethodPost,
+			"/admin/currencies",
+			gin.H{"code": jpy.Code, "exponent": 0, "symbol": jpy.Symbol},
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				arg := db.CreateCurrencyParams{Code: jpy.Code, Exponent: 0, Symbol: jpy.Symbol}
+				store.EXPECT().CreateCurrencyTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(jpy, nil)
+				store.EXPECT().ListCurrencies(gomock.Any()).
+					Times(1).
+					Return([]db.Currency{jpy, usd}, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp currencyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, jpy.Code, rsp.Code)
+				require.Equal(t, int32(0), rsp.Exponent)
+
+				// the validator accepts the new currency without a restart
+				require.True(t, util.IsSupportedCurrency(jpy.Code))
+				require.False(t, util.IsSupportedCurrency(util.EUR))
+			},
+		},
+		{
+			"CreateMissingExponent",
+			http.MethodPost,
+			"/admin/currencies",
+			gin.H{"code": jpy.Code, "symbol": jpy.Symbol},
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().CreateCurrencyTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				body := requireBodyMatchError(t, recorder, "invalid_argument")
+				require.Equal(t, map[string]any{"exponent": "required"}, body.Details["fields"])
+			},
+		},
+		{
+			"CreateInvalidCode",
+			http.MethodPost,
+			"/admin/currencies",
+			gin.H{"code": "XXZ", "exponent": 2, "symbol": "$"},
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().CreateCurrencyTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			"CreateDuplicate",
+			http.MethodPost,
+			"/admin/currencies",
+			gin.H{"code": usd.Code, "exponent": 2, "symbol": usd.Symbol},
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().CreateCurrencyTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Currency{}, &pq.Error{Code: "23505", Constraint: "currencies_pkey"})
+				store.EXPECT().ListCurrencies(gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireBodyMatchError(t, recorder, "already_exists")
+			},
+		},
+		{
+			"CreateOperatorForbidden",
+			http.MethodPost,
+			"/admin/currencies",
+			gin.H{"code": jpy.Code, "exponent": 0, "symbol": jpy.Symbol},
+			authAs(util.OperatorRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().CreateCurrencyTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			"DisableOK",
+			http.MethodPost,
+			"/admin/currencies/JPY/disable",
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				arg := db.SetCurrencyEnabledParams{Code: jpy.Code, Enabled: false}
+				store.EXPECT().SetCurrencyEnabledTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(disabledJPY, nil)
+				store.EXPECT().ListCurrencies(gomock.Any()).
+					Times(1).
+					Return([]db.Currency{disabledJPY, usd}, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp currencyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.False(t, rsp.Enabled)
+				require.False(t, util.IsSupportedCurrency(jpy.Code))
+			},
+		},
+		{
+			"EnableOK",
+			http.MethodPost,
+			"/admin/currencies/JPY/enable",
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				arg := db.SetCurrencyEnabledParams{Code: jpy.Code, Enabled: true}
+				store.EXPECT().SetCurrencyEnabledTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(jpy, nil)
+				store.EXPECT().ListCurrencies(gomock.Any()).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// the change is saved even if the cache could not be reloaded
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			"EnableNotFound",
+			http.MethodPost,
+			"/admin/currencies/GBP/enable",
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().SetCurrencyEnabledTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Currency{}, sql.ErrNoRows)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				body := requireBodyMatchError(t, recorder, "not_found")
+				require.Equal(t, "currency not found", body.Message)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, buildRequestBody(tc.body))
+			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCurrencyValidator(t *testing.T) {
+	defaults := util.SupportedCurrencies()
+	t.Cleanup(func() { util.SetCurrencies(defaults) })
+
+	// building a server registers the validator
+	newTestServer(t, mockdb.NewMockStore(gomock.NewController(t)))
+
+	type request struct {
+		Currency string `json:"currency" binding:"required,currency"`
+	}
+
+	validate := func(currency string) error {
+		return binding.Validator.ValidateStruct(request{Currency: currency})
+	}
+
+	require.NoError(t, validate(util.USD))
+	require.Error(t, validate("JPY"))
+
+	util.SetCurrencies([]util.Currency{{Code: "JPY", Exponent: 0, Symbol: "¥", Enabled: true}})
+	require.NoError(t, validate("JPY"))
+	require.Error(t, validate(util.USD))
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type openAPISpec struct {
+	OpenAPI string                                `json:"openapi"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// ginParam matches gin path parameters such as :id
+var ginParam = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/openapi.json", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var spec openAPISpec
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &spec))
+	require.True(t, strings.HasPrefix(spec.OpenAPI, "3."))
+
+	documented := map[string]bool{}
+	for _, route := range server.router.Routes() {
+		if route.Path == "/openapi.json" || route.Path == "/docs" || docsAssets[route.Path] != nil {
+			continue
+		}
+
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		method := strings.ToLower(route.Method)
+		_, ok := spec.Paths[path][method]
+		require.Truef(t, ok, "%s %s is missing from docs/openapi.json", route.Method, path)
+		documented[method+" "+path] = true
+	}
+
+	// and nothing documented that the router doesn't serve
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			require.Truef(t, documented[method+" "+path], "%s %s is documented but not routed", method, path)
+		}
+	}
+}
+
+func TestDocs(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/docs", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Header().Get("Content-Type"), "text/html")
+	require.Contains(t, recorder.Body.String(), "/openapi.json")
+	require.NotContains(t, recorder.Body.String(), "https://")
+
+	// the page loads its assets from the server, not a CDN
+	assets := []struct {
+		path        string
+		contentType string
+		marker      string
+	}{
+		{"/docs/swagger-ui.css", "text/css", ".swagger-ui"},
+		{"/docs/swagger-ui-bundle.js", "text/javascript", "SwaggerUIBundle"},
+	}
+	for _, asset := range assets {
+		require.Contains(t, recorder.Body.String(), asset.path)
+
+		assetRecorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, asset.path, nil)
+		require.NoError(t, err)
+
+		server.router.ServeHTTP(assetRecorder, request)
+		require.Equal(t, http.StatusOK, assetRecorder.Code, asset.path)
+		require.Contains(t, assetRecorder.Header().Get("Content-Type"), asset.contentType)
+		require.Contains(t, assetRecorder.Body.String(), asset.marker)
+	}
+}
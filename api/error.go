@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/julkar-naim/simple-bank/apperr"
+	"github.com/julkar-naim/simple-bank/util"
+	"reflect"
+	"strings"
+)
+
+// errorBody is the envelope of every error response:
+//
+//	{"error": {"code": "not_found", "message": "account not found", "request_id": "..."}}
+type errorBody struct {
+	Code      apperr.Code    `json:"code"`
+	Message   string         `json:"message"`
+	Details   map[string]any `json:"details,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+}
+
+type errorEnvelope struct {
+	Error errorBody `json:"error"`
+}
+
+// renderError writes err as an error response. Errors that are not an
+// *apperr.Error are translated as store errors. The full error, including
+// any cause hidden from the client, is attached to the context for the
+// logger middleware.
+func renderError(ctx *gin.Context, err error) {
+	status, body := newErrorResponse(ctx, err)
+	ctx.JSON(status, body)
+}
+
+// abortWithError is renderError for middleware: it also stops the chain
+func abortWithError(ctx *gin.Context, err error) {
+	status, body := newErrorResponse(ctx, err)
+	ctx.AbortWithStatusJSON(status, body)
+}
+
+func newErrorResponse(ctx *gin.Context, err error) (int, errorEnvelope) {
+	_ = ctx.Error(err)
+
+	appErr := apperr.Translate(err, "")
+
+	// a statement cancelled on behalf of the request context was cancelled
+	// by the client if the context says so, not by the deadline
+	if appErr.Code == apperr.CodeDeadlineExceeded && errors.Is(ctx.Request.Context().Err(), context.Canceled) {
+		appErr = apperr.Wrap(err, apperr.CodeCanceled, "request was cancelled")
+	}
+
+	return appErr.HTTPStatus(), errorEnvelope{
+		Error: errorBody{
+			Code:      appErr.Code,
+			Message:   appErr.Message,
+			Details:   appErr.Details,
+			RequestID: util.RequestIDFromContext(ctx.Request.Context()),
+		},
+	}
+}
+
+// registerFieldNames makes validation errors name fields the way clients
+// send them, by their json, uri or form tag
+func registerFieldNames() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "uri", "form"} {
+				name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+				if name != "" && name != "-" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
+}
+
+// bindingError turns a request binding error into an invalid argument error,
+// listing the failed validation rule of each field in its details
+func bindingError(err error) *apperr.Error {
+	appErr := apperr.Wrap(err, apperr.CodeInvalidArgument, err.Error())
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		appErr.Message = "invalid request"
+		fields := make(map[string]string, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			fields[fieldErr.Field()] = fieldErr.Tag()
+		}
+		appErr.WithDetail("fields", fields)
+	}
+	return appErr
+}
+
+// fieldError reports a field that failed a check binding can't express,
+// in the same shape as bindingError
+func fieldError(field string, tag string) *apperr.Error {
+	return apperr.New(apperr.CodeInvalidArgument, "invalid request").
+		WithDetail("fields", map[string]string{field: tag})
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/julkar-naim/simple-bank/apperr"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRenderError(t *testing.T) {
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	expiredCtx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	testCases := []struct {
+		name       string
+		requestCtx context.Context
+		err        error
+		status     int
+		code       apperr.Code
+		message    string
+	}{
+		{"AppError", context.Background(), apperr.New(apperr.CodePermissionDenied, "not yours"), http.StatusForbidden, apperr.CodePermissionDenied, "not yours"},
+		{"NoRows", context.Background(), sql.ErrNoRows, http.StatusNotFound, apperr.CodeNotFound, "resource not found"},
+		{"Translated", context.Background(), apperr.Translate(sql.ErrNoRows, "account"), http.StatusNotFound, apperr.CodeNotFound, "account not found"},
+		{"ForeignKeyViolation", context.Background(), &pq.Error{Code: "23503", Message: "raw driver message"}, http.StatusUnprocessableEntity, apperr.CodeFailedPrecondition, "resource references a record that does not exist or is still referenced by other records"},
+		{"Canceled", context.Background(), fmt.Errorf("tx: %w", context.Canceled), apperr.StatusClientClosedRequest, apperr.CodeCanceled, "request was cancelled"},
+		{"DeadlineExceeded", context.Background(), context.DeadlineExceeded, http.StatusGatewayTimeout, apperr.CodeDeadlineExceeded, "request timed out"},
+		{"QueryCanceledByClient", canceledCtx, &pq.Error{Code: "57014"}, apperr.StatusClientClosedRequest, apperr.CodeCanceled, "request was cancelled"},
+		{"QueryCanceledByDeadline", expiredCtx, &pq.Error{Code: "57014"}, http.StatusGatewayTimeout, apperr.CodeDeadlineExceeded, "request timed out"},
+		{"Internal", context.Background(), errors.New("connection reset by peer"), http.StatusInternalServerError, apperr.CodeInternal, "internal error"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			request, err := http.NewRequestWithContext(util.WithRequestID(tc.requestCtx, "req-1"), http.MethodGet, "/", nil)
+			require.NoError(t, err)
+			ctx.Request = request
+
+			renderError(ctx, tc.err)
+
+			require.Equal(t, tc.status, recorder.Code)
+			rsp := requireBodyMatchError(t, recorder, tc.code)
+			require.Equal(t, tc.message, rsp.Message)
+			require.Equal(t, "req-1", rsp.RequestID)
+
+			// the cause is kept for the logger but never sent to the client
+			require.Len(t, ctx.Errors, 1)
+			require.NotContains(t, recorder.Body.String(), "raw driver message")
+			require.NotContains(t, recorder.Body.String(), "connection reset")
+		})
+	}
+}
+
+func TestBindingError(t *testing.T) {
+	registerFieldNames()
+	registerValidators()
+
+	router := gin.New()
+	router.POST("/", func(ctx *gin.Context) {
+		var req createAccountRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			renderError(ctx, bindingError(err))
+			return
+		}
+	})
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/", buildRequestBody(gin.H{"currency": "XYZ"}))
+	require.NoError(t, err)
+	router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	rsp := requireBodyMatchError(t, recorder, apperr.CodeInvalidArgument)
+	require.Equal(t, "invalid request", rsp.Message)
+	require.Equal(t, map[string]any{"fields": map[string]any{"currency": "currency"}}, rsp.Details)
+}
+
+// requireBodyMatchError checks that the response is an error envelope with
+// the given code and returns its body
+func requireBodyMatchError(t *testing.T, recorder *httptest.ResponseRecorder, code apperr.Code) errorBody {
+	var rsp errorEnvelope
+	err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Equal(t, code, rsp.Error.Code)
+	require.NotEmpty(t, rsp.Error.Message)
+	return rsp.Error
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"net/http"
+	"time"
+)
+
+const (
+	checkStatusOK   = "ok"
+	checkStatusWarn = "warn"
+	checkStatusFail = "fail"
+)
+
+// readinessCheckTimeout bounds each dependency check so a hung database
+// makes the instance not-ready instead of hanging the probe
+const readinessCheckTimeout = 2 * time.Second
+
+// poolSaturationWarning is the share of the connection pool in use at
+// which the pool check warns. A saturated pool does not fail readiness,
+// since taking the instance out of rotation would only shift its load.
+const poolSaturationWarning = 0.9
+
+type checkResult struct {
+	Status    string         `json:"status"`
+	LatencyMs float64        `json:"latency_ms"`
+	Error     string         `json:"error,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+type healthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks,omitempty"`
+}
+
+// healthz reports that the process is alive. It does not touch any
+// dependency, so a database outage does not get the instance restarted.
+func (server *Server) healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, healthResponse{Status: checkStatusOK})
+}
+
+// readyz reports whether the instance can serve traffic
+func (server *Server) readyz(ctx *gin.Context) {
+	checks := map[string]checkResult{
+		"shutdown":   server.checkShutdown(),
+		"database":   runCheck(ctx.Request.Context(), server.checkDatabase),
+		"migrations": runCheck(ctx.Request.Context(), server.checkMigrations),
+		"pool":       server.checkPool(),
+	}
+
+	rsp := healthResponse{Status: checkStatusOK, Checks: checks}
+	status := http.StatusOK
+	for _, check := range checks {
+		if check.Status == checkStatusFail {
+			rsp.Status = checkStatusFail
+			status = http.StatusServiceUnavailable
+		}
+	}
+	ctx.JSON(status, rsp)
+}
+
+// runCheck times check and turns its error into a failed result
+func runCheck(ctx context.Context, check func(ctx context.Context) (map[string]any, error)) checkResult {
+	ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	details, err := check(ctx)
+	result := checkResult{
+		Status:    checkStatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		Details:   details,
+	}
+	if err != nil {
+		result.Status = checkStatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (server *Server) checkShutdown() checkResult {
+	if server.draining.Load() {
+		return checkResult{Status: checkStatusFail, Error: "server is shutting down"}
+	}
+	return checkResult{Status: checkStatusOK}
+}
+
+func (server *Server) checkDatabase(ctx context.Context) (map[string]any, error) {
+	return nil, server.store.Ping(ctx)
+}
+
+func (server *Server) checkMigrations(ctx context.Context) (map[string]any, error) {
+	version, dirty, err := server.store.MigrationVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]any{
+		"version":  version,
+		"expected": db.SchemaVersion,
+		"dirty":    dirty,
+	}
+	if dirty {
+		return details, fmt.Errorf("migration %d is dirty", version)
+	}
+	if version != db.SchemaVersion {
+		return details, fmt.Errorf("schema version %d does not match expected version %d", version, db.SchemaVersion)
+	}
+	return details, nil
+}
+
+func (server *Server) checkPool() checkResult {
+	stats := server.store.PoolStats()
+	result := checkResult{
+		Status: checkStatusOK,
+		Details: map[string]any{
+			"open":          stats.OpenConnections,
+			"in_use":        stats.InUse,
+			"idle":          stats.Idle,
+			"max_open":      stats.MaxOpenConnections,
+			"wait_count":    stats.WaitCount,
+			"wait_duration": stats.WaitDuration.String(),
+		},
+	}
+
+	// an unlimited pool cannot saturate
+	if stats.MaxOpenConnections > 0 {
+		saturation := float64(stats.InUse) / float64(stats.MaxOpenConnections)
+		result.Details["saturation"] = saturation
+		if saturation >= poolSaturationWarning {
+			result.Status = checkStatusWarn
+		}
+	}
+	return result
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthz(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// liveness must not depend on the database
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/healthz", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"status":"ok"}`, recorder.Body.String())
+}
+
+func TestReadyz(t *testing.T) {
+	testCases := []struct {
+		name          string
+		draining      bool
+		buildStub     func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			"OK",
+			false,
+			func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(db.SchemaVersion), false, nil)
+				store.EXPECT().PoolStats().Times(1).Return(sql.DBStats{MaxOpenConnections: 10, InUse: 2})
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				rsp := requireBodyMatchHealth(t, recorder)
+				require.Equal(t, checkStatusOK, rsp.Status)
+				for name, check := range rsp.Checks {
+					require.Equal(t, checkStatusOK, check.Status, name)
+				}
+				require.Equal(t, 0.2, rsp.Checks["pool"].Details["saturation"])
+			},
+		},
+		{
+			"DatabaseDown",
+			false,
+			func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(errors.New("connection refused"))
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(0), false, errors.New("connection refused"))
+				store.EXPECT().PoolStats().Times(1).Return(sql.DBStats{})
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				rsp := requireBodyMatchHealth(t, recorder)
+				require.Equal(t, checkStatusFail, rsp.Status)
+				require.Equal(t, checkStatusFail, rsp.Checks["database"].Status)
+				require.Equal(t, "connection refused", rsp.Checks["database"].Error)
+			},
+		},
+		{
+			"MigrationMismatch",
+			false,
+			func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(db.SchemaVersion-1), false, nil)
+				store.EXPECT().PoolStats().Times(1).Return(sql.DBStats{})
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				rsp := requireBodyMatchHealth(t, recorder)
+				require.Equal(t, checkStatusFail, rsp.Checks["migrations"].Status)
+				require.EqualValues(t, db.SchemaVersion-1, rsp.Checks["migrations"].Details["version"])
+				require.EqualValues(t, db.SchemaVersion, rsp.Checks["migrations"].Details["expected"])
+			},
+		},
+		{
+			"DirtyMigration",
+			false,
+			func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(db.SchemaVersion), true, nil)
+				store.EXPECT().PoolStats().Times(1).Return(sql.DBStats{})
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				rsp := requireBodyMatchHealth(t, recorder)
+				require.Equal(t, checkStatusFail, rsp.Checks["migrations"].Status)
+			},
+		},
+		{
+			"PoolSaturated",
+			false,
+			func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(db.SchemaVersion), false, nil)
+				store.EXPECT().PoolStats().Times(1).Return(sql.DBStats{MaxOpenConnections: 10, InUse: 10, WaitCount: 3})
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				rsp := requireBodyMatchHealth(t, recorder)
+				require.Equal(t, checkStatusOK, rsp.Status)
+				require.Equal(t, checkStatusWarn, rsp.Checks["pool"].Status)
+			},
+		},
+		{
+			"ShuttingDown",
+			true,
+			func(store *mockdb.MockStore) {
+				store.EXPECT().Ping(gomock.Any()).Times(1).Return(nil)
+				store.EXPECT().MigrationVersion(gomock.Any()).Times(1).Return(int64(db.SchemaVersion), false, nil)
+				store.EXPECT().PoolStats().Times(1).Return(sql.DBStats{})
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+				rsp := requireBodyMatchHealth(t, recorder)
+				require.Equal(t, checkStatusFail, rsp.Checks["shutdown"].Status)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+
+			server := newTestServer(t, store)
+			server.draining.Store(tc.draining)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestShutdownMarksNotReady(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	require.Equal(t, checkStatusOK, server.checkShutdown().Status)
+
+	require.NoError(t, server.Shutdown(context.Background()))
+	require.Equal(t, checkStatusFail, server.checkShutdown().Status)
+}
+
+func requireBodyMatchHealth(t *testing.T, recorder *httptest.ResponseRecorder) healthResponse {
+	var rsp healthResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	return rsp
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	testCases := []struct {
+		name          string
+		requestID     string
+		checkResponse func(t *testing.T, requestID string)
+	}{
+		{
+			"Honored",
+			"client-id-1",
+			func(t *testing.T, requestID string) {
+				require.Equal(t, "client-id-1", requestID)
+			},
+		},
+		{
+			"Generated",
+			"",
+			func(t *testing.T, requestID string) {
+				require.Len(t, requestID, 36)
+			},
+		},
+		{
+			"InvalidReplaced",
+			"bad id\n",
+			func(t *testing.T, requestID string) {
+				require.Len(t, requestID, 36)
+			},
+		},
+		{
+			"TooLongReplaced",
+			strings.Repeat("a", maxRequestIDLength+1),
+			func(t *testing.T, requestID string) {
+				require.Len(t, requestID, 36)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(requestIDMiddleware())
+			router.GET("/id", func(ctx *gin.Context) {
+				ctx.String(http.StatusOK, util.RequestIDFromContext(ctx.Request.Context()))
+			})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/id", nil)
+			require.NoError(t, err)
+			if tc.requestID != "" {
+				request.Header.Set(requestIDHeaderKey, tc.requestID)
+			}
+
+			router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			// the response header and the context agree
+			requestID := recorder.Header().Get(requestIDHeaderKey)
+			require.Equal(t, requestID, recorder.Body.String())
+			tc.checkResponse(t, requestID)
+		})
+	}
+}
+
+func TestRequestIDReachesStore(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount()
+	account.Owner = user.Username
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, id int64) (db.Account, error) {
+			require.Equal(t, "trace-me", util.RequestIDFromContext(ctx))
+			return account, nil
+		})
+
+	var buf bytes.Buffer
+	logger, err := util.NewLogger(&buf, "info", util.LogFormatJSON)
+	require.NoError(t, err)
+
+	server := newTestServer(t, store)
+	server.logger = logger
+	server.setupRouter()
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), nil)
+	require.NoError(t, err)
+	request.Header.Set(requestIDHeaderKey, "trace-me")
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "http request", record["msg"])
+	require.Equal(t, "trace-me", record["request_id"])
+	require.Equal(t, http.MethodGet, record["method"])
+	require.Equal(t, float64(http.StatusOK), record["status"])
+}
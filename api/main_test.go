@@ -2,10 +2,40 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/token"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
 	"os"
 	"testing"
+	"time"
 )
 
+// testAPIKeyEncryptionKey is shared by every test server so that API keys
+// sealed by a test can be opened by the server it calls
+var testAPIKeyEncryptionKey = util.RandomString(32)
+
+// testWebhookEncryptionKey is shared by every test server so that tests can
+// open the webhook secrets it seals
+var testWebhookEncryptionKey = util.RandomString(32)
+
+func newTestServer(t *testing.T, store db.Store) *Server {
+	config := util.Config{
+		TokenType:            token.TypePasetoV4,
+		TokenSymmetricKey:    util.RandomString(32),
+		APIKeyEncryptionKey:  testAPIKeyEncryptionKey,
+		WebhookEncryptionKey: testWebhookEncryptionKey,
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+		APISignatureMaxSkew:  5 * time.Minute,
+	}
+
+	server, err := NewServer(config, store)
+	require.NoError(t, err)
+
+	return server
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode)
 	os.Exit(m.Run())
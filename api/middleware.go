@@ -0,0 +1,233 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/julkar-naim/simple-bank/apperr"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/token"
+	"github.com/julkar-naim/simple-bank/util"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	authorizationHeaderKey  = "authorization"
+	authorizationTypeBearer = "bearer"
+	authorizationPayloadKey = "authorization_payload"
+)
+
+const (
+	apiKeyHeaderKey    = "X-Api-Key"
+	timestampHeaderKey = "X-Timestamp"
+	nonceHeaderKey     = "X-Nonce"
+	signatureHeaderKey = "X-Signature"
+	serviceIdentityKey = "service_identity"
+)
+
+// maxSignedBodyBytes bounds the body signatureMiddleware reads to check a
+// signature, which happens before the caller is authenticated
+const maxSignedBodyBytes = 64 << 10
+
+// serviceAuditActorPrefix marks audit log actors that are API keys rather than users
+const serviceAuditActorPrefix = "api_key:"
+
+// ServiceIdentity describes a caller authenticated with a signed API key request
+type ServiceIdentity struct {
+	KeyID  string
+	Name   string
+	Scopes []string
+}
+
+// authMiddleware rejects requests without a valid bearer token and stores
+// the token payload in the context under authorizationPayloadKey
+func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
+		if len(authorizationHeader) == 0 {
+			abortWithError(ctx, apperr.New(apperr.CodeUnauthenticated, "authorization header is not provided"))
+			return
+		}
+
+		fields := strings.Fields(authorizationHeader)
+		if len(fields) < 2 {
+			abortWithError(ctx, apperr.New(apperr.CodeUnauthenticated, "invalid authorization header format"))
+			return
+		}
+
+		authorizationType := strings.ToLower(fields[0])
+		if authorizationType != authorizationTypeBearer {
+			abortWithError(ctx, apperr.Newf(apperr.CodeUnauthenticated, "unsupported authorization type %s", authorizationType))
+			return
+		}
+
+		accessToken := fields[1]
+		payload, err := tokenMaker.VerifyToken(accessToken, token.TokenTypeAccessToken)
+		if err != nil {
+			abortWithError(ctx, apperr.New(apperr.CodeUnauthenticated, err.Error()))
+			return
+		}
+
+		ctx.Set(authorizationPayloadKey, payload)
+		setAuditActor(ctx, payload.Username)
+		ctx.Next()
+	}
+}
+
+// signatureMiddleware rejects requests without a valid HMAC-SHA256 signature
+// from an active API key and stores the caller's ServiceIdentity in the
+// context under serviceIdentityKey. Each nonce is accepted once per key, and
+// the timestamp must be within maxSkew of the server clock. Requests are
+// signed with the key's secret, which secretBox decrypts. A NonceCleaner
+// deletes the nonces once their timestamps are too old to pass.
+func signatureMiddleware(store db.Store, secretBox *util.SecretBox, maxSkew time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		keyID := ctx.GetHeader(apiKeyHeaderKey)
+		timestamp := ctx.GetHeader(timestampHeaderKey)
+		nonce := ctx.GetHeader(nonceHeaderKey)
+		signature := ctx.GetHeader(signatureHeaderKey)
+		if keyID == "" || timestamp == "" || nonce == "" || signature == "" {
+			abortWithError(ctx, apperr.New(apperr.CodeUnauthenticated, "signature headers are not provided"))
+			return
+		}
+
+		unixTime, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			abortWithError(ctx, apperr.New(apperr.CodeUnauthenticated, "invalid timestamp"))
+			return
+		}
+		skew := time.Since(time.Unix(unixTime, 0))
+		if skew > maxSkew || skew < -maxSkew {
+			abortWithError(ctx, apperr.New(apperr.CodeUnauthenticated, "timestamp is outside the allowed clock skew"))
+			return
+		}
+
+		apiKey, err := store.GetAPIKey(ctx.Request.Context(), keyID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				abortWithError(ctx, apperr.New(apperr.CodeUnauthenticated, "unknown api key"))
+				return
+			}
+			abortWithError(ctx, err)
+			return
+		}
+
+		if apiKey.IsRevoked {
+			abortWithError(ctx, apperr.New(apperr.CodeUnauthenticated, "api key is revoked"))
+			return
+		}
+
+		if time.Now().After(apiKey.ExpiresAt) {
+			abortWithError(ctx, apperr.New(apperr.CodeUnauthenticated, "api key is expired"))
+			return
+		}
+
+		var body []byte
+		if ctx.Request.Body != nil {
+			body, err = io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSignedBodyBytes))
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					abortWithError(ctx, apperr.Newf(apperr.CodeInvalidArgument, "request body is larger than %d bytes", maxSignedBodyBytes))
+					return
+				}
+				abortWithError(ctx, apperr.Wrap(err, apperr.CodeInvalidArgument, "cannot read request body"))
+				return
+			}
+			ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		secret, err := secretBox.Open(apiKey.SecretCiphertext, apiKey.KeyID)
+		if err != nil {
+			abortWithError(ctx, fmt.Errorf("cannot decrypt secret of api key %s: %w", apiKey.KeyID, err))
+			return
+		}
+
+		method := ctx.Request.Method
+		path := ctx.Request.URL.RequestURI()
+		if !util.CheckSignature(secret, signature, method, path, timestamp, nonce, body) {
+			abortWithError(ctx, apperr.New(apperr.CodeUnauthenticated, "signature mismatch"))
+			return
+		}
+
+		arg := db.CreateAPIKeyNonceParams{
+			KeyID: apiKey.KeyID,
+			Nonce: nonce,
+		}
+		err = store.CreateAPIKeyNonce(ctx.Request.Context(), arg)
+		if err != nil {
+			if apperr.Translate(err, "nonce").Code == apperr.CodeAlreadyExists {
+				abortWithError(ctx, apperr.New(apperr.CodeUnauthenticated, "nonce has already been used"))
+				return
+			}
+			abortWithError(ctx, err)
+			return
+		}
+
+		ctx.Set(serviceIdentityKey, &ServiceIdentity{
+			KeyID:  apiKey.KeyID,
+			Name:   apiKey.Name,
+			Scopes: apiKey.Scopes,
+		})
+		setAuditActor(ctx, serviceAuditActorPrefix+apiKey.KeyID)
+		ctx.Next()
+	}
+}
+
+// setAuditActor stores the caller and their IP in the request context so
+// that the store records them in the audit log
+func setAuditActor(ctx *gin.Context, name string) {
+	actor := db.AuditActor{
+		Name: name,
+		IP:   ctx.ClientIP(),
+	}
+	ctx.Request = ctx.Request.WithContext(db.WithAuditActor(ctx.Request.Context(), actor))
+}
+
+// userOrServiceMiddleware authenticates signed API key requests with
+// signatureMiddleware and everything else with authMiddleware
+func userOrServiceMiddleware(tokenMaker token.Maker, store db.Store, secretBox *util.SecretBox, maxSkew time.Duration) gin.HandlerFunc {
+	userAuth := authMiddleware(tokenMaker)
+	serviceAuth := signatureMiddleware(store, secretBox, maxSkew)
+	return func(ctx *gin.Context) {
+		if ctx.GetHeader(apiKeyHeaderKey) != "" {
+			serviceAuth(ctx)
+			return
+		}
+		userAuth(ctx)
+	}
+}
+
+// permissionMiddleware must run after authMiddleware or signatureMiddleware.
+// It rejects users whose role, or services whose scopes, lack any of the
+// given permissions.
+func permissionMiddleware(permissions ...util.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if value, ok := ctx.Get(serviceIdentityKey); ok {
+			identity := value.(*ServiceIdentity)
+			for _, permission := range permissions {
+				if !util.HasScope(identity.Scopes, permission) {
+					abortWithError(ctx, apperr.Newf(apperr.CodePermissionDenied, "api key %s lacks scope %s", identity.KeyID, permission))
+					return
+				}
+			}
+			ctx.Next()
+			return
+		}
+
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		for _, permission := range permissions {
+			if !util.HasPermission(authPayload.Role, permission) {
+				abortWithError(ctx, apperr.Newf(apperr.CodePermissionDenied, "role %s lacks permission %s", authPayload.Role, permission))
+				return
+			}
+		}
+		ctx.Next()
+	}
+}
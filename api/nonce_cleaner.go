@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"log/slog"
+	"time"
+)
+
+// NonceCleaner deletes the API key nonces that can no longer pass the
+// timestamp check of signatureMiddleware, so that the table of used nonces
+// stays small without a write on every signed request
+type NonceCleaner struct {
+	store   db.Store
+	maxSkew time.Duration
+	logger  *slog.Logger
+}
+
+// NewNonceCleaner creates a NonceCleaner for signatures checked with maxSkew.
+// It cleans up every maxSkew.
+func NewNonceCleaner(store db.Store, maxSkew time.Duration, logger *slog.Logger) *NonceCleaner {
+	return &NonceCleaner{
+		store:   store,
+		maxSkew: maxSkew,
+		logger:  logger,
+	}
+}
+
+// Run deletes expired nonces until ctx is done. A failed cleanup is retried
+// on the next tick.
+func (cleaner *NonceCleaner) Run(ctx context.Context) error {
+	ticker := time.NewTicker(cleaner.maxSkew)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			// nonces older than twice the skew can no longer pass the timestamp check
+			err := cleaner.store.DeleteAPIKeyNonces(ctx, time.Now().Add(-2*cleaner.maxSkew))
+			if err != nil && ctx.Err() == nil {
+				cleaner.logger.Warn("cannot delete api key nonces", "error", err)
+			}
+		}
+	}
+}
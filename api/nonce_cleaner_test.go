@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestNonceCleanerRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	maxSkew := 10 * time.Millisecond
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().DeleteAPIKeyNonces(gomock.Any(), gomock.Any()).
+		MinTimes(1).
+		DoAndReturn(func(_ context.Context, createdAt time.Time) error {
+			cancel()
+			require.WithinDuration(t, time.Now().Add(-2*maxSkew), createdAt, maxSkew)
+			return nil
+		})
+
+	cleaner := NewNonceCleaner(store, maxSkew, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	done := make(chan error, 1)
+	go func() { done <- cleaner.Run(ctx) }()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("nonce cleaner did not stop after its context was canceled")
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/julkar-naim/simple-bank/apperr"
+	"github.com/julkar-naim/simple-bank/ratelimit"
+	"github.com/julkar-naim/simple-bank/token"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	rateLimitLimitHeader     = "RateLimit-Limit"
+	rateLimitRemainingHeader = "RateLimit-Remaining"
+	rateLimitResetHeader     = "RateLimit-Reset"
+	retryAfterHeader         = "Retry-After"
+)
+
+// rateLimitMiddleware takes a token from the bucket of the caller for the
+// matched route, using the route's limit from routeLimits or defaultLimit.
+// Callers are identified by user, then API key, then client IP, so it must
+// run after the authentication middleware of the route. When the store
+// fails the request is let through, since an outage of the limiter should
+// not take the API down with it.
+func rateLimitMiddleware(store ratelimit.Store, defaultLimit ratelimit.Limit, routeLimits map[string]ratelimit.Limit, logger *slog.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		route := ratelimit.RouteKey(ctx.Request.Method, ctx.FullPath())
+		limit, ok := routeLimits[route]
+		if !ok {
+			limit = defaultLimit
+		}
+		if limit.Unlimited() {
+			ctx.Next()
+			return
+		}
+
+		result, err := store.Take(ctx.Request.Context(), route+"|"+rateLimitIdentity(ctx), limit)
+		if err != nil {
+			logger.WarnContext(ctx.Request.Context(), "rate limiter unavailable", "route", route, "error", err)
+			ctx.Next()
+			return
+		}
+
+		ctx.Header(rateLimitLimitHeader, strconv.Itoa(result.Limit))
+		ctx.Header(rateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+		ctx.Header(rateLimitResetHeader, ceilSeconds(result.Reset))
+
+		if !result.Allowed {
+			ctx.Header(retryAfterHeader, ceilSeconds(result.RetryAfter))
+			abortWithError(ctx, apperr.New(apperr.CodeRateLimited, "rate limit exceeded").
+				WithDetail("retry_after_seconds", int(math.Ceil(result.RetryAfter.Seconds()))))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// rateLimitIdentity returns the key of the caller whose bucket is used
+func rateLimitIdentity(ctx *gin.Context) string {
+	if value, ok := ctx.Get(authorizationPayloadKey); ok {
+		return "user:" + value.(*token.Payload).Username
+	}
+	if value, ok := ctx.Get(serviceIdentityKey); ok {
+		return "api_key:" + value.(*ServiceIdentity).KeyID
+	}
+	return "ip:" + ctx.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/julkar-naim/simple-bank/apperr"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/ratelimit"
+	"github.com/julkar-naim/simple-bank/token"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitByIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.User{}, sql.ErrNoRows)
+
+	server := newTestServer(t, store)
+	server.routeRateLimits = map[string]ratelimit.Limit{
+		"POST /users/login": {Rate: 1.0 / 60, Burst: 1},
+	}
+	server.setupRouter()
+
+	login := func() *httptest.ResponseRecorder {
+		body, err := json.Marshal(gin.H{"username": "alice", "password": "secret"})
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(body))
+		require.NoError(t, err)
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := login()
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	require.Equal(t, "1", recorder.Header().Get(rateLimitLimitHeader))
+	require.Equal(t, "0", recorder.Header().Get(rateLimitRemainingHeader))
+	require.Equal(t, "60", recorder.Header().Get(rateLimitResetHeader))
+
+	recorder = login()
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "60", recorder.Header().Get(retryAfterHeader))
+	require.Equal(t, "0", recorder.Header().Get(rateLimitRemainingHeader))
+	rsp := requireBodyMatchError(t, recorder, apperr.CodeRateLimited)
+	require.EqualValues(t, 60, rsp.Details["retry_after_seconds"])
+}
+
+func TestRateLimitIgnoresForgedForwardedFor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(db.User{}, sql.ErrNoRows)
+
+	newServer := func(trustedProxies string) *Server {
+		server := newTestServer(t, store)
+		server.routeRateLimits = map[string]ratelimit.Limit{
+			"POST /users/login": {Rate: 1.0 / 60, Burst: 1},
+		}
+		var err error
+		server.trustedProxies, err = parseTrustedProxies(trustedProxies)
+		require.NoError(t, err)
+		server.setupRouter()
+		return server
+	}
+
+	login := func(server *Server, forwardedFor string) *httptest.ResponseRecorder {
+		body, err := json.Marshal(gin.H{"username": "alice", "password": "secret"})
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(body))
+		require.NoError(t, err)
+		request.RemoteAddr = "10.0.0.1:1234"
+		request.Header.Set("X-Forwarded-For", forwardedFor)
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// by default no proxy is trusted, so a forged header does not get a
+	// fresh bucket
+	server := newServer("")
+	require.Equal(t, http.StatusNotFound, login(server, "203.0.113.1").Code)
+	require.Equal(t, http.StatusTooManyRequests, login(server, "203.0.113.2").Code)
+
+	// behind a trusted proxy, the forwarded client IP picks the bucket
+	server = newServer("10.0.0.0/8")
+	require.Equal(t, http.StatusNotFound, login(server, "203.0.113.1").Code)
+	require.Equal(t, http.StatusNotFound, login(server, "203.0.113.2").Code)
+	require.Equal(t, http.StatusTooManyRequests, login(server, "203.0.113.2").Code)
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	alice, _ := randomUser(t)
+	bob, _ := randomUser(t)
+	account := randomAccount()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(2).
+		Return(account, nil)
+
+	server := newTestServer(t, store)
+	server.defaultRateLimit = ratelimit.Limit{Rate: 1, Burst: 1}
+	server.setupRouter()
+
+	getAccount := func(user db.User) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), nil)
+		require.NoError(t, err)
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// alice and bob have their own buckets
+	require.NotEqual(t, http.StatusTooManyRequests, getAccount(alice).Code)
+	require.Equal(t, http.StatusTooManyRequests, getAccount(alice).Code)
+	require.NotEqual(t, http.StatusTooManyRequests, getAccount(bob).Code)
+
+	// operational routes are not limited
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/healthz", nil)
+	require.NoError(t, err)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Empty(t, recorder.Header().Get(rateLimitLimitHeader))
+}
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func TestRateLimitStoreFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	server.rateLimitStore = failingRateLimitStore{}
+	server.defaultRateLimit = ratelimit.Limit{Rate: 1, Burst: 1}
+	server.setupRouter()
+
+	// the request goes through to the handler, which rejects the bad body
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/users", bytes.NewReader([]byte("{}")))
+	require.NoError(t, err)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestRateLimitIdentity(t *testing.T) {
+	newContext := func() *gin.Context {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		request, err := http.NewRequest(http.MethodGet, "/", nil)
+		require.NoError(t, err)
+		request.RemoteAddr = "10.0.0.1:1234"
+		ctx.Request = request
+		return ctx
+	}
+
+	ctx := newContext()
+	require.Equal(t, "ip:10.0.0.1", rateLimitIdentity(ctx))
+
+	ctx = newContext()
+	ctx.Set(serviceIdentityKey, &ServiceIdentity{KeyID: "abc123"})
+	require.Equal(t, "api_key:abc123", rateLimitIdentity(ctx))
+
+	ctx = newContext()
+	ctx.Set(authorizationPayloadKey, &token.Payload{Username: "alice"})
+	require.Equal(t, "user:alice", rateLimitIdentity(ctx))
+}
+
+func TestNewServerRateLimitConfig(t *testing.T) {
+	server := newTestServer(t, nil)
+	require.True(t, server.defaultRateLimit.Unlimited())
+	require.Empty(t, server.routeRateLimits)
+
+	config := server.config
+	config.RateLimitStore = "redis"
+	_, err := NewServer(config, nil)
+	require.Error(t, err)
+
+	config = server.config
+	config.RateLimitRoutes = "POST /transfers=fast"
+	_, err = NewServer(config, nil)
+	require.Error(t, err)
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := parseTrustedProxies("")
+	require.NoError(t, err)
+	require.Nil(t, proxies)
+
+	proxies, err = parseTrustedProxies(" 10.0.0.0/8, 192.0.2.7 ,::1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"10.0.0.0/8", "192.0.2.7", "::1"}, proxies)
+
+	_, err = parseTrustedProxies("10.0.0.0/8,proxy.internal")
+	require.Error(t, err)
+
+	config := newTestServer(t, nil).config
+	config.TrustedProxies = "not-an-ip"
+	_, err = NewServer(config, nil)
+	require.Error(t, err)
+}
@@ -1,35 +1,245 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/ratelimit"
+	"github.com/julkar-naim/simple-bank/stream"
+	"github.com/julkar-naim/simple-bank/telemetry"
+	"github.com/julkar-naim/simple-bank/token"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 type Server struct {
-	store  db.Store
-	router *gin.Engine
+	config     util.Config
+	store      db.Store
+	tokenMaker token.Maker
+	// secretBox encrypts API key secrets, which sign service requests
+	secretBox *util.SecretBox
+	// webhookSecretBox encrypts webhook secrets, which sign deliveries
+	webhookSecretBox *util.SecretBox
+	logger           *slog.Logger
+	router           *gin.Engine
+	httpServer       *http.Server
+	draining         atomic.Bool
+
+	// accountHub feeds account streams, which end when streamsCtx is done
+	accountHub  *stream.Hub
+	streamsCtx  context.Context
+	stopStreams context.CancelFunc
+
+	rateLimitStore   ratelimit.Store
+	defaultRateLimit ratelimit.Limit
+	routeRateLimits  map[string]ratelimit.Limit
+
+	// trustedProxies may set X-Forwarded-For; with none, the client IP
+	// used for rate limits and the audit log is the peer address
+	trustedProxies []string
+}
+
+func NewServer(config util.Config, store db.Store) (*Server, error) {
+	tokenMaker, err := token.NewMaker(config.TokenType, config.TokenSymmetricKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create token maker: %w", err)
+	}
+
+	secretBox, err := util.NewSecretBox(config.APIKeyEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create api key secret box: %w", err)
+	}
+	webhookSecretBox, err := util.NewSecretBox(config.WebhookEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create webhook secret box: %w", err)
+	}
+
+	rateLimitStore, err := ratelimit.NewStore(config.RateLimitStore, store)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate limit store: %w", err)
+	}
+	defaultRateLimit, err := ratelimit.ParseLimit(config.RateLimitDefault)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse default rate limit: %w", err)
+	}
+	routeRateLimits, err := ratelimit.ParseRouteLimits(config.RateLimitRoutes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse route rate limits: %w", err)
+	}
+	trustedProxies, err := parseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse trusted proxies: %w", err)
+	}
+
+	registerFieldNames()
+	registerValidators()
+
+	streamsCtx, stopStreams := context.WithCancel(context.Background())
+
+	server := &Server{
+		config:           config,
+		store:            store,
+		tokenMaker:       tokenMaker,
+		secretBox:        secretBox,
+		webhookSecretBox: webhookSecretBox,
+		logger:           slog.Default(),
+		httpServer:       &http.Server{ReadHeaderTimeout: 10 * time.Second},
+		rateLimitStore:   rateLimitStore,
+		defaultRateLimit: defaultRateLimit,
+		routeRateLimits:  routeRateLimits,
+		trustedProxies:   trustedProxies,
+		accountHub:       stream.NewHub(),
+		streamsCtx:       streamsCtx,
+		stopStreams:      stopStreams,
+	}
+	server.setupRouter()
+	return server, nil
+}
+
+// parseTrustedProxies parses a comma separated list of proxy IPs and CIDRs
+func parseTrustedProxies(s string) ([]string, error) {
+	var proxies []string
+	for _, proxy := range strings.Split(s, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return nil, fmt.Errorf("invalid proxy %q: must be an IP or CIDR", proxy)
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies, nil
 }
 
-func NewServer(store db.Store) *Server {
-	server := &Server{store: store}
-	router := gin.Default()
+func (server *Server) setupRouter() {
+	router := gin.New()
+	// the proxies were validated by NewServer, so this cannot fail
+	_ = router.SetTrustedProxies(server.trustedProxies)
+	router.Use(
+		otelgin.Middleware(telemetry.ServiceName),
+		requestIDMiddleware(),
+		loggerMiddleware(server.logger),
+		metricsMiddleware(),
+		gin.Recovery(),
+		timeoutMiddleware(server.config.RequestTimeout, map[string]time.Duration{
+			"/transfers":            server.config.TransferTimeout,
+			"/admin/accounts":       server.config.ReportTimeout,
+			"/admin/reconciliation": server.config.ReportTimeout,
+			// streams are bounded by their access token instead
+			"/accounts/:id/stream": 0,
+		}),
+	)
+
+	router.GET("/healthz", server.healthz)
+	router.GET("/readyz", server.readyz)
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.GET("/openapi.json", serveOpenAPI)
+	router.GET("/docs", serveDocs)
+	for path, handler := range docsAssets {
+		router.GET(path, handler)
+	}
+
+	rateLimit := rateLimitMiddleware(server.rateLimitStore, server.defaultRateLimit, server.routeRateLimits, server.logger)
+
+	publicRoutes := router.Group("/").Use(rateLimit)
+
+	publicRoutes.POST("/users", server.createUser)
+	publicRoutes.POST("/users/login", server.loginUser)
+	publicRoutes.POST("/users/logout", server.logoutUser)
+	publicRoutes.POST("/tokens/renew_access", server.renewAccessToken)
+	publicRoutes.GET("/currencies", server.listCurrencies)
+
+	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker), rateLimit)
+
+	authRoutes.POST("/accounts", server.createAccount)
+	authRoutes.GET("/accounts", server.getAccountList)
+	authRoutes.GET("/accounts/:id", server.getAccount)
+	authRoutes.GET("/accounts/:id/stream", server.streamAccount)
+	authRoutes.GET("/accounts/delete/:id", server.deleteAccount)
+
+	authRoutes.POST("/transfers", server.createTransfer)
+
+	userOrServiceAuth := userOrServiceMiddleware(server.tokenMaker, server.store, server.secretBox, server.config.APISignatureMaxSkew)
 
-	router.POST("/accounts", server.createAccount)
-	router.POST("/accounts/update", server.updateAccount)
-	router.GET("/accounts", server.getAccountList)
-	router.GET("/accounts/:id", server.getAccount)
-	router.GET("/accounts/delete/:id", server.deleteAccount)
+	// routes guarded by a permission that API keys may be granted as a
+	// scope accept signed requests as well as bearer tokens
+	serviceRoutes := router.Group("/").Use(userOrServiceAuth, rateLimit)
+
+	serviceRoutes.POST("/accounts/update", permissionMiddleware(util.PermAdjustAccounts), server.updateAccount)
+
+	adminRoutes := router.Group("/admin").Use(userOrServiceAuth, rateLimit)
+
+	adminRoutes.GET("/accounts", permissionMiddleware(util.PermListAllAccounts), server.listAllAccounts)
+	adminRoutes.POST("/accounts/:id/freeze", permissionMiddleware(util.PermFreezeAccounts), server.freezeAccount)
+	adminRoutes.POST("/accounts/:id/unfreeze", permissionMiddleware(util.PermFreezeAccounts), server.unfreezeAccount)
+	adminRoutes.GET("/reconciliation", permissionMiddleware(util.PermReconcile), server.listUnreconciledAccounts)
+	adminRoutes.POST("/users/role", permissionMiddleware(util.PermManageRoles), server.updateUserRole)
+	adminRoutes.POST("/api_keys", permissionMiddleware(util.PermManageAPIKeys), server.createAPIKey)
+	adminRoutes.GET("/api_keys", permissionMiddleware(util.PermManageAPIKeys), server.listAPIKeys)
+	adminRoutes.POST("/api_keys/:key_id/revoke", permissionMiddleware(util.PermManageAPIKeys), server.revokeAPIKey)
+	adminRoutes.GET("/currencies", permissionMiddleware(util.PermManageCurrencies), server.listAllCurrencies)
+	adminRoutes.POST("/currencies", permissionMiddleware(util.PermManageCurrencies), server.createCurrency)
+	adminRoutes.POST("/currencies/:code/enable", permissionMiddleware(util.PermManageCurrencies), server.enableCurrency)
+	adminRoutes.POST("/currencies/:code/disable", permissionMiddleware(util.PermManageCurrencies), server.disableCurrency)
+	adminRoutes.POST("/webhooks", permissionMiddleware(util.PermManageWebhooks), server.createWebhook)
+	adminRoutes.GET("/webhooks", permissionMiddleware(util.PermManageWebhooks), server.listWebhooks)
+	adminRoutes.GET("/webhooks/:id", permissionMiddleware(util.PermManageWebhooks), server.getWebhook)
+	adminRoutes.POST("/webhooks/:id/enable", permissionMiddleware(util.PermManageWebhooks), server.enableWebhook)
+	adminRoutes.POST("/webhooks/:id/disable", permissionMiddleware(util.PermManageWebhooks), server.disableWebhook)
+	adminRoutes.GET("/webhooks/:id/deliveries", permissionMiddleware(util.PermManageWebhooks), server.listWebhookDeliveries)
+	adminRoutes.GET("/webhook_deliveries/:id", permissionMiddleware(util.PermManageWebhooks), server.getWebhookDelivery)
+	adminRoutes.POST("/webhook_deliveries/:id/retry", permissionMiddleware(util.PermManageWebhooks), server.retryWebhookDelivery)
+	adminRoutes.GET("/audit_logs", permissionMiddleware(util.PermReadAuditLog), server.listAuditLogs)
 
 	server.router = router
-	return server
 }
 
+// Start serves HTTP requests on address until Shutdown is called, in which
+// case it returns nil
 func (server *Server) Start(address string) error {
-	return server.router.Run(address)
+	server.httpServer.Addr = address
+	server.httpServer.Handler = server.router
+
+	err := server.httpServer.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 }
 
-func errorResponse(err error) gin.H {
-	return gin.H{
-		"error": err.Error(),
+// RateLimitStore returns the store of the rate limit buckets, for the gRPC
+// server to share
+func (server *Server) RateLimitStore() ratelimit.Store {
+	return server.rateLimitStore
+}
+
+// AccountHub returns the hub that account streams subscribe to, for a
+// stream.Listener to publish into
+func (server *Server) AccountHub() *stream.Hub {
+	return server.accountHub
+}
+
+// Shutdown marks the server not-ready and ends open account streams so their
+// clients reconnect elsewhere. After the configured drain delay gives load
+// balancers time to notice, it stops accepting connections and waits for
+// in-flight requests to finish, or for ctx to be done.
+func (server *Server) Shutdown(ctx context.Context) error {
+	server.draining.Store(true)
+	server.stopStreams()
+
+	select {
+	case <-time.After(server.config.ShutdownDrainDelay):
+	case <-ctx.Done():
 	}
+	return server.httpServer.Shutdown(ctx)
 }
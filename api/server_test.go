@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServerShutdownDrainsRequests(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server.router.GET("/slow", func(ctx *gin.Context) {
+		close(started)
+		<-release
+		ctx.Status(http.StatusOK)
+	})
+
+	// reserve a free port for the server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	startErr := make(chan error, 1)
+	go func() { startErr <- server.Start(address) }()
+
+	responses := make(chan int, 1)
+	go func() {
+		var response *http.Response
+		require.Eventually(t, func() bool {
+			var err error
+			response, err = http.Get("http://" + address + "/slow")
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+		response.Body.Close()
+		responses <- response.StatusCode
+	}()
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- server.Shutdown(context.Background()) }()
+
+	// the in-flight request holds shutdown open
+	select {
+	case err := <-shutdownErr:
+		t.Fatalf("shutdown returned before the request finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	require.Equal(t, http.StatusOK, <-responses)
+	require.NoError(t, <-shutdownErr)
+	require.NoError(t, <-startErr)
+}
+
+func TestServerStartAfterShutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(ctrl))
+	require.NoError(t, server.Shutdown(context.Background()))
+
+	// a server that was shut down before it started exits cleanly
+	require.NoError(t, server.Start("127.0.0.1:0"))
+}
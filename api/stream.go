@@ -0,0 +1,305 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/money"
+	"github.com/julkar-naim/simple-bank/stream"
+	"github.com/julkar-naim/simple-bank/token"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultStreamHeartbeat is used when the config leaves the heartbeat unset
+const defaultStreamHeartbeat = 15 * time.Second
+
+// replayPageSize is how many entries a stream loads at a time when it
+// catches up
+const replayPageSize = 100
+
+// names of the events an account stream sends
+const (
+	// streamEventEntry carries a new entry. Its id is the entry id, which a
+	// client sends back to resume after it.
+	streamEventEntry = "entry"
+	// streamEventBalance carries the account's balance
+	streamEventBalance = "balance"
+)
+
+type balanceEvent struct {
+	AccountID int64        `json:"account_id"`
+	Balance   money.Amount `json:"balance"`
+}
+
+// streamWriter sends account stream events over one transport
+type streamWriter interface {
+	writeEvent(id int64, event string, data any) error
+	writeHeartbeat() error
+}
+
+type streamAccountRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type streamAccountQuery struct {
+	LastEventID int64 `form:"last_event_id" binding:"omitempty,min=1"`
+}
+
+// streamAccount pushes an account's new entries and balance changes as
+// Server-Sent Events, or over a WebSocket when the client asks to upgrade.
+//
+// A stream starts with the current balance. A client that reconnects with
+// the id of the last entry it saw, in the Last-Event-ID header or the
+// last_event_id parameter, first gets the entries it missed. The stream
+// ends when the access token expires, so the client must reconnect with a
+// fresh one.
+func (server *Server) streamAccount(ctx *gin.Context) {
+	var req streamAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+	var query streamAccountQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+	if header := ctx.GetHeader("Last-Event-ID"); header != "" {
+		lastEventID, err := strconv.ParseInt(header, 10, 64)
+		if err != nil || lastEventID < 1 {
+			renderError(ctx, fieldError("Last-Event-ID", "numeric"))
+			return
+		}
+		query.LastEventID = lastEventID
+	}
+
+	account, ok := server.ownedAccount(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	// subscribe before catching up, so nothing committed in between is missed
+	sub := server.accountHub.Subscribe(account.ID)
+	defer sub.Close()
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	streamCtx, cancel := context.WithDeadline(ctx.Request.Context(), authPayload.ExpiredAt)
+	defer cancel()
+	stop := context.AfterFunc(server.streamsCtx, cancel)
+	defer stop()
+
+	if websocket.IsWebSocketUpgrade(ctx.Request) {
+		server.streamWebSocket(ctx, streamCtx, cancel, sub, account, query.LastEventID)
+		return
+	}
+	server.streamSSE(ctx, streamCtx, sub, account, query.LastEventID)
+}
+
+func (server *Server) streamSSE(ctx *gin.Context, streamCtx context.Context, sub *stream.Subscription, account db.Account, lastEventID int64) {
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// stop proxies from buffering the stream
+	header.Set("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	err := server.runAccountStream(streamCtx, sseWriter{ctx.Writer}, sub, account, lastEventID)
+	if err != nil && streamCtx.Err() == nil {
+		server.logger.Warn("account stream failed", "account_id", account.ID, "error", err)
+	}
+}
+
+type sseWriter struct {
+	w gin.ResponseWriter
+}
+
+func (writer sseWriter) writeEvent(id int64, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id > 0 {
+		_, err = fmt.Fprintf(writer.w, "id: %d\n", id)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(writer.w, "event: %s\ndata: %s\n\n", event, payload)
+	if err != nil {
+		return err
+	}
+	writer.w.Flush()
+	return nil
+}
+
+func (writer sseWriter) writeHeartbeat() error {
+	_, err := fmt.Fprint(writer.w, ": heartbeat\n\n")
+	if err != nil {
+		return err
+	}
+	writer.w.Flush()
+	return nil
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// webSocketMessage wraps each event sent over a WebSocket
+type webSocketMessage struct {
+	ID    int64  `json:"id,omitempty"`
+	Event string `json:"event"`
+	Data  any    `json:"data"`
+}
+
+func (server *Server) streamWebSocket(ctx *gin.Context, streamCtx context.Context, cancel context.CancelFunc, sub *stream.Subscription, account db.Account, lastEventID int64) {
+	// Upgrade has already replied to the client if it fails
+	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	heartbeat := server.streamHeartbeat()
+	writer := &webSocketWriter{conn: conn, heartbeat: heartbeat}
+
+	// the client only sends control frames; reading handles them and notices
+	// when the client goes away or stops answering pings
+	conn.SetReadLimit(512)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * heartbeat))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * heartbeat))
+	})
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	err = server.runAccountStream(streamCtx, writer, sub, account, lastEventID)
+	if err != nil && streamCtx.Err() == nil {
+		server.logger.Warn("account stream failed", "account_id", account.ID, "error", err)
+	}
+
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if streamCtx.Err() == nil && err != nil {
+		message = websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "")
+	}
+	_ = conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+}
+
+type webSocketWriter struct {
+	conn      *websocket.Conn
+	heartbeat time.Duration
+}
+
+func (writer *webSocketWriter) writeEvent(id int64, event string, data any) error {
+	_ = writer.conn.SetWriteDeadline(time.Now().Add(writer.heartbeat))
+	return writer.conn.WriteJSON(webSocketMessage{ID: id, Event: event, Data: data})
+}
+
+func (writer *webSocketWriter) writeHeartbeat() error {
+	return writer.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writer.heartbeat))
+}
+
+func (server *Server) streamHeartbeat() time.Duration {
+	if server.config.StreamHeartbeat > 0 {
+		return server.config.StreamHeartbeat
+	}
+	return defaultStreamHeartbeat
+}
+
+// runAccountStream sends an account's events until ctx is done or a write
+// fails. It catches up from the database at the start and whenever the
+// subscription missed notifications, and otherwise relays them as they come.
+func (server *Server) runAccountStream(ctx context.Context, writer streamWriter, sub *stream.Subscription, account db.Account, lastEventID int64) error {
+	// a client that is not resuming starts from the latest entry
+	if lastEventID == 0 {
+		var err error
+		lastEventID, err = server.store.GetLastEntryID(ctx, account.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// entries sent while catching up may also arrive as notifications
+	sent := make(map[int64]bool)
+
+	catchUp := func() error {
+		clear(sent)
+		for {
+			entries, err := server.store.ListEntriesAfter(ctx, db.ListEntriesAfterParams{
+				AccountID: account.ID,
+				ID:        lastEventID,
+				Limit:     replayPageSize,
+			})
+			if err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				err = writer.writeEvent(entry.ID, streamEventEntry, newEntryResponse(entry, account.Currency))
+				if err != nil {
+					return err
+				}
+				sent[entry.ID] = true
+				lastEventID = entry.ID
+			}
+			if len(entries) < replayPageSize {
+				break
+			}
+		}
+
+		current, err := server.store.GetAccount(ctx, account.ID)
+		if err != nil {
+			return err
+		}
+		return writer.writeEvent(0, streamEventBalance, balanceEvent{
+			AccountID: current.ID,
+			Balance:   money.New(current.Balance, current.Currency),
+		})
+	}
+
+	err := catchUp()
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(server.streamHeartbeat())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err = writer.writeHeartbeat()
+		case <-sub.Resync():
+			err = catchUp()
+		case notification := <-sub.Events():
+			if entry := notification.Entry; entry != nil && !sent[entry.ID] {
+				err = writer.writeEvent(entry.ID, streamEventEntry, newEntryResponse(*entry, notification.Currency))
+				if err != nil {
+					return err
+				}
+				lastEventID = max(lastEventID, entry.ID)
+			}
+			err = writer.writeEvent(0, streamEventBalance, balanceEvent{
+				AccountID: notification.AccountID,
+				Balance:   money.New(notification.Balance, notification.Currency),
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseEvent is one event read from a Server-Sent Events stream
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// readSSEEvent reads the next event, or "heartbeat" for a heartbeat comment
+func readSSEEvent(t *testing.T, reader *bufio.Reader) sseEvent {
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "":
+			return event
+		case line == ": heartbeat":
+			event.Event = "heartbeat"
+		case strings.HasPrefix(line, "id: "):
+			event.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.Event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.Data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func requireBalanceEvent(t *testing.T, data string, accountID, balance int64, currency string) {
+	var rsp balanceEvent
+	require.NoError(t, json.Unmarshal([]byte(data), &rsp))
+	require.Equal(t, accountID, rsp.AccountID)
+	require.Equal(t, balance, rsp.Balance.Value())
+	require.Equal(t, currency, rsp.Balance.Currency())
+}
+
+func requireEntryEvent(t *testing.T, data string, entry db.Entry) {
+	var rsp entryResponse
+	require.NoError(t, json.Unmarshal([]byte(data), &rsp))
+	require.Equal(t, entry.ID, rsp.ID)
+	require.Equal(t, entry.AccountID, rsp.AccountID)
+	require.Equal(t, entry.Amount, rsp.Amount.Value())
+}
+
+// openSSEStream starts an SSE stream, failing the test unless it is accepted
+func openSSEStream(t *testing.T, server *Server, url string, owner string, header http.Header, tokenDuration time.Duration) (*http.Response, *bufio.Reader) {
+	httpServer := httptest.NewServer(server.router)
+	t.Cleanup(httpServer.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, httpServer.URL+url, nil)
+	require.NoError(t, err)
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, owner, util.CustomerRole, tokenDuration)
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	t.Cleanup(func() { response.Body.Close() })
+	return response, bufio.NewReader(response.Body)
+}
+
+func TestStreamAccountSSE(t *testing.T) {
+	account := randomAccount()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(2).
+		Return(account, nil)
+	store.EXPECT().GetLastEntryID(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(int64(5), nil)
+	store.EXPECT().ListEntriesAfter(gomock.Any(), gomock.Eq(db.ListEntriesAfterParams{AccountID: account.ID, ID: 5, Limit: replayPageSize})).
+		Times(1).
+		Return([]db.Entry{}, nil)
+
+	server := newTestServer(t, store)
+	response, reader := openSSEStream(t, server, fmt.Sprintf("/accounts/%d/stream", account.ID), account.Owner, nil, time.Minute)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	// the stream starts with the current balance
+	event := readSSEEvent(t, reader)
+	require.Equal(t, streamEventBalance, event.Event)
+	require.Empty(t, event.ID)
+	requireBalanceEvent(t, event.Data, account.ID, account.Balance, account.Currency)
+
+	// then relays transfers committed on any instance
+	entry := db.Entry{ID: 6, AccountID: account.ID, Amount: -10, CreatedAt: time.Now()}
+	server.accountHub.Publish(db.AccountNotification{
+		AccountID: account.ID,
+		Balance:   account.Balance - 10,
+		Currency:  account.Currency,
+		Entry:     &entry,
+	})
+
+	event = readSSEEvent(t, reader)
+	require.Equal(t, streamEventEntry, event.Event)
+	require.Equal(t, "6", event.ID)
+	requireEntryEvent(t, event.Data, entry)
+
+	event = readSSEEvent(t, reader)
+	require.Equal(t, streamEventBalance, event.Event)
+	requireBalanceEvent(t, event.Data, account.ID, account.Balance-10, account.Currency)
+
+	// and notifications of other accounts are not sent
+	server.accountHub.Publish(db.AccountNotification{AccountID: account.ID + 1, Balance: 1, Currency: account.Currency})
+	server.accountHub.Publish(db.AccountNotification{AccountID: account.ID, Balance: 7, Currency: account.Currency})
+	event = readSSEEvent(t, reader)
+	requireBalanceEvent(t, event.Data, account.ID, 7, account.Currency)
+}
+
+func TestStreamAccountSSEResume(t *testing.T) {
+	account := randomAccount()
+	missed := []db.Entry{
+		{ID: 4, AccountID: account.ID, Amount: 10, CreatedAt: time.Now()},
+		{ID: 5, AccountID: account.ID, Amount: -3, CreatedAt: time.Now()},
+	}
+
+	testCases := []struct {
+		name   string
+		url    string
+		header http.Header
+	}{
+		{"Header", fmt.Sprintf("/accounts/%d/stream", account.ID), http.Header{"Last-Event-Id": {"3"}}},
+		{"Query", fmt.Sprintf("/accounts/%d/stream?last_event_id=3", account.ID), nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(3).
+				Return(account, nil)
+			store.EXPECT().GetLastEntryID(gomock.Any(), gomock.Any()).
+				Times(0)
+			gomock.InOrder(
+				store.EXPECT().ListEntriesAfter(gomock.Any(), gomock.Eq(db.ListEntriesAfterParams{AccountID: account.ID, ID: 3, Limit: replayPageSize})).
+					Return(missed, nil),
+				// after a resync it catches up from the last entry it sent
+				store.EXPECT().ListEntriesAfter(gomock.Any(), gomock.Eq(db.ListEntriesAfterParams{AccountID: account.ID, ID: 5, Limit: replayPageSize})).
+					Return([]db.Entry{}, nil),
+			)
+
+			server := newTestServer(t, store)
+			response, reader := openSSEStream(t, server, tc.url, account.Owner, tc.header, time.Minute)
+			require.Equal(t, http.StatusOK, response.StatusCode)
+
+			for _, entry := range missed {
+				event := readSSEEvent(t, reader)
+				require.Equal(t, streamEventEntry, event.Event)
+				require.Equal(t, fmt.Sprint(entry.ID), event.ID)
+				requireEntryEvent(t, event.Data, entry)
+			}
+			event := readSSEEvent(t, reader)
+			require.Equal(t, streamEventBalance, event.Event)
+
+			// a replayed entry that also arrives as a notification is sent once
+			server.accountHub.Publish(db.AccountNotification{AccountID: account.ID, Balance: 9, Currency: account.Currency, Entry: &missed[1]})
+			event = readSSEEvent(t, reader)
+			require.Equal(t, streamEventBalance, event.Event)
+			requireBalanceEvent(t, event.Data, account.ID, 9, account.Currency)
+
+			server.accountHub.Resync()
+			event = readSSEEvent(t, reader)
+			require.Equal(t, streamEventBalance, event.Event)
+			requireBalanceEvent(t, event.Data, account.ID, account.Balance, account.Currency)
+		})
+	}
+}
+
+func TestStreamAccountSSEEnds(t *testing.T) {
+	account := randomAccount()
+
+	testCases := []struct {
+		name          string
+		tokenDuration time.Duration
+		end           func(server *Server)
+	}{
+		// tokens carry their expiry in whole seconds
+		{"TokenExpires", 2 * time.Second, func(server *Server) {}},
+		{"Shutdown", time.Minute, func(server *Server) { server.stopStreams() }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(2).
+				Return(account, nil)
+			store.EXPECT().GetLastEntryID(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(int64(0), nil)
+			store.EXPECT().ListEntriesAfter(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return([]db.Entry{}, nil)
+
+			server := newTestServer(t, store)
+			server.config.StreamHeartbeat = 20 * time.Millisecond
+			_, reader := openSSEStream(t, server, fmt.Sprintf("/accounts/%d/stream", account.ID), account.Owner, nil, tc.tokenDuration)
+
+			require.Equal(t, streamEventBalance, readSSEEvent(t, reader).Event)
+			require.Equal(t, "heartbeat", readSSEEvent(t, reader).Event)
+
+			tc.end(server)
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				_, _ = io.Copy(io.Discard, reader)
+			}()
+			select {
+			case <-done:
+			case <-time.After(3 * time.Second):
+				t.Fatal("stream did not end")
+			}
+			require.Zero(t, server.accountHub.Subscribers(account.ID))
+		})
+	}
+}
+
+func TestStreamAccountRejected(t *testing.T) {
+	account := randomAccount()
+
+	testCases := []struct {
+		name       string
+		url        string
+		header     http.Header
+		owner      string
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			"OtherUsersAccount",
+			fmt.Sprintf("/accounts/%d/stream", account.ID),
+			nil,
+			"someoneelse",
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			http.StatusForbidden,
+		},
+		{
+			"NotFound",
+			fmt.Sprintf("/accounts/%d/stream", account.ID),
+			nil,
+			account.Owner,
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+			},
+			http.StatusNotFound,
+		},
+		{
+			"InvalidLastEventID",
+			fmt.Sprintf("/accounts/%d/stream", account.ID),
+			http.Header{"Last-Event-Id": {"abc"}},
+			account.Owner,
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			response, _ := openSSEStream(t, server, tc.url, tc.owner, tc.header, time.Minute)
+			require.Equal(t, tc.status, response.StatusCode)
+			require.Zero(t, server.accountHub.Subscribers(account.ID))
+		})
+	}
+}
+
+func TestStreamAccountUnauthenticated(t *testing.T) {
+	server := newTestServer(t, mockdb.NewMockStore(gomock.NewController(t)))
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/accounts/1/stream", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestStreamAccountWebSocket(t *testing.T) {
+	account := randomAccount()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(2).
+		Return(account, nil)
+	store.EXPECT().GetLastEntryID(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(int64(5), nil)
+	store.EXPECT().ListEntriesAfter(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.Entry{}, nil)
+
+	server := newTestServer(t, store)
+	server.config.StreamHeartbeat = 20 * time.Millisecond
+	httpServer := httptest.NewServer(server.router)
+	defer httpServer.Close()
+
+	request, err := http.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + fmt.Sprintf("/accounts/%d/stream", account.ID)
+	conn, response, err := websocket.DefaultDialer.Dial(url, request.Header)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+
+	pinged := make(chan struct{}, 1)
+	conn.SetPingHandler(func(data string) error {
+		select {
+		case pinged <- struct{}{}:
+		default:
+		}
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+
+	type message struct {
+		ID    int64           `json:"id"`
+		Event string          `json:"event"`
+		Data  json.RawMessage `json:"data"`
+	}
+	readMessage := func() message {
+		var msg message
+		require.NoError(t, conn.ReadJSON(&msg))
+		return msg
+	}
+
+	msg := readMessage()
+	require.Equal(t, streamEventBalance, msg.Event)
+	requireBalanceEvent(t, string(msg.Data), account.ID, account.Balance, account.Currency)
+
+	entry := db.Entry{ID: 6, AccountID: account.ID, Amount: 25, CreatedAt: time.Now()}
+	server.accountHub.Publish(db.AccountNotification{AccountID: account.ID, Balance: account.Balance + 25, Currency: account.Currency, Entry: &entry})
+
+	msg = readMessage()
+	require.Equal(t, streamEventEntry, msg.Event)
+	require.Equal(t, entry.ID, msg.ID)
+	requireEntryEvent(t, string(msg.Data), entry)
+
+	msg = readMessage()
+	require.Equal(t, streamEventBalance, msg.Event)
+	requireBalanceEvent(t, string(msg.Data), account.ID, account.Balance+25, account.Currency)
+
+	// heartbeats are pings, handled while reading
+	go func() {
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+	select {
+	case <-pinged:
+	case <-time.After(time.Second):
+		t.Fatal("no heartbeat")
+	}
+
+	// closing the socket ends the stream
+	require.NoError(t, conn.Close())
+	require.Eventually(t, func() bool {
+		return server.accountHub.Subscribers(account.ID) == 0
+	}, time.Second, 10*time.Millisecond)
+}
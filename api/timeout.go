@@ -0,0 +1,29 @@
+package api
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+// timeoutMiddleware bounds the request context, and so every store call made
+// with it, by the timeout of the matched route template, or defaultTimeout
+// for routes without an override. A zero timeout leaves the request unbounded.
+func timeoutMiddleware(defaultTimeout time.Duration, routeTimeouts map[string]time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		timeout, ok := routeTimeouts[ctx.FullPath()]
+		if !ok {
+			timeout = defaultTimeout
+		}
+		if timeout <= 0 {
+			ctx.Next()
+			return
+		}
+
+		requestCtx, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+		defer cancel()
+
+		ctx.Request = ctx.Request.WithContext(requestCtx)
+		ctx.Next()
+	}
+}
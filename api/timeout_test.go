@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/julkar-naim/simple-bank/apperr"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount()
+	account.Owner = user.Username
+
+	// waitForContext behaves like a query that outlives its context
+	waitForContext := func(ctx context.Context, id int64) (db.Account, error) {
+		<-ctx.Done()
+		return db.Account{}, ctx.Err()
+	}
+
+	testCases := []struct {
+		name          string
+		timeout       time.Duration
+		buildRequest  func(request *http.Request) *http.Request
+		buildStub     func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			"OK",
+			time.Second,
+			func(request *http.Request) *http.Request {
+				return request
+			},
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, id int64) (db.Account, error) {
+						deadline, ok := ctx.Deadline()
+						require.True(t, ok)
+						require.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+						return account, nil
+					})
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			"DeadlineExceeded",
+			10 * time.Millisecond,
+			func(request *http.Request) *http.Request {
+				return request
+			},
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					DoAndReturn(waitForContext)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusGatewayTimeout, recorder.Code)
+			},
+		},
+		{
+			"ClientCanceled",
+			time.Second,
+			func(request *http.Request) *http.Request {
+				ctx, cancel := context.WithCancel(request.Context())
+				cancel()
+				return request.WithContext(ctx)
+			},
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					DoAndReturn(waitForContext)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, apperr.StatusClientClosedRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+
+			server := newTestServer(t, store)
+			server.config.RequestTimeout = tc.timeout
+			server.setupRouter()
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+			server.router.ServeHTTP(recorder, tc.buildRequest(request))
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestTimeoutMiddlewareRouteOverride(t *testing.T) {
+	router := gin.New()
+	router.Use(timeoutMiddleware(time.Second, map[string]time.Duration{
+		"/slow/:id": time.Minute,
+		"/open":     0,
+	}))
+
+	deadlines := map[string]time.Time{}
+	handler := func(ctx *gin.Context) {
+		deadline, _ := ctx.Request.Context().Deadline()
+		deadlines[ctx.FullPath()] = deadline
+		ctx.Status(http.StatusOK)
+	}
+	router.GET("/fast", handler)
+	router.GET("/slow/:id", handler)
+	router.GET("/open", handler)
+
+	for _, path := range []string{"/fast", "/slow/1", "/open"} {
+		request, err := http.NewRequest(http.MethodGet, path, nil)
+		require.NoError(t, err)
+		router.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	require.WithinDuration(t, time.Now().Add(time.Second), deadlines["/fast"], 100*time.Millisecond)
+	require.WithinDuration(t, time.Now().Add(time.Minute), deadlines["/slow/:id"], 100*time.Millisecond)
+	require.True(t, deadlines["/open"].IsZero())
+}
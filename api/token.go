@@ -0,0 +1,94 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/julkar-naim/simple-bank/apperr"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/token"
+	"net/http"
+	"time"
+)
+
+type renewAccessTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type renewAccessTokenResponse struct {
+	AccessToken          string    `json:"access_token"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+}
+
+func (server *Server) renewAccessToken(ctx *gin.Context) {
+	var req renewAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	session, ok := server.validSession(ctx, req.RefreshToken)
+	if !ok {
+		return
+	}
+
+	// the role is read again so that role changes apply from the next renewal
+	user, err := server.store.GetUser(ctx.Request.Context(), session.Username)
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, token.TokenTypeAccessToken, server.config.AccessTokenDuration)
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	rsp := renewAccessTokenResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiredAt,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// validSession verifies the refresh token and loads its session, rejecting
+// blocked, expired or mismatched sessions. It writes the error response itself.
+func (server *Server) validSession(ctx *gin.Context, refreshToken string) (db.Session, bool) {
+	refreshPayload, err := server.tokenMaker.VerifyToken(refreshToken, token.TokenTypeRefreshToken)
+	if err != nil {
+		renderError(ctx, apperr.New(apperr.CodeUnauthenticated, err.Error()))
+		return db.Session{}, false
+	}
+
+	session, err := server.store.GetSession(ctx.Request.Context(), refreshPayload.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			renderError(ctx, apperr.New(apperr.CodeUnauthenticated, "session not found"))
+			return session, false
+		}
+		renderError(ctx, err)
+		return session, false
+	}
+
+	if session.IsBlocked {
+		renderError(ctx, apperr.New(apperr.CodeUnauthenticated, "blocked session"))
+		return session, false
+	}
+
+	if session.Username != refreshPayload.Username {
+		renderError(ctx, apperr.New(apperr.CodeUnauthenticated, "incorrect session user"))
+		return session, false
+	}
+
+	if session.RefreshToken != refreshToken {
+		renderError(ctx, apperr.New(apperr.CodeUnauthenticated, "mismatched session token"))
+		return session, false
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		renderError(ctx, apperr.New(apperr.CodeUnauthenticated, "expired session"))
+		return session, false
+	}
+	return session, true
+}
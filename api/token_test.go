@@ -0,0 +1,349 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/token"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// sessionFor builds the session row the store would hold for refreshToken
+func sessionFor(refreshToken string, payload *token.Payload) db.Session {
+	return db.Session{
+		ID:           payload.ID,
+		Username:     payload.Username,
+		RefreshToken: refreshToken,
+		IsBlocked:    false,
+		ExpiresAt:    payload.ExpiredAt,
+	}
+}
+
+func TestRenewAccessTokenAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	testCases := []struct {
+		name          string
+		body          func(refreshToken string) gin.H
+		buildStub     func(store *mockdb.MockStore, refreshToken string, payload *token.Payload)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			"OK",
+			func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": refreshToken}
+			},
+			func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(sessionFor(refreshToken, payload), nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(payload.Username)).
+					Times(1).
+					Return(db.User{Username: payload.Username, Role: util.OperatorRole}, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp renewAccessTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.AccessToken)
+			},
+		},
+		{
+			"MissingToken",
+			func(refreshToken string) gin.H {
+				return gin.H{}
+			},
+			func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			"InvalidToken",
+			func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": "invalid"}
+			},
+			func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			"SessionNotFound",
+			func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": refreshToken}
+			},
+			func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(db.Session{}, sql.ErrNoRows)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			"BlockedSession",
+			func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": refreshToken}
+			},
+			func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				session := sessionFor(refreshToken, payload)
+				session.IsBlocked = true
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			"IncorrectSessionUser",
+			func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": refreshToken}
+			},
+			func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				session := sessionFor(refreshToken, payload)
+				session.Username = "other_user"
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			"MismatchedSessionToken",
+			func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": refreshToken}
+			},
+			func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				session := sessionFor(refreshToken, payload)
+				session.RefreshToken = "another-token"
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			"ExpiredSession",
+			func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": refreshToken}
+			},
+			func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				session := sessionFor(refreshToken, payload)
+				session.ExpiresAt = time.Now().Add(-time.Minute)
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			"GetUserError",
+			func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": refreshToken}
+			},
+			func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(sessionFor(refreshToken, payload), nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			"InternalServerError",
+			func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": refreshToken}
+			},
+			func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrConnDone)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+
+			// configure test server
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			refreshToken, payload, err := server.tokenMaker.CreateToken(username, util.CustomerRole, token.TokenTypeRefreshToken, time.Hour)
+			require.NoError(t, err)
+			tc.buildStub(store, refreshToken, payload)
+
+			request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", buildRequestBody(tc.body(refreshToken)))
+			require.NoError(t, err)
+
+			// start test server
+			server.router.ServeHTTP(recorder, request)
+
+			// check response
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLogoutUserAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	testCases := []struct {
+		name          string
+		body          func(refreshToken string) gin.H
+		buildStub     func(store *mockdb.MockStore, refreshToken string, payload *token.Payload)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			"OK",
+			func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": refreshToken}
+			},
+			func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				session := sessionFor(refreshToken, payload)
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(session, nil)
+				session.IsBlocked = true
+				store.EXPECT().BlockSessionTx(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(session, nil)
+				store.EXPECT().BlockUserSessionsTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			"AllSessions",
+			func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": refreshToken, "all_sessions": true}
+			},
+			func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(sessionFor(refreshToken, payload), nil)
+				store.EXPECT().BlockSessionTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().BlockUserSessionsTx(gomock.Any(), gomock.Eq(payload.Username)).
+					Times(1).
+					Return([]db.Session{{}, {}, {}}, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			"BlockedSession",
+			func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": refreshToken}
+			},
+			func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				session := sessionFor(refreshToken, payload)
+				session.IsBlocked = true
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(session, nil)
+				store.EXPECT().BlockSessionTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			"InvalidToken",
+			func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": "invalid"}
+			},
+			func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			"InternalServerError",
+			func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": refreshToken}
+			},
+			func(store *mockdb.MockStore, refreshToken string, payload *token.Payload) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+					Times(1).
+					Return(sessionFor(refreshToken, payload), nil)
+				store.EXPECT().BlockSessionTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrConnDone)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+
+			// configure test server
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			refreshToken, payload, err := server.tokenMaker.CreateToken(username, util.CustomerRole, token.TokenTypeRefreshToken, time.Hour)
+			require.NoError(t, err)
+			tc.buildStub(store, refreshToken, payload)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/logout", buildRequestBody(tc.body(refreshToken)))
+			require.NoError(t, err)
+
+			// start test server
+			server.router.ServeHTTP(recorder, request)
+
+			// check response
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
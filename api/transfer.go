@@ -2,15 +2,151 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/julkar-naim/simple-bank/apperr"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/money"
+	"github.com/julkar-naim/simple-bank/token"
+	"time"
 )
 
 type CreateTransferRequest struct {
-	FromAccountID int64  `json:"from_account_id"`
-	ToAccountId   int64  `json:"to_account_id"`
-	Amount        int64  `json:"amount" binding:"required,gt=0"`
-	Currency      string `json:"currency" binding:"required,oneof=USD EUR CAD"`
+	FromAccountID int64 `json:"from_account_id" binding:"required,min=1"`
+	ToAccountId   int64 `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"`
+	// Amount is a money object, or a legacy integer in minor units of Currency
+	Amount   amountParam `json:"amount"`
+	Currency string      `json:"currency" binding:"omitempty,currency"`
+}
+
+type transferResponse struct {
+	ID            int64        `json:"id"`
+	FromAccountID int64        `json:"from_account_id"`
+	ToAccountID   int64        `json:"to_account_id"`
+	Amount        money.Amount `json:"amount"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+// newTransferResponse needs the currency of the transfer, which is that of
+// both its accounts
+func newTransferResponse(transfer db.Transfer, currency string) transferResponse {
+	return transferResponse{
+		ID:            transfer.ID,
+		FromAccountID: transfer.FromAccountID,
+		ToAccountID:   transfer.ToAccountID,
+		Amount:        money.New(transfer.Amount, currency),
+		CreatedAt:     transfer.CreatedAt,
+	}
+}
+
+type entryResponse struct {
+	ID        int64        `json:"id"`
+	AccountID int64        `json:"account_id"`
+	Amount    money.Amount `json:"amount"`
+	// TransferID is the transfer the entry belongs to, if any
+	TransferID  *int64       `json:"transfer_id,omitempty"`
+	EntryType   db.EntryType `json:"entry_type"`
+	Description string       `json:"description"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+func newEntryResponse(entry db.Entry, currency string) entryResponse {
+	rsp := entryResponse{
+		ID:          entry.ID,
+		AccountID:   entry.AccountID,
+		Amount:      money.New(entry.Amount, currency),
+		EntryType:   entry.EntryType,
+		Description: entry.Description,
+		CreatedAt:   entry.CreatedAt,
+	}
+	if entry.TransferID.Valid {
+		rsp.TransferID = &entry.TransferID.Int64
+	}
+	return rsp
+}
+
+type transferTxResponse struct {
+	Transfer    transferResponse `json:"transfer"`
+	FromAccount accountResponse  `json:"from_account"`
+	ToAccount   accountResponse  `json:"to_account"`
+	FromEntry   entryResponse    `json:"from_entry"`
+	ToEntry     entryResponse    `json:"to_entry"`
+}
+
+func newTransferTxResponse(result db.TransferTxResult) transferTxResponse {
+	currency := result.FromAccount.Currency
+	return transferTxResponse{
+		Transfer:    newTransferResponse(result.Transfer, currency),
+		FromAccount: newAccountResponse(result.FromAccount),
+		ToAccount:   newAccountResponse(result.ToAccount),
+		FromEntry:   newEntryResponse(result.FromEntry, currency),
+		ToEntry:     newEntryResponse(result.ToEntry, currency),
+	}
 }
 
 func (server *Server) createTransfer(ctx *gin.Context) {
+	var req CreateTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	amount, err := req.Amount.resolve("amount", req.Currency)
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+	if !amount.IsPositive() {
+		renderError(ctx, fieldError("amount", "gt"))
+		return
+	}
+
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, amount.Currency())
+	if !valid {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if fromAccount.Owner != authPayload.Username {
+		renderError(ctx, apperr.New(apperr.CodePermissionDenied, "from account doesn't belong to the authenticated user"))
+		return
+	}
+
+	if _, valid := server.validAccount(ctx, req.ToAccountId, amount.Currency()); !valid {
+		return
+	}
+
+	arg := db.TransferTxParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountId,
+		Amount:        amount,
+	}
+
+	result, err := server.store.TransferTx(ctx.Request.Context(), arg)
+	if err != nil {
+		renderError(ctx, apperr.Translate(err, "transfer"))
+		return
+	}
+	renderAmounts(ctx, newTransferTxResponse(result), result)
+}
+
+// validAccount checks that the account exists, isn't frozen and holds the given currency,
+// writing the error response itself when it doesn't
+func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+	account, err := server.store.GetAccount(ctx.Request.Context(), accountID)
+	if err != nil {
+		renderError(ctx, apperr.Translate(err, "account"))
+		return account, false
+	}
+
+	if account.IsFrozen {
+		renderError(ctx, apperr.Newf(apperr.CodePermissionDenied, "account [%d] is frozen", account.ID).
+			WithDetail("account_id", account.ID))
+		return account, false
+	}
 
+	if account.Currency != currency {
+		renderError(ctx, apperr.Newf(apperr.CodeInvalidArgument, "account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency).
+			WithDetail("account_id", account.ID))
+		return account, false
+	}
+	return account, true
 }
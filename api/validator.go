@@ -0,0 +1,32 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/julkar-naim/simple-bank/util"
+)
+
+// validCurrency accepts the codes of enabled currencies, as cached from the
+// currencies table
+var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if currency, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsSupportedCurrency(currency)
+	}
+	return false
+}
+
+// validRole accepts the roles that util grants permissions to
+var validRole validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if role, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsSupportedRole(role)
+	}
+	return false
+}
+
+// registerValidators adds the custom binding tags used by request structs
+func registerValidators() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		_ = v.RegisterValidation("currency", validCurrency)
+		_ = v.RegisterValidation("role", validRole)
+	}
+}
@@ -0,0 +1,317 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/julkar-naim/simple-bank/apperr"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/token"
+	"github.com/julkar-naim/simple-bank/util"
+	"net/http"
+	"time"
+)
+
+type webhookResponse struct {
+	ID         int64     `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	IsActive   bool      `json:"is_active"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func newWebhookResponse(webhook db.Webhook) webhookResponse {
+	return webhookResponse{
+		ID:         webhook.ID,
+		URL:        webhook.Url,
+		EventTypes: webhook.EventTypes,
+		IsActive:   webhook.IsActive,
+		CreatedBy:  webhook.CreatedBy,
+		CreatedAt:  webhook.CreatedAt,
+	}
+}
+
+type createWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,http_url"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,unique"`
+}
+
+type createWebhookResponse struct {
+	// Secret signs every delivery and is only returned once
+	Secret  string          `json:"secret"`
+	Webhook webhookResponse `json:"webhook"`
+}
+
+func (server *Server) createWebhook(ctx *gin.Context) {
+	var req createWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	for _, eventType := range req.EventTypes {
+		if !db.IsSupportedEventType(eventType) {
+			renderError(ctx, apperr.Newf(apperr.CodeInvalidArgument, "unsupported event type %s", eventType).
+				WithDetail("event_type", eventType))
+			return
+		}
+	}
+
+	secret, err := util.RandomToken(32)
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	secretCiphertext, err := server.webhookSecretBox.Seal(secret, db.WebhookSecretLabel(req.URL))
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.CreateWebhookParams{
+		Url:              req.URL,
+		SecretCiphertext: secretCiphertext,
+		EventTypes:       req.EventTypes,
+		CreatedBy:        authPayload.Username,
+	}
+
+	webhook, err := server.store.CreateWebhookTx(ctx.Request.Context(), arg)
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	rsp := createWebhookResponse{
+		Secret:  secret,
+		Webhook: newWebhookResponse(webhook),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type listWebhooksRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+func (server *Server) listWebhooks(ctx *gin.Context) {
+	var req listWebhooksRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	arg := db.ListWebhooksParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	webhooks, err := server.store.ListWebhooks(ctx.Request.Context(), arg)
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	rsp := make([]webhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		rsp = append(rsp, newWebhookResponse(webhook))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type webhookIDRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) getWebhook(ctx *gin.Context) {
+	var req webhookIDRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	webhook, err := server.store.GetWebhook(ctx.Request.Context(), req.ID)
+	if err != nil {
+		renderError(ctx, apperr.Translate(err, "webhook"))
+		return
+	}
+	ctx.JSON(http.StatusOK, newWebhookResponse(webhook))
+}
+
+func (server *Server) enableWebhook(ctx *gin.Context) {
+	server.setWebhookActive(ctx, true)
+}
+
+func (server *Server) disableWebhook(ctx *gin.Context) {
+	server.setWebhookActive(ctx, false)
+}
+
+// setWebhookActive stops or resumes deliveries. Events raised while a
+// webhook is disabled are not queued for it, and its pending deliveries wait
+// until it is enabled again.
+func (server *Server) setWebhookActive(ctx *gin.Context, active bool) {
+	var req webhookIDRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	arg := db.SetWebhookActiveParams{
+		ID:       req.ID,
+		IsActive: active,
+	}
+
+	webhook, err := server.store.SetWebhookActiveTx(ctx.Request.Context(), arg)
+	if err != nil {
+		renderError(ctx, apperr.Translate(err, "webhook"))
+		return
+	}
+	ctx.JSON(http.StatusOK, newWebhookResponse(webhook))
+}
+
+type webhookDeliveryResponse struct {
+	ID            int64           `json:"id"`
+	WebhookID     int64           `json:"webhook_id"`
+	EventID       int64           `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int32           `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+func newWebhookDeliveryResponse(delivery db.WebhookDelivery) webhookDeliveryResponse {
+	return webhookDeliveryResponse{
+		ID:            delivery.ID,
+		WebhookID:     delivery.WebhookID,
+		EventID:       delivery.EventID,
+		EventType:     delivery.EventType,
+		Payload:       delivery.Payload,
+		Status:        delivery.Status,
+		Attempts:      delivery.Attempts,
+		NextAttemptAt: delivery.NextAttemptAt,
+		LastError:     delivery.LastError,
+		CreatedAt:     delivery.CreatedAt,
+		UpdatedAt:     delivery.UpdatedAt,
+	}
+}
+
+type listWebhookDeliveriesRequest struct {
+	Status   string `form:"status" binding:"omitempty,oneof=pending succeeded dead"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listWebhookDeliveries returns a webhook's deliveries, newest first
+func (server *Server) listWebhookDeliveries(ctx *gin.Context) {
+	var uri webhookIDRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+	var req listWebhookDeliveriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	arg := db.ListWebhookDeliveriesParams{
+		WebhookID: uri.ID,
+		Status:    sql.NullString{String: req.Status, Valid: req.Status != ""},
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	}
+
+	deliveries, err := server.store.ListWebhookDeliveries(ctx.Request.Context(), arg)
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	rsp := make([]webhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		rsp = append(rsp, newWebhookDeliveryResponse(delivery))
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type webhookAttemptResponse struct {
+	Attempt    int32     `json:"attempt"`
+	StatusCode int32     `json:"status_code"`
+	Error      string    `json:"error"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type webhookDeliveryDetailResponse struct {
+	Delivery webhookDeliveryResponse  `json:"delivery"`
+	Attempts []webhookAttemptResponse `json:"attempts"`
+}
+
+// getWebhookDelivery returns a delivery with every attempt made so far
+func (server *Server) getWebhookDelivery(ctx *gin.Context) {
+	var req webhookIDRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	delivery, err := server.store.GetWebhookDelivery(ctx.Request.Context(), req.ID)
+	if err != nil {
+		renderError(ctx, apperr.Translate(err, "webhook delivery"))
+		return
+	}
+
+	attempts, err := server.store.ListWebhookAttempts(ctx.Request.Context(), delivery.ID)
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	rsp := webhookDeliveryDetailResponse{
+		Delivery: newWebhookDeliveryResponse(delivery),
+		Attempts: make([]webhookAttemptResponse, 0, len(attempts)),
+	}
+	for _, attempt := range attempts {
+		rsp.Attempts = append(rsp.Attempts, webhookAttemptResponse{
+			Attempt:    attempt.Attempt,
+			StatusCode: attempt.StatusCode,
+			Error:      attempt.Error,
+			DurationMs: attempt.DurationMs,
+			CreatedAt:  attempt.CreatedAt,
+		})
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// retryWebhookDelivery queues a dead delivery for a full set of attempts
+func (server *Server) retryWebhookDelivery(ctx *gin.Context) {
+	var req webhookIDRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		renderError(ctx, bindingError(err))
+		return
+	}
+
+	delivery, err := server.store.GetWebhookDelivery(ctx.Request.Context(), req.ID)
+	if err != nil {
+		renderError(ctx, apperr.Translate(err, "webhook delivery"))
+		return
+	}
+	if delivery.Status != db.DeliveryDead {
+		renderError(ctx, apperr.Newf(apperr.CodeFailedPrecondition, "webhook delivery %d is %s, only dead deliveries can be retried", delivery.ID, delivery.Status).
+			WithDetail("status", delivery.Status))
+		return
+	}
+
+	delivery, err = server.store.RetryWebhookDeliveryTx(ctx.Request.Context(), req.ID)
+	if err != nil {
+		renderError(ctx, apperr.Translate(err, "webhook delivery"))
+		return
+	}
+	ctx.JSON(http.StatusOK, newWebhookDeliveryResponse(delivery))
+}
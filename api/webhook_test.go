@@ -0,0 +1,375 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/token"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func randomWebhook() db.Webhook {
+	return db.Webhook{
+		ID:               util.RandomInt(1000) + 1,
+		Url:              "https://partner.example/hooks",
+		SecretCiphertext: util.RandomString(64),
+		EventTypes:       []string{db.EventTransferCompleted},
+		IsActive:         true,
+		CreatedBy:        util.AdminRole + "user",
+		CreatedAt:        time.Now(),
+	}
+}
+
+func randomWebhookDelivery(webhook db.Webhook, status string) db.WebhookDelivery {
+	return db.WebhookDelivery{
+		ID:            util.RandomInt(1000) + 1,
+		WebhookID:     webhook.ID,
+		EventID:       util.RandomInt(1000) + 1,
+		EventType:     db.EventTransferCompleted,
+		Payload:       json.RawMessage(`{"id":1}`),
+		Status:        status,
+		Attempts:      8,
+		NextAttemptAt: time.Now(),
+		LastError:     "unexpected status 500",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+}
+
+func TestWebhookAPI(t *testing.T) {
+	webhook := randomWebhook()
+	disabledWebhook := webhook
+	disabledWebhook.IsActive = false
+	var createdSecretCiphertext string
+	deadDelivery := randomWebhookDelivery(webhook, db.DeliveryDead)
+	retriedDelivery := deadDelivery
+	retriedDelivery.Status = db.DeliveryPending
+	succeededDelivery := randomWebhookDelivery(webhook, db.DeliverySucceeded)
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStub     func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			"CreateOK",
+			http.MethodPost,
+			"/admin/webhooks",
+			gin.H{"url": webhook.Url, "event_types": webhook.EventTypes},
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateWebhookParams) (db.Webhook, error) {
+						require.Equal(t, webhook.Url, arg.Url)
+						require.Equal(t, webhook.EventTypes, arg.EventTypes)
+						require.Equal(t, webhook.CreatedBy, arg.CreatedBy)
+						createdSecretCiphertext = arg.SecretCiphertext
+
+						created := webhook
+						created.SecretCiphertext = arg.SecretCiphertext
+						return created, nil
+					})
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp createWebhookResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp.Secret, 64)
+				require.Equal(t, webhook.ID, rsp.Webhook.ID)
+				require.Equal(t, webhook.Url, rsp.Webhook.URL)
+				require.NotContains(t, recorder.Body.String(), createdSecretCiphertext)
+
+				// the store only gets the secret sealed for the webhook's url
+				secretBox, err := util.NewSecretBox(testWebhookEncryptionKey)
+				require.NoError(t, err)
+				require.NotContains(t, createdSecretCiphertext, rsp.Secret)
+				secret, err := secretBox.Open(createdSecretCiphertext, db.WebhookSecretLabel(webhook.Url))
+				require.NoError(t, err)
+				require.Equal(t, rsp.Secret, secret)
+			},
+		},
+		{
+			"CreateUnsupportedEventType",
+			http.MethodPost,
+			"/admin/webhooks",
+			gin.H{"url": webhook.Url, "event_types": []string{"MoneyPrinted"}},
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				body := requireBodyMatchError(t, recorder, "invalid_argument")
+				require.Equal(t, "MoneyPrinted", body.Details["event_type"])
+			},
+		},
+		{
+			"CreateInvalidURL",
+			http.MethodPost,
+			"/admin/webhooks",
+			gin.H{"url": "ftp://partner.example/hooks", "event_types": webhook.EventTypes},
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				body := requireBodyMatchError(t, recorder, "invalid_argument")
+				require.Equal(t, map[string]any{"url": "http_url"}, body.Details["fields"])
+			},
+		},
+		{
+			"CreateNoEventTypes",
+			http.MethodPost,
+			"/admin/webhooks",
+			gin.H{"url": webhook.Url, "event_types": []string{}},
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			"CreateOperatorForbidden",
+			http.MethodPost,
+			"/admin/webhooks",
+			gin.H{"url": webhook.Url, "event_types": webhook.EventTypes},
+			authAs(util.OperatorRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			"List",
+			http.MethodGet,
+			"/admin/webhooks?page_id=2&page_size=5",
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				arg := db.ListWebhooksParams{Limit: 5, Offset: 5}
+				store.EXPECT().ListWebhooks(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.Webhook{webhook}, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), webhook.SecretCiphertext)
+
+				var rsp []webhookResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp, 1)
+				require.Equal(t, webhook.ID, rsp[0].ID)
+				require.Equal(t, webhook.EventTypes, rsp[0].EventTypes)
+			},
+		},
+		{
+			"GetNotFound",
+			http.MethodGet,
+			"/admin/webhooks/99",
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(int64(99))).
+					Times(1).
+					Return(db.Webhook{}, sql.ErrNoRows)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				body := requireBodyMatchError(t, recorder, "not_found")
+				require.Equal(t, "webhook not found", body.Message)
+			},
+		},
+		{
+			"Disable",
+			http.MethodPost,
+			fmt.Sprintf("/admin/webhooks/%d/disable", webhook.ID),
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				arg := db.SetWebhookActiveParams{ID: webhook.ID, IsActive: false}
+				store.EXPECT().SetWebhookActiveTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(disabledWebhook, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp webhookResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.False(t, rsp.IsActive)
+			},
+		},
+		{
+			"ListDeliveriesByStatus",
+			http.MethodGet,
+			fmt.Sprintf("/admin/webhooks/%d/deliveries?status=dead&page_id=1&page_size=10", webhook.ID),
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				arg := db.ListWebhookDeliveriesParams{
+					WebhookID: webhook.ID,
+					Status:    sql.NullString{String: db.DeliveryDead, Valid: true},
+					Limit:     10,
+					Offset:    0,
+				}
+				store.EXPECT().ListWebhookDeliveries(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.WebhookDelivery{deadDelivery}, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp []webhookDeliveryResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Len(t, rsp, 1)
+				require.Equal(t, deadDelivery.ID, rsp[0].ID)
+				require.Equal(t, db.DeliveryDead, rsp[0].Status)
+				require.Equal(t, deadDelivery.LastError, rsp[0].LastError)
+			},
+		},
+		{
+			"ListDeliveriesAnyStatus",
+			http.MethodGet,
+			fmt.Sprintf("/admin/webhooks/%d/deliveries?page_id=1&page_size=10", webhook.ID),
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				arg := db.ListWebhookDeliveriesParams{WebhookID: webhook.ID, Limit: 10, Offset: 0}
+				store.EXPECT().ListWebhookDeliveries(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.WebhookDelivery{}, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, `[]`, recorder.Body.String())
+			},
+		},
+		{
+			"ListDeliveriesInvalidStatus",
+			http.MethodGet,
+			fmt.Sprintf("/admin/webhooks/%d/deliveries?status=lost&page_id=1&page_size=10", webhook.ID),
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().ListWebhookDeliveries(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			"GetDeliveryWithAttempts",
+			http.MethodGet,
+			fmt.Sprintf("/admin/webhook_deliveries/%d", deadDelivery.ID),
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(deadDelivery.ID)).
+					Times(1).
+					Return(deadDelivery, nil)
+				store.EXPECT().ListWebhookAttempts(gomock.Any(), gomock.Eq(deadDelivery.ID)).
+					Times(1).
+					Return([]db.WebhookAttempt{
+						{ID: 1, DeliveryID: deadDelivery.ID, Attempt: 1, StatusCode: 500, Error: "unexpected status 500", DurationMs: 12},
+						{ID: 2, DeliveryID: deadDelivery.ID, Attempt: 2, StatusCode: 0, Error: "connection refused", DurationMs: 3},
+					}, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp webhookDeliveryDetailResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, deadDelivery.ID, rsp.Delivery.ID)
+				require.Len(t, rsp.Attempts, 2)
+				require.Equal(t, int32(500), rsp.Attempts[0].StatusCode)
+				require.Equal(t, "connection refused", rsp.Attempts[1].Error)
+			},
+		},
+		{
+			"RetryDead",
+			http.MethodPost,
+			fmt.Sprintf("/admin/webhook_deliveries/%d/retry", deadDelivery.ID),
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(deadDelivery.ID)).
+					Times(1).
+					Return(deadDelivery, nil)
+				store.EXPECT().RetryWebhookDeliveryTx(gomock.Any(), gomock.Eq(deadDelivery.ID)).
+					Times(1).
+					Return(retriedDelivery, nil)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp webhookDeliveryResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, db.DeliveryPending, rsp.Status)
+			},
+		},
+		{
+			"RetrySucceeded",
+			http.MethodPost,
+			fmt.Sprintf("/admin/webhook_deliveries/%d/retry", succeededDelivery.ID),
+			nil,
+			authAs(util.AdminRole),
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(succeededDelivery.ID)).
+					Times(1).
+					Return(succeededDelivery, nil)
+				store.EXPECT().RetryWebhookDeliveryTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				body := requireBodyMatchError(t, recorder, "failed_precondition")
+				require.Equal(t, db.DeliverySucceeded, body.Details["status"])
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, buildRequestBody(tc.body))
+			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
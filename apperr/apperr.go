@@ -0,0 +1,119 @@
+// Package apperr defines the errors the API reports to its clients. Each
+// error has a stable Code that clients can switch on and a message that is
+// safe to show, while the underlying cause is kept for logging only.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Code identifies a class of error. Codes are part of the API contract and
+// must not change once published.
+type Code string
+
+const (
+	CodeInvalidArgument    Code = "invalid_argument"
+	CodeUnauthenticated    Code = "unauthenticated"
+	CodePermissionDenied   Code = "permission_denied"
+	CodeNotFound           Code = "not_found"
+	CodeAlreadyExists      Code = "already_exists"
+	CodeConflict           Code = "conflict"
+	CodeFailedPrecondition Code = "failed_precondition"
+	CodeRateLimited        Code = "rate_limited"
+	CodeCanceled           Code = "canceled"
+	CodeInternal           Code = "internal"
+	CodeUnavailable        Code = "unavailable"
+	CodeDeadlineExceeded   Code = "deadline_exceeded"
+)
+
+// StatusClientClosedRequest is the non-standard status, popularised by nginx,
+// for requests the client cancelled before a response was written
+const StatusClientClosedRequest = 499
+
+var httpStatuses = map[Code]int{
+	CodeInvalidArgument:    http.StatusBadRequest,
+	CodeUnauthenticated:    http.StatusUnauthorized,
+	CodePermissionDenied:   http.StatusForbidden,
+	CodeNotFound:           http.StatusNotFound,
+	CodeAlreadyExists:      http.StatusConflict,
+	CodeConflict:           http.StatusConflict,
+	CodeFailedPrecondition: http.StatusUnprocessableEntity,
+	CodeRateLimited:        http.StatusTooManyRequests,
+	CodeCanceled:           StatusClientClosedRequest,
+	CodeInternal:           http.StatusInternalServerError,
+	CodeUnavailable:        http.StatusServiceUnavailable,
+	CodeDeadlineExceeded:   http.StatusGatewayTimeout,
+}
+
+// HTTPStatus returns the HTTP status code reported for code
+func (code Code) HTTPStatus() int {
+	if status, ok := httpStatuses[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is an error with a stable code and a client-safe message
+type Error struct {
+	Code    Code
+	Message string
+	Details map[string]any
+	cause   error
+}
+
+// New creates an Error with the given code and message
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Newf creates an Error with the given code and a formatted message
+func Newf(code Code, format string, args ...any) *Error {
+	return New(code, fmt.Sprintf(format, args...))
+}
+
+// Wrap creates an Error with the given code and message that keeps err as
+// its cause. The cause is logged but never sent to the client.
+func Wrap(err error, code Code, message string) *Error {
+	return &Error{Code: code, Message: message, cause: err}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.cause)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// WithDetail adds a detail to the error and returns it
+func (e *Error) WithDetail(key string, value any) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]any)
+	}
+	e.Details[key] = value
+	return e
+}
+
+// HTTPStatus returns the HTTP status code reported for the error
+func (e *Error) HTTPStatus() int {
+	return e.Code.HTTPStatus()
+}
+
+// As returns err as an *Error if it is one, or nil
+func As(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return nil
+}
+
+// Internal wraps an unexpected error. Its message does not reveal the cause.
+func Internal(err error) *Error {
+	return Wrap(err, CodeInternal, "internal error")
+}
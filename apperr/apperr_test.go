@@ -0,0 +1,32 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	cause := errors.New("boom")
+	err := Wrap(cause, CodeNotFound, "account not found").WithDetail("account_id", 7)
+
+	require.Equal(t, "account not found: boom", err.Error())
+	require.ErrorIs(t, err, cause)
+	require.Equal(t, http.StatusNotFound, err.HTTPStatus())
+	require.Equal(t, map[string]any{"account_id": 7}, err.Details)
+
+	require.Equal(t, "account [7] is frozen", Newf(CodePermissionDenied, "account [%d] is frozen", 7).Error())
+	require.Same(t, err, As(fmt.Errorf("wrapped: %w", err)))
+	require.Nil(t, As(cause))
+}
+
+func TestHTTPStatus(t *testing.T) {
+	for code := range httpStatuses {
+		require.NotZero(t, code.HTTPStatus())
+	}
+	require.Equal(t, http.StatusUnprocessableEntity, CodeFailedPrecondition.HTTPStatus())
+	require.Equal(t, StatusClientClosedRequest, CodeCanceled.HTTPStatus())
+	require.Equal(t, http.StatusInternalServerError, Code("unknown").HTTPStatus())
+}
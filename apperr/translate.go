@@ -0,0 +1,60 @@
+package apperr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+)
+
+// Translate turns an error returned by the store into an Error. resource
+// names the kind of record the operation was about, such as "account", and
+// is used in the message. Errors that already are an *Error are returned
+// unchanged, and errors that are not recognised become internal errors.
+func Translate(err error, resource string) *Error {
+	if appErr := As(err); appErr != nil {
+		return appErr
+	}
+	if resource == "" {
+		resource = "resource"
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return Wrap(err, CodeCanceled, "request was cancelled")
+	case errors.Is(err, context.DeadlineExceeded):
+		return Wrap(err, CodeDeadlineExceeded, "request timed out")
+	case errors.Is(err, sql.ErrNoRows):
+		return Wrap(err, CodeNotFound, resource+" not found")
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return Internal(err)
+	}
+
+	switch pqErr.Code.Name() {
+	case "foreign_key_violation":
+		return constraintError(err, pqErr, CodeFailedPrecondition,
+			resource+" references a record that does not exist or is still referenced by other records")
+	case "unique_violation":
+		return constraintError(err, pqErr, CodeAlreadyExists, resource+" already exists")
+	case "check_violation":
+		return constraintError(err, pqErr, CodeFailedPrecondition, resource+" violates a constraint")
+	case "serialization_failure", "deadlock_detected":
+		return Wrap(err, CodeConflict, "request conflicted with a concurrent update, retry it")
+	case "query_canceled":
+		// Postgres reports statements cancelled on behalf of a context this
+		// way; the caller decides whether the client or the deadline did it
+		return Wrap(err, CodeDeadlineExceeded, "request timed out")
+	}
+	return Internal(err)
+}
+
+func constraintError(err error, pqErr *pq.Error, code Code, message string) *Error {
+	appErr := Wrap(err, code, message)
+	if pqErr.Constraint != "" {
+		appErr.WithDetail("constraint", pqErr.Constraint)
+	}
+	return appErr
+}
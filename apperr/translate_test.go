@@ -0,0 +1,53 @@
+package apperr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		err     error
+		code    Code
+		message string
+	}{
+		{"NoRows", sql.ErrNoRows, CodeNotFound, "account not found"},
+		{"WrappedNoRows", fmt.Errorf("get: %w", sql.ErrNoRows), CodeNotFound, "account not found"},
+		{"ForeignKeyViolation", &pq.Error{Code: "23503"}, CodeFailedPrecondition, "account references a record that does not exist or is still referenced by other records"},
+		{"UniqueViolation", &pq.Error{Code: "23505"}, CodeAlreadyExists, "account already exists"},
+		{"CheckViolation", &pq.Error{Code: "23514"}, CodeFailedPrecondition, "account violates a constraint"},
+		{"SerializationFailure", &pq.Error{Code: "40001"}, CodeConflict, "request conflicted with a concurrent update, retry it"},
+		{"Deadlock", &pq.Error{Code: "40P01"}, CodeConflict, "request conflicted with a concurrent update, retry it"},
+		{"QueryCanceled", &pq.Error{Code: "57014"}, CodeDeadlineExceeded, "request timed out"},
+		{"Canceled", context.Canceled, CodeCanceled, "request was cancelled"},
+		{"DeadlineExceeded", context.DeadlineExceeded, CodeDeadlineExceeded, "request timed out"},
+		{"UnknownPqError", &pq.Error{Code: "42P01", Message: "relation does not exist"}, CodeInternal, "internal error"},
+		{"Unknown", errors.New("boom"), CodeInternal, "internal error"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			appErr := Translate(tc.err, "account")
+			require.Equal(t, tc.code, appErr.Code)
+			require.Equal(t, tc.message, appErr.Message)
+			require.ErrorIs(t, appErr, tc.err)
+		})
+	}
+}
+
+func TestTranslateKeepsAppErrors(t *testing.T) {
+	err := New(CodePermissionDenied, "not yours")
+	require.Same(t, err, Translate(fmt.Errorf("wrapped: %w", err), "account"))
+}
+
+func TestTranslateConstraintDetail(t *testing.T) {
+	appErr := Translate(&pq.Error{Code: "23505", Constraint: "owner_currency_key"}, "")
+	require.Equal(t, "resource already exists", appErr.Message)
+	require.Equal(t, "owner_currency_key", appErr.Details["constraint"])
+}
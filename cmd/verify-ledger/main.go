@@ -0,0 +1,63 @@
+// Command verify-ledger recomputes the hash chains of the entries and
+// transfers tables and reports the first broken link. It exits with status 1
+// if a chain is broken and 2 if the verification could not run.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/ledger"
+	"github.com/julkar-naim/simple-bank/util"
+	_ "github.com/lib/pq"
+	"math"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	accountID := flag.Int64("account", 0, "verify only this account's chains")
+	pageSize := flag.Int("page-size", 500, "rows read per query")
+	flag.Parse()
+
+	report, err := run(*accountID, *pageSize)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot verify ledger:", err)
+		os.Exit(2)
+	}
+
+	if report.Break != nil {
+		fmt.Printf("ledger broken: %s (after %d entries and %d transfers)\n", report.Break, report.Entries, report.Transfers)
+		os.Exit(1)
+	}
+	fmt.Printf("ledger verified: %d accounts, %d entries, %d transfers\n", report.Accounts, report.Entries, report.Transfers)
+}
+
+func run(accountID int64, pageSize int) (ledger.Report, error) {
+	if pageSize < 1 || pageSize > math.MaxInt32 {
+		return ledger.Report{}, fmt.Errorf("invalid page size %d", pageSize)
+	}
+
+	config, err := util.LoadConfig()
+	if err != nil {
+		return ledger.Report{}, fmt.Errorf("cannot read config: %w", err)
+	}
+
+	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	if err != nil {
+		return ledger.Report{}, fmt.Errorf("cannot connect to database: %w", err)
+	}
+	defer conn.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	verifier := ledger.NewVerifier(db.NewSqlStore(conn), int32(pageSize))
+	if accountID > 0 {
+		return verifier.VerifyAccount(ctx, accountID)
+	}
+	return verifier.Verify(ctx)
+}
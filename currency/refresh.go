@@ -0,0 +1,67 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/util"
+	"log/slog"
+	"time"
+)
+
+// Refresh loads the currencies from store into the registry that backs
+// util.IsSupportedCurrency
+func Refresh(ctx context.Context, store db.Store) error {
+	rows, err := store.ListCurrencies(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot list currencies: %w", err)
+	}
+
+	currencies := make([]util.Currency, 0, len(rows))
+	for _, row := range rows {
+		currencies = append(currencies, util.Currency{
+			Code:     row.Code,
+			Exponent: row.Exponent,
+			Symbol:   row.Symbol,
+			Enabled:  row.Enabled,
+		})
+	}
+	util.SetCurrencies(currencies)
+	return nil
+}
+
+// Refresher reloads the registry periodically, so that currencies changed
+// through another instance are picked up
+type Refresher struct {
+	store    db.Store
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+// NewRefresher creates a Refresher that reloads every interval
+func NewRefresher(store db.Store, interval time.Duration, logger *slog.Logger) *Refresher {
+	return &Refresher{
+		store:    store,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run refreshes the registry until ctx is done. A failed refresh keeps the
+// currencies loaded last and is retried on the next tick.
+func (refresher *Refresher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(refresher.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err := Refresh(ctx, refresher.store)
+			if err != nil && ctx.Err() == nil {
+				refresher.logger.Warn("cannot refresh currencies", "error", err)
+			}
+		}
+	}
+}
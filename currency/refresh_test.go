@@ -0,0 +1,90 @@
+package currency
+
+import (
+	"context"
+	"database/sql"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestRefresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	defaults := util.SupportedCurrencies()
+	t.Cleanup(func() { util.SetCurrencies(defaults) })
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListCurrencies(gomock.Any()).
+		Times(1).
+		Return([]db.Currency{
+			{Code: "JPY", Exponent: 0, Symbol: "¥", Enabled: true},
+			{Code: util.USD, Exponent: 2, Symbol: "$", Enabled: true},
+			{Code: util.EUR, Exponent: 2, Symbol: "€", Enabled: false},
+		}, nil)
+
+	err := Refresh(context.Background(), store)
+	require.NoError(t, err)
+
+	require.True(t, util.IsSupportedCurrency("JPY"))
+	require.True(t, util.IsSupportedCurrency(util.USD))
+	require.False(t, util.IsSupportedCurrency(util.EUR))
+	require.False(t, util.IsSupportedCurrency(util.CAD))
+
+	_, ok := util.LookupCurrency(util.EUR)
+	require.True(t, ok)
+}
+
+func TestRefreshKeepsCurrenciesOnError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListCurrencies(gomock.Any()).
+		Times(1).
+		Return(nil, sql.ErrConnDone)
+
+	before := util.SupportedCurrencies()
+	err := Refresh(context.Background(), store)
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	require.Equal(t, before, util.SupportedCurrencies())
+}
+
+func TestRefresherRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	defaults := util.SupportedCurrencies()
+	t.Cleanup(func() { util.SetCurrencies(defaults) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListCurrencies(gomock.Any()).
+		MinTimes(1).
+		DoAndReturn(func(context.Context) ([]db.Currency, error) {
+			cancel()
+			return []db.Currency{{Code: "JPY", Exponent: 0, Symbol: "¥", Enabled: true}}, nil
+		})
+
+	refresher := NewRefresher(store, time.Millisecond, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	done := make(chan error, 1)
+	go func() { done <- refresher.Run(ctx) }()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("refresher did not stop after its context was canceled")
+	}
+	require.True(t, util.IsSupportedCurrency("JPY"))
+}
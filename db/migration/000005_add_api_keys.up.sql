@@ -0,0 +1,24 @@
+CREATE TABLE "api_keys" (
+  "id" bigserial PRIMARY KEY,
+  "key_id" varchar UNIQUE NOT NULL,
+  "name" varchar NOT NULL,
+  "secret_hash" varchar NOT NULL,
+  "scopes" varchar[] NOT NULL DEFAULT '{}',
+  "created_by" varchar NOT NULL,
+  "is_revoked" boolean NOT NULL DEFAULT false,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "api_key_nonces" (
+  "key_id" varchar NOT NULL,
+  "nonce" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("key_id", "nonce")
+);
+
+CREATE INDEX ON "api_key_nonces" ("created_at");
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("username");
+
+ALTER TABLE "api_key_nonces" ADD FOREIGN KEY ("key_id") REFERENCES "api_keys" ("key_id");
@@ -0,0 +1,8 @@
+CREATE TABLE "rate_limit_buckets" (
+  "key" varchar PRIMARY KEY,
+  "tokens" double precision NOT NULL,
+  "updated_at" timestamptz NOT NULL,
+  "full_at" timestamptz NOT NULL
+);
+
+CREATE INDEX ON "rate_limit_buckets" ("full_at");
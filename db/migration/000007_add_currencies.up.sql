@@ -0,0 +1,18 @@
+CREATE TABLE "currencies" (
+  "code" varchar(3) PRIMARY KEY CHECK ("code" ~ '^[A-Z]{3}$'),
+  "exponent" int NOT NULL CHECK ("exponent" BETWEEN 0 AND 4),
+  "symbol" varchar NOT NULL,
+  "enabled" boolean NOT NULL DEFAULT true,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+INSERT INTO "currencies" ("code", "exponent", "symbol") VALUES
+  ('USD', 2, '$'),
+  ('EUR', 2, '€'),
+  ('CAD', 2, 'CA$');
+
+INSERT INTO "currencies" ("code", "exponent", "symbol")
+SELECT DISTINCT "currency", 2, "currency" FROM "accounts"
+ON CONFLICT ("code") DO NOTHING;
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_currency_fkey" FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
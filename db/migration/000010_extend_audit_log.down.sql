@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS "audit_log_no_truncate" ON "audit_log";
+DROP TRIGGER IF EXISTS "audit_log_append_only" ON "audit_log";
+DROP FUNCTION IF EXISTS forbid_mutation();
+
+DROP INDEX IF EXISTS "audit_log_created_at_idx";
+DROP INDEX IF EXISTS "audit_log_action_idx";
+
+ALTER TABLE IF EXISTS "audit_log" DROP COLUMN IF EXISTS "ip";
+ALTER TABLE IF EXISTS "audit_log" DROP COLUMN IF EXISTS "request_id";
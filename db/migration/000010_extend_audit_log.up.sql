@@ -0,0 +1,25 @@
+ALTER TABLE "audit_log" ADD COLUMN "request_id" varchar NOT NULL DEFAULT '';
+ALTER TABLE "audit_log" ADD COLUMN "ip" varchar NOT NULL DEFAULT '';
+
+CREATE INDEX ON "audit_log" ("action");
+
+CREATE INDEX ON "audit_log" ("created_at");
+
+-- forbid_mutation rejects any change to the rows of an append-only table,
+-- including by its owner, who is not bound by grants
+CREATE FUNCTION forbid_mutation() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION '% is append-only', TG_TABLE_NAME
+    USING ERRCODE = 'insufficient_privilege';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "audit_log_append_only"
+  BEFORE UPDATE OR DELETE ON "audit_log"
+  FOR EACH ROW EXECUTE FUNCTION forbid_mutation();
+
+CREATE TRIGGER "audit_log_no_truncate"
+  BEFORE TRUNCATE ON "audit_log"
+  FOR EACH STATEMENT EXECUTE FUNCTION forbid_mutation();
+
+REVOKE UPDATE, DELETE, TRUNCATE ON "audit_log" FROM PUBLIC;
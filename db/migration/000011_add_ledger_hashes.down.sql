@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS "transfers_from_account_id_id_idx";
+DROP INDEX IF EXISTS "entries_account_id_id_idx";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "hash";
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "prev_hash";
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "hash";
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "prev_hash";
@@ -0,0 +1,62 @@
+ALTER TABLE "entries" ADD COLUMN "prev_hash" varchar NOT NULL DEFAULT '';
+ALTER TABLE "entries" ADD COLUMN "hash" varchar NOT NULL DEFAULT '';
+ALTER TABLE "transfers" ADD COLUMN "prev_hash" varchar NOT NULL DEFAULT '';
+ALTER TABLE "transfers" ADD COLUMN "hash" varchar NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN "entries"."hash" IS 'sha256 of the entry and the hash of the previous entry of its account';
+COMMENT ON COLUMN "transfers"."hash" IS 'sha256 of the transfer and the hash of the previous transfer from its source account';
+
+-- the chains are walked per account in id order
+CREATE INDEX ON "entries" ("account_id", "id");
+
+CREATE INDEX ON "transfers" ("from_account_id", "id");
+
+-- ledger_unix_micros matches time.Time.UnixMicro in db.EntryHash and
+-- db.TransferHash. extract(epoch) alone loses microseconds to rounding.
+CREATE FUNCTION ledger_unix_micros(t timestamptz) RETURNS bigint AS $$
+  SELECT extract(epoch FROM date_trunc('second', t))::bigint * 1000000
+    + extract(microseconds FROM t)::bigint % 1000000;
+$$ LANGUAGE sql IMMUTABLE;
+
+-- chain the rows written before this migration
+DO $$
+DECLARE
+  r record;
+  prev varchar;
+  current_account bigint;
+BEGIN
+  FOR r IN SELECT id, account_id, amount, created_at FROM entries ORDER BY account_id, id LOOP
+    IF current_account IS DISTINCT FROM r.account_id THEN
+      current_account := r.account_id;
+      prev := '';
+    END IF;
+    UPDATE entries
+    SET prev_hash = prev,
+        hash = encode(sha256(convert_to(format('entry|%s|%s|%s|%s',
+          r.account_id, r.amount, ledger_unix_micros(r.created_at), prev), 'UTF8')), 'hex')
+    WHERE id = r.id
+    RETURNING hash INTO prev;
+  END LOOP;
+
+  current_account := NULL;
+  FOR r IN SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers ORDER BY from_account_id, id LOOP
+    IF current_account IS DISTINCT FROM r.from_account_id THEN
+      current_account := r.from_account_id;
+      prev := '';
+    END IF;
+    UPDATE transfers
+    SET prev_hash = prev,
+        hash = encode(sha256(convert_to(format('transfer|%s|%s|%s|%s|%s',
+          r.from_account_id, r.to_account_id, r.amount, ledger_unix_micros(r.created_at), prev), 'UTF8')), 'hex')
+    WHERE id = r.id
+    RETURNING hash INTO prev;
+  END LOOP;
+END $$;
+
+DROP FUNCTION ledger_unix_micros(timestamptz);
+
+-- new rows must be chained by the application
+ALTER TABLE "entries" ALTER COLUMN "prev_hash" DROP DEFAULT;
+ALTER TABLE "entries" ALTER COLUMN "hash" DROP DEFAULT;
+ALTER TABLE "transfers" ALTER COLUMN "prev_hash" DROP DEFAULT;
+ALTER TABLE "transfers" ALTER COLUMN "hash" DROP DEFAULT;
@@ -0,0 +1,17 @@
+COMMENT ON COLUMN "transfers"."amount" IS 'must be positive';
+
+ALTER TABLE IF EXISTS "transfers"
+  DROP CONSTRAINT IF EXISTS "transfers_prev_hash_check",
+  DROP CONSTRAINT IF EXISTS "transfers_hash_check",
+  DROP CONSTRAINT IF EXISTS "transfers_accounts_check",
+  DROP CONSTRAINT IF EXISTS "transfers_amount_check";
+
+ALTER TABLE IF EXISTS "entries"
+  DROP CONSTRAINT IF EXISTS "entries_prev_hash_check",
+  DROP CONSTRAINT IF EXISTS "entries_hash_check",
+  DROP CONSTRAINT IF EXISTS "entries_amount_check";
+
+DROP TRIGGER IF EXISTS "transfers_no_truncate" ON "transfers";
+DROP TRIGGER IF EXISTS "transfers_append_only" ON "transfers";
+DROP TRIGGER IF EXISTS "entries_no_truncate" ON "entries";
+DROP TRIGGER IF EXISTS "entries_append_only" ON "entries";
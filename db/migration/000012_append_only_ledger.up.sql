@@ -0,0 +1,32 @@
+-- entries and transfers are the ledger: corrections are made by appending
+-- rows, never by changing or removing them
+CREATE TRIGGER "entries_append_only"
+  BEFORE UPDATE OR DELETE ON "entries"
+  FOR EACH ROW EXECUTE FUNCTION forbid_mutation();
+
+CREATE TRIGGER "entries_no_truncate"
+  BEFORE TRUNCATE ON "entries"
+  FOR EACH STATEMENT EXECUTE FUNCTION forbid_mutation();
+
+CREATE TRIGGER "transfers_append_only"
+  BEFORE UPDATE OR DELETE ON "transfers"
+  FOR EACH ROW EXECUTE FUNCTION forbid_mutation();
+
+CREATE TRIGGER "transfers_no_truncate"
+  BEFORE TRUNCATE ON "transfers"
+  FOR EACH STATEMENT EXECUTE FUNCTION forbid_mutation();
+
+REVOKE UPDATE, DELETE, TRUNCATE ON "entries", "transfers" FROM PUBLIC;
+
+ALTER TABLE "entries"
+  ADD CONSTRAINT "entries_amount_check" CHECK ("amount" <> 0),
+  ADD CONSTRAINT "entries_hash_check" CHECK ("hash" ~ '^[0-9a-f]{64}$'),
+  ADD CONSTRAINT "entries_prev_hash_check" CHECK ("prev_hash" = '' OR "prev_hash" ~ '^[0-9a-f]{64}$');
+
+ALTER TABLE "transfers"
+  ADD CONSTRAINT "transfers_amount_check" CHECK ("amount" > 0),
+  ADD CONSTRAINT "transfers_accounts_check" CHECK ("from_account_id" <> "to_account_id"),
+  ADD CONSTRAINT "transfers_hash_check" CHECK ("hash" ~ '^[0-9a-f]{64}$'),
+  ADD CONSTRAINT "transfers_prev_hash_check" CHECK ("prev_hash" = '' OR "prev_hash" ~ '^[0-9a-f]{64}$');
+
+COMMENT ON COLUMN "transfers"."amount" IS NULL;
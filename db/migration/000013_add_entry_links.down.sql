@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS "entries_transfer_id_idx";
+
+ALTER TABLE IF EXISTS "entries" DROP CONSTRAINT IF EXISTS "entries_transfer_id_check";
+
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "description";
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "entry_type";
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "transfer_id";
+
+DROP TYPE IF EXISTS "entry_type";
@@ -0,0 +1,68 @@
+CREATE TYPE "entry_type" AS ENUM (
+  'transfer',
+  'fee',
+  'adjustment',
+  'interest',
+  'deposit',
+  'withdrawal',
+  'reversal'
+);
+
+-- entries of unknown origin are left as adjustments by the backfill below
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+ALTER TABLE "entries" ADD COLUMN "entry_type" entry_type NOT NULL DEFAULT 'adjustment';
+ALTER TABLE "entries" ADD COLUMN "description" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "entries"
+  ADD CONSTRAINT "entries_transfer_id_check" CHECK ("entry_type" <> 'transfer' OR "transfer_id" IS NOT NULL);
+
+CREATE INDEX ON "entries" ("transfer_id");
+
+-- link the entries written before this migration to their transfer. Both
+-- entries of a transfer were written in its transaction, right after it, so
+-- a pair is the earliest unlinked entry of each account with the transfer's
+-- amount within a second of it. Transfers whose pair cannot be found are
+-- left alone.
+ALTER TABLE "entries" DISABLE TRIGGER "entries_append_only";
+
+DO $$
+DECLARE
+  t record;
+  from_entry bigint;
+  to_entry bigint;
+BEGIN
+  FOR t IN SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers ORDER BY id LOOP
+    SELECT id INTO from_entry FROM entries
+    WHERE account_id = t.from_account_id AND amount = -t.amount AND transfer_id IS NULL
+      AND created_at BETWEEN t.created_at AND t.created_at + interval '1 second'
+    ORDER BY created_at, id
+    LIMIT 1;
+
+    SELECT id INTO to_entry FROM entries
+    WHERE account_id = t.to_account_id AND amount = t.amount AND transfer_id IS NULL
+      AND created_at BETWEEN t.created_at AND t.created_at + interval '1 second'
+    ORDER BY created_at, id
+    LIMIT 1;
+
+    IF from_entry IS NOT NULL AND to_entry IS NOT NULL THEN
+      UPDATE entries
+      SET transfer_id = t.id,
+          entry_type = 'transfer',
+          description = format('transfer to account %s', t.to_account_id)
+      WHERE id = from_entry;
+
+      UPDATE entries
+      SET transfer_id = t.id,
+          entry_type = 'transfer',
+          description = format('transfer from account %s', t.from_account_id)
+      WHERE id = to_entry;
+    END IF;
+  END LOOP;
+END $$;
+
+ALTER TABLE "entries" ENABLE TRIGGER "entries_append_only";
+
+-- new rows must be classified by the application
+ALTER TABLE "entries" ALTER COLUMN "entry_type" DROP DEFAULT;
@@ -0,0 +1 @@
+ALTER TABLE IF EXISTS "api_keys" RENAME COLUMN "secret_ciphertext" TO "secret_hash";
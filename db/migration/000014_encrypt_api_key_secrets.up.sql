@@ -0,0 +1,7 @@
+-- secret_hash held the SHA-256 of each secret, which was also the key that
+-- signed requests, so anyone who could read the table could sign them.
+-- Those keys are revoked and their hashes dropped; new keys store the secret
+-- encrypted under the server's API_KEY_ENCRYPTION_KEY.
+ALTER TABLE "api_keys" RENAME COLUMN "secret_hash" TO "secret_ciphertext";
+
+UPDATE "api_keys" SET "is_revoked" = true, "secret_ciphertext" = '';
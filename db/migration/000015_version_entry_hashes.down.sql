@@ -0,0 +1,42 @@
+CREATE FUNCTION ledger_unix_micros(t timestamptz) RETURNS bigint AS $$
+  SELECT extract(epoch FROM date_trunc('second', t))::bigint * 1000000
+    + extract(microseconds FROM t)::bigint % 1000000;
+$$ LANGUAGE sql IMMUTABLE;
+
+-- rechain every entry with the unversioned content of the 000011 migration,
+-- after checking it against its version 2 hash
+ALTER TABLE "entries" DISABLE TRIGGER "entries_append_only";
+
+DO $$
+DECLARE
+  r record;
+  prev varchar;
+  prev_v2 varchar;
+  current_account bigint;
+BEGIN
+  FOR r IN SELECT * FROM entries ORDER BY account_id, id LOOP
+    IF current_account IS DISTINCT FROM r.account_id THEN
+      current_account := r.account_id;
+      prev := '';
+      prev_v2 := '';
+    END IF;
+    IF r.prev_hash <> prev_v2 OR r.hash <> encode(sha256(convert_to(format('entry|v2|%s|%s|%s|%s|%s|%s|%s',
+        r.account_id, r.amount, ledger_unix_micros(r.created_at),
+        r.transfer_id, r.entry_type, r.description, r.prev_hash), 'UTF8')), 'hex') THEN
+      RAISE EXCEPTION 'entry % of account % does not match its version 2 hash', r.id, r.account_id;
+    END IF;
+    prev_v2 := r.hash;
+    UPDATE entries
+    SET prev_hash = prev,
+        hash = encode(sha256(convert_to(format('entry|%s|%s|%s|%s',
+          r.account_id, r.amount, ledger_unix_micros(r.created_at), prev), 'UTF8')), 'hex')
+    WHERE id = r.id
+    RETURNING hash INTO prev;
+  END LOOP;
+END $$;
+
+ALTER TABLE "entries" ENABLE TRIGGER "entries_append_only";
+
+DROP FUNCTION ledger_unix_micros(timestamptz);
+
+COMMENT ON COLUMN "entries"."hash" IS 'sha256 of the entry and the hash of the previous entry of its account';
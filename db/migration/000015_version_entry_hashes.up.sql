@@ -0,0 +1,46 @@
+COMMENT ON COLUMN "entries"."hash" IS 'sha256 of the entry, its transfer, type and description, and the hash of the previous entry of its account';
+
+-- ledger_unix_micros matches time.Time.UnixMicro in db.EntryHash
+CREATE FUNCTION ledger_unix_micros(t timestamptz) RETURNS bigint AS $$
+  SELECT extract(epoch FROM date_trunc('second', t))::bigint * 1000000
+    + extract(microseconds FROM t)::bigint % 1000000;
+$$ LANGUAGE sql IMMUTABLE;
+
+-- rechain every entry with version 2 of the hashed content, which adds
+-- transfer_id, entry_type and description. format renders a NULL
+-- transfer_id as an empty string, like db.EntryHash. Each entry is first
+-- checked against its version 1 hash, so that a chain tampered with before
+-- this migration fails it instead of being rehashed into a valid one.
+ALTER TABLE "entries" DISABLE TRIGGER "entries_append_only";
+
+DO $$
+DECLARE
+  r record;
+  prev varchar;
+  prev_v1 varchar;
+  current_account bigint;
+BEGIN
+  FOR r IN SELECT * FROM entries ORDER BY account_id, id LOOP
+    IF current_account IS DISTINCT FROM r.account_id THEN
+      current_account := r.account_id;
+      prev := '';
+      prev_v1 := '';
+    END IF;
+    IF r.prev_hash <> prev_v1 OR r.hash <> encode(sha256(convert_to(format('entry|%s|%s|%s|%s',
+        r.account_id, r.amount, ledger_unix_micros(r.created_at), r.prev_hash), 'UTF8')), 'hex') THEN
+      RAISE EXCEPTION 'entry % of account % does not match its version 1 hash', r.id, r.account_id;
+    END IF;
+    prev_v1 := r.hash;
+    UPDATE entries
+    SET prev_hash = prev,
+        hash = encode(sha256(convert_to(format('entry|v2|%s|%s|%s|%s|%s|%s|%s',
+          r.account_id, r.amount, ledger_unix_micros(r.created_at),
+          r.transfer_id, r.entry_type, r.description, prev), 'UTF8')), 'hex')
+    WHERE id = r.id
+    RETURNING hash INTO prev;
+  END LOOP;
+END $$;
+
+ALTER TABLE "entries" ENABLE TRIGGER "entries_append_only";
+
+DROP FUNCTION ledger_unix_micros(timestamptz);
@@ -0,0 +1,4 @@
+-- txid_current() is the Postgres 12 form of pg_current_xact_id()
+ALTER TABLE "outbox" ADD COLUMN "txid" bigint NOT NULL DEFAULT (txid_current());
+
+COMMENT ON COLUMN "outbox"."txid" IS 'id of the transaction that wrote the event; the relay delivers it once every transaction up to this one has ended';
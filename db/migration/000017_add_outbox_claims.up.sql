@@ -0,0 +1,3 @@
+ALTER TABLE "outbox" ADD COLUMN "claimed_until" timestamptz;
+
+COMMENT ON COLUMN "outbox"."claimed_until" IS 'end of the lease of the relay publishing the event, which no other relay takes over before it runs out';
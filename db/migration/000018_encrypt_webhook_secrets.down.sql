@@ -0,0 +1 @@
+ALTER TABLE IF EXISTS "webhooks" RENAME COLUMN "secret_ciphertext" TO "secret";
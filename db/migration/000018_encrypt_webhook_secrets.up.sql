@@ -0,0 +1,7 @@
+-- secret held each webhook's signing secret in plaintext, so anyone who
+-- could read the table could sign deliveries. The key that encrypts them is
+-- only in the server's WEBHOOK_ENCRYPTION_KEY, so the existing webhooks are
+-- disabled and their secrets dropped; they must be created again.
+ALTER TABLE "webhooks" RENAME COLUMN "secret" TO "secret_ciphertext";
+
+UPDATE "webhooks" SET "is_active" = false, "secret_ciphertext" = '';
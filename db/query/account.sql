@@ -18,14 +18,14 @@ FOR NO KEY UPDATE;
 
 -- name: ListAccounts :many
 SELECT * FROM accounts
+WHERE owner = $1
 ORDER BY id
-LIMIT $1
-OFFSET $2;
+LIMIT $2
+OFFSET $3;
 
-
--- name: UpdateAccount :one
+-- name: UpdateAccountOwner :one
 UPDATE accounts
-SET owner = $2, balance = $3, currency = $4
+SET owner = $2
 WHERE id = $1
 RETURNING *;
 
@@ -33,8 +33,31 @@ RETURNING *;
 UPDATE accounts
 SET balance = balance + sqlc.arg(amount)
 WHERE id = sqlc.arg(id)
-    RETURNING *;
+RETURNING *;
 
 -- name: DeleteAccount :exec
 DELETE FROM accounts
 WHERE id = $1;
+
+-- name: ListAllAccounts :many
+SELECT * FROM accounts
+ORDER BY id
+LIMIT $1
+OFFSET $2;
+
+-- name: SetAccountFrozen :one
+UPDATE accounts
+SET is_frozen = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: ListUnreconciledAccounts :many
+SELECT a.id, a.owner, a.currency, a.balance,
+       COALESCE(SUM(e.amount), 0)::bigint AS entries_total
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+GROUP BY a.id
+HAVING a.balance <> COALESCE(SUM(e.amount), 0)
+ORDER BY a.id
+LIMIT $1
+OFFSET $2;
@@ -0,0 +1,39 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (
+    key_id,
+    name,
+    secret_ciphertext,
+    scopes,
+    created_by,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetAPIKey :one
+SELECT * FROM api_keys
+WHERE key_id = $1 LIMIT 1;
+
+-- name: ListAPIKeys :many
+SELECT * FROM api_keys
+ORDER BY id
+LIMIT $1
+OFFSET $2;
+
+-- name: RevokeAPIKey :one
+UPDATE api_keys
+SET is_revoked = true
+WHERE key_id = $1
+RETURNING *;
+
+-- name: CreateAPIKeyNonce :exec
+INSERT INTO api_key_nonces (
+    key_id,
+    nonce
+) VALUES (
+    $1, $2
+);
+
+-- name: DeleteAPIKeyNonces :exec
+DELETE FROM api_key_nonces
+WHERE created_at < $1;
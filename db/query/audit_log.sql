@@ -0,0 +1,25 @@
+-- name: CreateAuditLog :one
+INSERT INTO audit_log (
+    actor,
+    action,
+    target_type,
+    target_id,
+    before,
+    after,
+    request_id,
+    ip
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: ListAuditLogs :many
+SELECT * FROM audit_log
+WHERE (sqlc.narg(actor)::varchar IS NULL OR actor = sqlc.narg(actor))
+    AND (sqlc.narg(action)::varchar IS NULL OR action = sqlc.narg(action))
+    AND (sqlc.narg(target_type)::varchar IS NULL OR target_type = sqlc.narg(target_type))
+    AND (sqlc.narg(target_id)::varchar IS NULL OR target_id = sqlc.narg(target_id))
+    AND (sqlc.narg(created_from)::timestamptz IS NULL OR created_at >= sqlc.narg(created_from))
+    AND (sqlc.narg(created_to)::timestamptz IS NULL OR created_at < sqlc.narg(created_to))
+ORDER BY id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
@@ -1,9 +1,15 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
     account_id,
-    amount
+    amount,
+    created_at,
+    prev_hash,
+    hash,
+    transfer_id,
+    entry_type,
+    description
 ) VALUES (
-    $1, $2
+    $1, $2, $3, $4, $5, $6, $7, $8
 ) RETURNING *;
 
 -- name: GetEntry :one
@@ -17,11 +23,30 @@ ORDER BY id
 LIMIT $2
 OFFSET $3;
 
--- name: DeleteEntry :exec
-DELETE FROM entries
-WHERE id = $1;
-
 -- name: GetAccountEntries :many
 SELECT * FROM entries
 WHERE account_id = $1
 ORDER BY created_at DESC;
+
+-- name: ListEntriesAfter :many
+SELECT * FROM entries
+WHERE account_id = $1 AND id > $2
+ORDER BY id
+LIMIT $3;
+
+-- name: GetLastEntryID :one
+SELECT COALESCE(MAX(id), 0)::bigint FROM entries
+WHERE account_id = $1;
+
+-- name: GetLastEntryHash :one
+SELECT COALESCE((
+    SELECT hash FROM entries
+    WHERE account_id = $1
+    ORDER BY id DESC
+    LIMIT 1
+), '')::varchar;
+
+-- name: ListTransferEntries :many
+SELECT * FROM entries
+WHERE transfer_id = $1
+ORDER BY id;
@@ -0,0 +1,43 @@
+-- name: ClaimOutboxEvents :many
+UPDATE outbox
+SET claimed_until = now() + make_interval(secs => sqlc.arg(lease_seconds)::float8)
+WHERE id IN (
+    SELECT id FROM outbox
+    WHERE sent_at IS NULL
+      AND txid < txid_snapshot_xmin(txid_current_snapshot())
+    ORDER BY id
+    LIMIT sqlc.arg('limit')
+)
+AND NOT EXISTS (
+    SELECT 1 FROM outbox
+    WHERE sent_at IS NULL AND claimed_until > now()
+)
+RETURNING *;
+
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox (
+    event_type,
+    aggregate_type,
+    aggregate_id,
+    payload
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetOutboxEvent :one
+SELECT * FROM outbox
+WHERE id = $1 LIMIT 1;
+
+-- name: LockOutboxClaims :exec
+SELECT pg_advisory_xact_lock(hashtext('outbox_claims'));
+
+-- name: MarkOutboxEventsSent :exec
+UPDATE outbox
+SET sent_at = now(),
+    claimed_until = NULL
+WHERE id = ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: ReleaseOutboxEvents :exec
+UPDATE outbox
+SET claimed_until = NULL
+WHERE id = ANY(sqlc.arg(ids)::bigint[]);
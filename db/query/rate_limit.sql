@@ -0,0 +1,25 @@
+-- name: CreateRateLimitBucket :exec
+INSERT INTO rate_limit_buckets (
+    key,
+    tokens,
+    updated_at,
+    full_at
+) VALUES (
+    $1, $2, $3, $4
+) ON CONFLICT (key) DO NOTHING;
+
+-- name: GetRateLimitBucketForUpdate :one
+SELECT * FROM rate_limit_buckets
+WHERE key = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: UpdateRateLimitBucket :exec
+UPDATE rate_limit_buckets
+SET tokens = $2,
+    updated_at = $3,
+    full_at = $4
+WHERE key = $1;
+
+-- name: DeleteFullRateLimitBuckets :exec
+DELETE FROM rate_limit_buckets
+WHERE full_at < $1;
@@ -2,9 +2,12 @@
 INSERT INTO transfers (
     from_account_id,
     to_account_id,
-    amount
+    amount,
+    created_at,
+    prev_hash,
+    hash
 ) VALUES (
-    $1, $2, $3
+    $1, $2, $3, $4, $5, $6
 ) RETURNING *;
 
 -- name: GetTransfer :one
@@ -17,6 +20,16 @@ ORDER BY id
     LIMIT $1
 OFFSET $2;
 
--- name: DeleteTransfer :exec
-DELETE FROM transfers
-WHERE id = $1;
+-- name: ListTransfersFromAccountAfter :many
+SELECT * FROM transfers
+WHERE from_account_id = $1 AND id > $2
+ORDER BY id
+LIMIT $3;
+
+-- name: GetLastTransferHash :one
+SELECT COALESCE((
+    SELECT hash FROM transfers
+    WHERE from_account_id = $1
+    ORDER BY id DESC
+    LIMIT 1
+), '')::varchar;
@@ -7,13 +7,15 @@ import (
 	"time"
 
 	"github.com/julkar-naim/simple-bank/util"
+	"github.com/lib/pq"
 
 	"github.com/stretchr/testify/require"
 )
 
 func TestCreateAccount(t *testing.T) {
+	user := createRandomUser(t)
 	arg := CreateAccountParams{
-		Owner:    util.RandomOwner(),
+		Owner:    user.Username,
 		Balance:  util.RandomMoney(),
 		Currency: util.RandomCurrency(),
 	}
@@ -44,17 +46,16 @@ func TestGetAccount(t *testing.T) {
 	require.WithinDuration(t, account1.CreatedAt, account2.CreatedAt, time.Second)
 }
 
-func TestUpdateAccount(t *testing.T) {
+func TestUpdateAccountOwner(t *testing.T) {
 	account1 := createRandomAccount(t)
+	user := createRandomUser(t)
 
-	arg := UpdateAccountParams{
-		ID:       account1.ID,
-		Balance:  util.RandomMoney(),
-		Owner:    util.RandomOwner(),
-		Currency: util.RandomCurrency(),
+	arg := UpdateAccountOwnerParams{
+		ID:    account1.ID,
+		Owner: user.Username,
 	}
 
-	_, err := testQueries.UpdateAccount(context.Background(), arg)
+	_, err := testQueries.UpdateAccountOwner(context.Background(), arg)
 	require.NoError(t, err)
 
 	updatedAccount, err := testQueries.GetAccount(context.Background(), account1.ID)
@@ -63,8 +64,8 @@ func TestUpdateAccount(t *testing.T) {
 
 	require.Equal(t, account1.ID, updatedAccount.ID)
 	require.Equal(t, arg.Owner, updatedAccount.Owner)
-	require.Equal(t, arg.Balance, updatedAccount.Balance)
-	require.Equal(t, arg.Currency, updatedAccount.Currency)
+	require.Equal(t, account1.Balance, updatedAccount.Balance)
+	require.Equal(t, account1.Currency, updatedAccount.Currency)
 	require.WithinDuration(t, account1.CreatedAt, updatedAccount.CreatedAt, time.Second)
 }
 
@@ -79,28 +80,129 @@ func TestDeleteAccount(t *testing.T) {
 	require.Empty(t, account2)
 }
 
+func TestCreateAccountDuplicateCurrency(t *testing.T) {
+	account1 := createRandomAccount(t)
+
+	arg := CreateAccountParams{
+		Owner:    account1.Owner,
+		Balance:  util.RandomMoney(),
+		Currency: account1.Currency,
+	}
+
+	account2, err := testQueries.CreateAccount(context.Background(), arg)
+	require.Error(t, err)
+	require.Empty(t, account2)
+
+	var pqErr *pq.Error
+	require.ErrorAs(t, err, &pqErr)
+	require.Equal(t, "unique_violation", pqErr.Code.Name())
+}
+
 func TestListAccounts(t *testing.T) {
+	var lastAccount Account
 	for i := 0; i < 10; i++ {
-		createRandomAccount(t)
+		lastAccount = createRandomAccount(t)
 	}
 
 	arg := ListAccountsParams{
+		Owner:  lastAccount.Owner,
 		Limit:  5,
-		Offset: 5,
+		Offset: 0,
 	}
 
 	accounts, err := testQueries.ListAccounts(context.Background(), arg)
 	require.NoError(t, err)
-	require.Len(t, accounts, 5)
+	require.NotEmpty(t, accounts)
 
 	for _, account := range accounts {
 		require.NotEmpty(t, account)
+		require.Equal(t, lastAccount.Owner, account.Owner)
+	}
+}
+
+func TestListAllAccounts(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		createRandomAccount(t)
+	}
+
+	accounts, err := testQueries.ListAllAccounts(context.Background(), ListAllAccountsParams{
+		Limit:  5,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, accounts, 5)
+}
+
+func TestSetAccountFrozen(t *testing.T) {
+	account1 := createRandomAccount(t)
+	require.False(t, account1.IsFrozen)
+
+	account2, err := testQueries.SetAccountFrozen(context.Background(), SetAccountFrozenParams{
+		ID:       account1.ID,
+		IsFrozen: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account1.ID, account2.ID)
+	require.True(t, account2.IsFrozen)
+
+	account3, err := testQueries.SetAccountFrozen(context.Background(), SetAccountFrozenParams{
+		ID:       account1.ID,
+		IsFrozen: false,
+	})
+	require.NoError(t, err)
+	require.False(t, account3.IsFrozen)
+}
+
+func TestListUnreconciledAccounts(t *testing.T) {
+	// an opening balance has no entries behind it
+	account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Balance:  100,
+		Currency: util.RandomCurrency(),
+	})
+	require.NoError(t, err)
+
+	var found *ListUnreconciledAccountsRow
+	for offset := int32(0); found == nil; offset += 100 {
+		rows, err := testQueries.ListUnreconciledAccounts(context.Background(), ListUnreconciledAccountsParams{
+			Limit:  100,
+			Offset: offset,
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, rows)
+
+		for i := range rows {
+			if rows[i].ID == account.ID {
+				found = &rows[i]
+			}
+		}
+	}
+	require.Equal(t, int64(100), found.Balance)
+	require.Zero(t, found.EntriesTotal)
+}
+
+// createTransferAccounts creates two accounts in the same currency that can
+// each cover the transfers of a test
+func createTransferAccounts(t *testing.T) (Account, Account) {
+	currency := util.RandomCurrency()
+	accounts := make([]Account, 2)
+	for i := range accounts {
+		user := createRandomUser(t)
+		account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+			Owner:    user.Username,
+			Balance:  util.RandomMoney() + 1000,
+			Currency: currency,
+		})
+		require.NoError(t, err)
+		accounts[i] = account
 	}
+	return accounts[0], accounts[1]
 }
 
 func createRandomAccount(t *testing.T) Account {
+	user := createRandomUser(t)
 	arg := CreateAccountParams{
-		Owner:    util.RandomOwner(),
+		Owner:    user.Username,
 		Balance:  util.RandomMoney(),
 		Currency: util.RandomCurrency(),
 	}
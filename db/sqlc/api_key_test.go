@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAPIKey(t *testing.T) ApiKey {
+	user := createRandomUser(t)
+
+	arg := CreateAPIKeyParams{
+		KeyID:            util.RandomString(16),
+		Name:             util.RandomString(8),
+		SecretCiphertext: util.RandomString(32),
+		Scopes:           []string{string(util.PermReconcile), string(util.PermFreezeAccounts)},
+		CreatedBy:        user.Username,
+		ExpiresAt:        time.Now().Add(time.Hour),
+	}
+
+	apiKey, err := testQueries.CreateAPIKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, apiKey)
+
+	require.Equal(t, arg.KeyID, apiKey.KeyID)
+	require.Equal(t, arg.Name, apiKey.Name)
+	require.Equal(t, arg.SecretCiphertext, apiKey.SecretCiphertext)
+	require.Equal(t, arg.Scopes, apiKey.Scopes)
+	require.Equal(t, arg.CreatedBy, apiKey.CreatedBy)
+	require.False(t, apiKey.IsRevoked)
+	require.WithinDuration(t, arg.ExpiresAt, apiKey.ExpiresAt, time.Second)
+	require.NotZero(t, apiKey.ID)
+	require.NotZero(t, apiKey.CreatedAt)
+
+	return apiKey
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	createRandomAPIKey(t)
+}
+
+func TestGetAPIKey(t *testing.T) {
+	apiKey1 := createRandomAPIKey(t)
+
+	apiKey2, err := testQueries.GetAPIKey(context.Background(), apiKey1.KeyID)
+	require.NoError(t, err)
+	require.Equal(t, apiKey1.ID, apiKey2.ID)
+	require.Equal(t, apiKey1.SecretCiphertext, apiKey2.SecretCiphertext)
+	require.Equal(t, apiKey1.Scopes, apiKey2.Scopes)
+
+	_, err = testQueries.GetAPIKey(context.Background(), util.RandomString(16))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestListAPIKeys(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		createRandomAPIKey(t)
+	}
+
+	arg := ListAPIKeysParams{
+		Limit:  5,
+		Offset: 0,
+	}
+
+	apiKeys, err := testQueries.ListAPIKeys(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 5)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	apiKey1 := createRandomAPIKey(t)
+
+	apiKey2, err := testQueries.RevokeAPIKey(context.Background(), apiKey1.KeyID)
+	require.NoError(t, err)
+	require.Equal(t, apiKey1.KeyID, apiKey2.KeyID)
+	require.True(t, apiKey2.IsRevoked)
+}
+
+func TestAPIKeyNonce(t *testing.T) {
+	apiKey := createRandomAPIKey(t)
+
+	arg := CreateAPIKeyNonceParams{
+		KeyID: apiKey.KeyID,
+		Nonce: util.RandomString(16),
+	}
+	require.NoError(t, testQueries.CreateAPIKeyNonce(context.Background(), arg))
+
+	err := testQueries.CreateAPIKeyNonce(context.Background(), arg)
+	var pqErr *pq.Error
+	require.ErrorAs(t, err, &pqErr)
+	require.Equal(t, "unique_violation", pqErr.Code.Name())
+
+	// once purged the nonce can be stored again
+	require.NoError(t, testQueries.DeleteAPIKeyNonces(context.Background(), time.Now().Add(time.Minute)))
+	require.NoError(t, testQueries.CreateAPIKeyNonce(context.Background(), arg))
+}
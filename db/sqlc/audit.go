@@ -0,0 +1,200 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julkar-naim/simple-bank/util"
+)
+
+// audit log actions recorded by the store
+const (
+	AuditActionCreateAccount        = "account.create"
+	AuditActionUpdateAccount        = "account.update"
+	AuditActionDeleteAccount        = "account.delete"
+	AuditActionFreezeAccount        = "account.freeze"
+	AuditActionUnfreezeAccount      = "account.unfreeze"
+	AuditActionCreateTransfer       = "transfer.create"
+	AuditActionCreateUser           = "user.create"
+	AuditActionUpdateUserRole       = "user.update_role"
+	AuditActionCreateAPIKey         = "api_key.create"
+	AuditActionRevokeAPIKey         = "api_key.revoke"
+	AuditActionCreateCurrency       = "currency.create"
+	AuditActionEnableCurrency       = "currency.enable"
+	AuditActionDisableCurrency      = "currency.disable"
+	AuditActionCreateWebhook        = "webhook.create"
+	AuditActionEnableWebhook        = "webhook.enable"
+	AuditActionDisableWebhook       = "webhook.disable"
+	AuditActionRetryWebhookDelivery = "webhook_delivery.retry"
+	AuditActionCreateSession        = "session.create"
+	AuditActionRevokeSession        = "session.revoke"
+)
+
+// audit log target types
+const (
+	AuditTargetAccount         = "account"
+	AuditTargetTransfer        = "transfer"
+	AuditTargetUser            = "user"
+	AuditTargetAPIKey          = "api_key"
+	AuditTargetCurrency        = "currency"
+	AuditTargetWebhook         = "webhook"
+	AuditTargetWebhookDelivery = "webhook_delivery"
+	AuditTargetSession         = "session"
+)
+
+// AuditSystemActor is recorded as the actor of changes made without an
+// AuditActor in the context, such as those of background workers
+const AuditSystemActor = "system"
+
+// AuditActor identifies who asked for a change and from where
+type AuditActor struct {
+	Name string
+	IP   string
+}
+
+type auditActorKey struct{}
+
+// WithAuditActor returns a copy of ctx carrying the actor recorded in the
+// audit log by the store's transactions
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// AuditActorFromContext returns the actor stored by WithAuditActor, or
+// AuditSystemActor
+func AuditActorFromContext(ctx context.Context) AuditActor {
+	actor, ok := ctx.Value(auditActorKey{}).(AuditActor)
+	if !ok || actor.Name == "" {
+		actor.Name = AuditSystemActor
+	}
+	return actor
+}
+
+// recordAudit writes an audit log entry for the actor in ctx. before and
+// after are marshalled to JSON; nil is stored as null for creations and
+// deletions.
+func recordAudit(ctx context.Context, q *Queries, action, targetType, targetID string, before, after any) (AuditLog, error) {
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return AuditLog{}, err
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return AuditLog{}, err
+	}
+
+	actor := AuditActorFromContext(ctx)
+	return q.CreateAuditLog(ctx, CreateAuditLogParams{
+		Actor:      actor.Name,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     beforeJSON,
+		After:      afterJSON,
+		RequestID:  util.RequestIDFromContext(ctx),
+		Ip:         actor.IP,
+	})
+}
+
+// userSnapshot is the audited state of a user, without the password hash
+type userSnapshot struct {
+	Username string `json:"username"`
+	FullName string `json:"full_name"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+}
+
+func newUserSnapshot(user User) userSnapshot {
+	return userSnapshot{
+		Username: user.Username,
+		FullName: user.FullName,
+		Email:    user.Email,
+		Role:     user.Role,
+	}
+}
+
+// apiKeySnapshot is the audited state of an API key, without the encrypted secret
+type apiKeySnapshot struct {
+	KeyID     string    `json:"key_id"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	IsRevoked bool      `json:"is_revoked"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func newAPIKeySnapshot(apiKey ApiKey) apiKeySnapshot {
+	return apiKeySnapshot{
+		KeyID:     apiKey.KeyID,
+		Name:      apiKey.Name,
+		Scopes:    apiKey.Scopes,
+		IsRevoked: apiKey.IsRevoked,
+		ExpiresAt: apiKey.ExpiresAt,
+	}
+}
+
+// sessionSnapshot is the audited state of a session, without the refresh token
+type sessionSnapshot struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	UserAgent string    `json:"user_agent"`
+	ClientIp  string    `json:"client_ip"`
+	IsBlocked bool      `json:"is_blocked"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func newSessionSnapshot(session Session) sessionSnapshot {
+	return sessionSnapshot{
+		ID:        session.ID,
+		Username:  session.Username,
+		UserAgent: session.UserAgent,
+		ClientIp:  session.ClientIp,
+		IsBlocked: session.IsBlocked,
+		ExpiresAt: session.ExpiresAt,
+	}
+}
+
+// webhookSnapshot is the audited state of a webhook, without the secret
+type webhookSnapshot struct {
+	ID         int64    `json:"id"`
+	Url        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	IsActive   bool     `json:"is_active"`
+}
+
+func newWebhookSnapshot(webhook Webhook) webhookSnapshot {
+	return webhookSnapshot{
+		ID:         webhook.ID,
+		Url:        webhook.Url,
+		EventTypes: webhook.EventTypes,
+		IsActive:   webhook.IsActive,
+	}
+}
+
+// webhookDeliverySnapshot is the audited state of a delivery, without its payload
+type webhookDeliverySnapshot struct {
+	ID            int64     `json:"id"`
+	Status        string    `json:"status"`
+	Attempts      int32     `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error"`
+}
+
+func newWebhookDeliverySnapshot(delivery WebhookDelivery) webhookDeliverySnapshot {
+	return webhookDeliverySnapshot{
+		ID:            delivery.ID,
+		Status:        delivery.Status,
+		Attempts:      delivery.Attempts,
+		NextAttemptAt: delivery.NextAttemptAt,
+		LastError:     delivery.LastError,
+	}
+}
+
+// transferSnapshot is the audited state of a transfer and the balances of
+// the accounts it moves money between. Transfer is nil before it is made.
+type transferSnapshot struct {
+	Transfer    *Transfer `json:"transfer"`
+	FromBalance int64     `json:"from_balance"`
+	ToBalance   int64     `json:"to_balance"`
+}
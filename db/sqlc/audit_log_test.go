@@ -0,0 +1,313 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julkar-naim/simple-bank/money"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// auditContext returns a context carrying a random actor and request ID
+func auditContext() (context.Context, AuditActor, string) {
+	actor := AuditActor{Name: util.RandomOwner(), IP: "192.0.2.10"}
+	requestID := util.RandomString(12)
+	ctx := util.WithRequestID(context.Background(), requestID)
+	return WithAuditActor(ctx, actor), actor, requestID
+}
+
+// listTargetAuditLogs returns the audit log of a target, newest first
+func listTargetAuditLogs(t *testing.T, targetType, targetID string) []AuditLog {
+	auditLogs, err := testQueries.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		TargetType: sql.NullString{String: targetType, Valid: true},
+		TargetID:   sql.NullString{String: targetID, Valid: true},
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	return auditLogs
+}
+
+func TestAccountTxAudit(t *testing.T) {
+	store := NewSqlStore(testDB)
+	ctx, actor, requestID := auditContext()
+	user := createRandomUser(t)
+
+	account, err := store.CreateAccountTx(ctx, CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  100,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+
+	// an account with ledger entries cannot be deleted, so only its owner changes
+	newOwner := createRandomUser(t)
+	updated, err := store.UpdateAccountTx(ctx, UpdateAccountTxParams{
+		ID:      account.ID,
+		Owner:   newOwner.Username,
+		Balance: account.Balance,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, store.DeleteAccountTx(ctx, account.ID))
+
+	auditLogs := listTargetAuditLogs(t, AuditTargetAccount, strconv.FormatInt(account.ID, 10))
+	require.Len(t, auditLogs, 3)
+
+	deleted, update, created := auditLogs[0], auditLogs[1], auditLogs[2]
+	for _, auditLog := range auditLogs {
+		require.Equal(t, actor.Name, auditLog.Actor)
+		require.Equal(t, actor.IP, auditLog.Ip)
+		require.Equal(t, requestID, auditLog.RequestID)
+	}
+
+	require.Equal(t, AuditActionCreateAccount, created.Action)
+	require.JSONEq(t, `null`, string(created.Before))
+	require.Contains(t, string(created.After), `"balance":100`)
+
+	require.Equal(t, AuditActionUpdateAccount, update.Action)
+	require.Contains(t, string(update.Before), `"owner":"`+user.Username+`"`)
+	require.Contains(t, string(update.After), `"owner":"`+newOwner.Username+`"`)
+	require.Equal(t, newOwner.Username, updated.Owner)
+
+	require.Equal(t, AuditActionDeleteAccount, deleted.Action)
+	require.Contains(t, string(deleted.Before), `"owner":"`+newOwner.Username+`"`)
+	require.JSONEq(t, `null`, string(deleted.After))
+}
+
+func TestUpdateAccountTxNotFound(t *testing.T) {
+	store := NewSqlStore(testDB)
+
+	_, err := store.UpdateAccountTx(context.Background(), UpdateAccountTxParams{
+		ID:    -1,
+		Owner: util.RandomOwner(),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestTransferTxAudit(t *testing.T) {
+	store := NewSqlStore(testDB)
+	ctx, actor, _ := auditContext()
+	account1, account2 := createTransferAccounts(t)
+
+	result, err := store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.New(10, account1.Currency),
+	})
+	require.NoError(t, err)
+
+	auditLogs := listTargetAuditLogs(t, AuditTargetTransfer, strconv.FormatInt(result.Transfer.ID, 10))
+	require.Len(t, auditLogs, 1)
+	require.Equal(t, actor.Name, auditLogs[0].Actor)
+	require.Equal(t, AuditActionCreateTransfer, auditLogs[0].Action)
+	require.JSONEq(t, `{"transfer": null, "from_balance": `+strconv.FormatInt(account1.Balance, 10)+
+		`, "to_balance": `+strconv.FormatInt(account2.Balance, 10)+`}`, string(auditLogs[0].Before))
+	require.Contains(t, string(auditLogs[0].After), `"from_balance":`+strconv.FormatInt(account1.Balance-10, 10))
+	require.Contains(t, string(auditLogs[0].After), `"to_balance":`+strconv.FormatInt(account2.Balance+10, 10))
+}
+
+func TestAuditLeavesOutSecrets(t *testing.T) {
+	store := NewSqlStore(testDB)
+	ctx, _, _ := auditContext()
+	user := createRandomUser(t)
+
+	apiKey, err := store.CreateAPIKeyTx(ctx, CreateAPIKeyParams{
+		KeyID:            util.RandomString(16),
+		Name:             util.RandomString(8),
+		SecretCiphertext: util.RandomString(32),
+		Scopes:           []string{string(util.PermReconcile)},
+		CreatedBy:        user.Username,
+		ExpiresAt:        time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	_, err = store.RevokeAPIKeyTx(ctx, apiKey.KeyID)
+	require.NoError(t, err)
+
+	webhook, err := store.CreateWebhookTx(ctx, CreateWebhookParams{
+		Url:              "https://" + util.RandomString(8) + ".example/hooks",
+		SecretCiphertext: util.RandomString(32),
+		EventTypes:       []string{EventTransferCompleted},
+		CreatedBy:        user.Username,
+	})
+	require.NoError(t, err)
+
+	apiKeyLogs := listTargetAuditLogs(t, AuditTargetAPIKey, apiKey.KeyID)
+	require.Len(t, apiKeyLogs, 2)
+	require.Equal(t, AuditActionRevokeAPIKey, apiKeyLogs[0].Action)
+	require.Contains(t, string(apiKeyLogs[0].After), `"is_revoked":true`)
+	for _, auditLog := range apiKeyLogs {
+		require.NotContains(t, string(auditLog.Before), apiKey.SecretCiphertext)
+		require.NotContains(t, string(auditLog.After), apiKey.SecretCiphertext)
+	}
+
+	webhookLogs := listTargetAuditLogs(t, AuditTargetWebhook, strconv.FormatInt(webhook.ID, 10))
+	require.Len(t, webhookLogs, 1)
+	require.NotContains(t, string(webhookLogs[0].After), webhook.SecretCiphertext)
+}
+
+func TestSessionTxAudit(t *testing.T) {
+	store := NewSqlStore(testDB)
+	ctx, actor, requestID := auditContext()
+	user := createRandomUser(t)
+
+	createSession := func() Session {
+		session, err := store.CreateSessionTx(ctx, CreateSessionParams{
+			ID:           uuid.New(),
+			Username:     user.Username,
+			RefreshToken: util.RandomString(32),
+			UserAgent:    "go-test",
+			ClientIp:     actor.IP,
+			ExpiresAt:    time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+		return session
+	}
+
+	session1 := createSession()
+	session2 := createSession()
+	session3 := createSession()
+
+	_, err := store.BlockSessionTx(ctx, session1.ID)
+	require.NoError(t, err)
+	blocked, err := store.BlockUserSessionsTx(ctx, user.Username)
+	require.NoError(t, err)
+	require.Len(t, blocked, 2)
+
+	auditLogs := listTargetAuditLogs(t, AuditTargetSession, session1.ID.String())
+	require.Len(t, auditLogs, 2)
+	require.Equal(t, AuditActionRevokeSession, auditLogs[0].Action)
+	require.Contains(t, string(auditLogs[0].Before), `"is_blocked":false`)
+	require.Contains(t, string(auditLogs[0].After), `"is_blocked":true`)
+	require.Equal(t, AuditActionCreateSession, auditLogs[1].Action)
+	require.Equal(t, "null", string(auditLogs[1].Before))
+	for _, auditLog := range auditLogs {
+		require.Equal(t, actor.Name, auditLog.Actor)
+		require.Equal(t, requestID, auditLog.RequestID)
+		require.Equal(t, actor.IP, auditLog.Ip)
+		require.NotContains(t, string(auditLog.Before), session1.RefreshToken)
+		require.NotContains(t, string(auditLog.After), session1.RefreshToken)
+	}
+
+	// logging out of every session revokes each one
+	for _, session := range []Session{session2, session3} {
+		auditLogs := listTargetAuditLogs(t, AuditTargetSession, session.ID.String())
+		require.Len(t, auditLogs, 2)
+		require.Equal(t, AuditActionRevokeSession, auditLogs[0].Action)
+	}
+}
+
+func TestCreateUserTxAudit(t *testing.T) {
+	store := NewSqlStore(testDB)
+	username := util.RandomOwner()
+	ctx := WithAuditActor(context.Background(), AuditActor{Name: username})
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+	_, err = store.CreateUserTx(ctx, CreateUserParams{
+		Username:       username,
+		HashedPassword: hashedPassword,
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+
+	auditLogs := listTargetAuditLogs(t, AuditTargetUser, username)
+	require.Len(t, auditLogs, 1)
+	require.Equal(t, username, auditLogs[0].Actor)
+	require.Equal(t, AuditActionCreateUser, auditLogs[0].Action)
+	require.NotContains(t, string(auditLogs[0].After), hashedPassword)
+}
+
+func TestAuditSystemActor(t *testing.T) {
+	store := NewSqlStore(testDB)
+	user := createRandomUser(t)
+
+	account, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+
+	auditLogs := listTargetAuditLogs(t, AuditTargetAccount, strconv.FormatInt(account.ID, 10))
+	require.Len(t, auditLogs, 1)
+	require.Equal(t, AuditSystemActor, auditLogs[0].Actor)
+	require.Empty(t, auditLogs[0].RequestID)
+	require.Empty(t, auditLogs[0].Ip)
+}
+
+func TestListAuditLogsFilters(t *testing.T) {
+	store := NewSqlStore(testDB)
+	ctx, actor, _ := auditContext()
+	account := createRandomAccount(t)
+
+	_, err := store.SetAccountFrozenTx(ctx, SetAccountFrozenParams{ID: account.ID, IsFrozen: true})
+	require.NoError(t, err)
+	_, err = store.SetAccountFrozenTx(ctx, SetAccountFrozenParams{ID: account.ID, IsFrozen: false})
+	require.NoError(t, err)
+
+	byActor, err := testQueries.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		Actor: sql.NullString{String: actor.Name, Valid: true},
+		Limit: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, byActor, 2)
+	require.Equal(t, AuditActionUnfreezeAccount, byActor[0].Action)
+	require.Equal(t, AuditActionFreezeAccount, byActor[1].Action)
+
+	byAction, err := testQueries.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		Actor:  sql.NullString{String: actor.Name, Valid: true},
+		Action: sql.NullString{String: AuditActionFreezeAccount, Valid: true},
+		Limit:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, byAction, 1)
+
+	future, err := testQueries.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		Actor:       sql.NullString{String: actor.Name, Valid: true},
+		CreatedFrom: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+		Limit:       10,
+	})
+	require.NoError(t, err)
+	require.Empty(t, future)
+
+	past, err := testQueries.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		Actor:     sql.NullString{String: actor.Name, Valid: true},
+		CreatedTo: sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true},
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Empty(t, past)
+}
+
+func TestAuditLogIsAppendOnly(t *testing.T) {
+	store := NewSqlStore(testDB)
+	ctx, _, _ := auditContext()
+	account := createRandomAccount(t)
+
+	_, err := store.SetAccountFrozenTx(ctx, SetAccountFrozenParams{ID: account.ID, IsFrozen: true})
+	require.NoError(t, err)
+	auditLog := listTargetAuditLogs(t, AuditTargetAccount, strconv.FormatInt(account.ID, 10))[0]
+
+	statements := []string{
+		"UPDATE audit_log SET actor = 'mallory' WHERE id = $1",
+		"DELETE FROM audit_log WHERE id = $1",
+	}
+	for _, statement := range statements {
+		_, err = testDB.ExecContext(context.Background(), statement, auditLog.ID)
+		var pqErr *pq.Error
+		require.ErrorAs(t, err, &pqErr)
+		require.Equal(t, "insufficient_privilege", pqErr.Code.Name())
+	}
+
+	_, err = testDB.ExecContext(context.Background(), "TRUNCATE audit_log")
+	require.Error(t, err)
+
+	unchanged := listTargetAuditLogs(t, AuditTargetAccount, strconv.FormatInt(account.ID, 10))[0]
+	require.Equal(t, auditLog.Actor, unchanged.Actor)
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createRandomCurrency creates an X-prefixed code, which ISO 4217 never
+// assigns to a national currency
+func createRandomCurrency(t *testing.T) Currency {
+	arg := CreateCurrencyParams{
+		Code:     "X" + strings.ToUpper(util.RandomString(2)),
+		Exponent: int32(util.RandomInt(4)),
+		Symbol:   util.RandomString(2),
+	}
+
+	currency, err := testQueries.CreateCurrency(context.Background(), arg)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+		return createRandomCurrency(t)
+	}
+	require.NoError(t, err)
+	require.Equal(t, arg.Code, currency.Code)
+	require.Equal(t, arg.Exponent, currency.Exponent)
+	require.Equal(t, arg.Symbol, currency.Symbol)
+	require.True(t, currency.Enabled)
+	require.NotZero(t, currency.CreatedAt)
+
+	return currency
+}
+
+func TestCreateCurrency(t *testing.T) {
+	createRandomCurrency(t)
+}
+
+func TestCreateCurrencyInvalid(t *testing.T) {
+	testCases := []CreateCurrencyParams{
+		{Code: "usd", Exponent: 2, Symbol: "$"},
+		{Code: "XYZ", Exponent: 5, Symbol: "$"},
+		{Code: "XYZ", Exponent: -1, Symbol: "$"},
+	}
+
+	for _, arg := range testCases {
+		_, err := testQueries.CreateCurrency(context.Background(), arg)
+		require.Error(t, err)
+
+		var pqErr *pq.Error
+		require.ErrorAs(t, err, &pqErr)
+		require.Equal(t, "check_violation", pqErr.Code.Name())
+	}
+}
+
+func TestGetCurrency(t *testing.T) {
+	currency1 := createRandomCurrency(t)
+
+	currency2, err := testQueries.GetCurrency(context.Background(), currency1.Code)
+	require.NoError(t, err)
+	require.Equal(t, currency1.Code, currency2.Code)
+	require.Equal(t, currency1.Exponent, currency2.Exponent)
+	require.Equal(t, currency1.Symbol, currency2.Symbol)
+	require.Equal(t, currency1.Enabled, currency2.Enabled)
+	require.WithinDuration(t, currency1.CreatedAt, currency2.CreatedAt, time.Second)
+
+	_, err = testQueries.GetCurrency(context.Background(), "ZZZ")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestListCurrencies(t *testing.T) {
+	created := createRandomCurrency(t)
+
+	currencies, err := testQueries.ListCurrencies(context.Background())
+	require.NoError(t, err)
+
+	codes := make([]string, len(currencies))
+	for i, currency := range currencies {
+		codes[i] = currency.Code
+	}
+	require.IsNonDecreasing(t, codes)
+	require.Contains(t, codes, util.USD)
+	require.Contains(t, codes, util.EUR)
+	require.Contains(t, codes, util.CAD)
+	require.Contains(t, codes, created.Code)
+}
+
+func TestSetCurrencyEnabled(t *testing.T) {
+	currency := createRandomCurrency(t)
+
+	disabled, err := testQueries.SetCurrencyEnabled(context.Background(), SetCurrencyEnabledParams{
+		Code:    currency.Code,
+		Enabled: false,
+	})
+	require.NoError(t, err)
+	require.False(t, disabled.Enabled)
+
+	enabled, err := testQueries.SetCurrencyEnabled(context.Background(), SetCurrencyEnabledParams{
+		Code:    currency.Code,
+		Enabled: true,
+	})
+	require.NoError(t, err)
+	require.True(t, enabled.Enabled)
+}
+
+func TestCreateAccountUnknownCurrency(t *testing.T) {
+	user := createRandomUser(t)
+
+	_, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  util.RandomMoney(),
+		Currency: "ZZZ",
+	})
+	require.Error(t, err)
+
+	var pqErr *pq.Error
+	require.ErrorAs(t, err, &pqErr)
+	require.Equal(t, "foreign_key_violation", pqErr.Code.Name())
+}
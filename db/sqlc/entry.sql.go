@@ -7,46 +7,64 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (
     account_id,
-    amount
+    amount,
+    created_at,
+    prev_hash,
+    hash,
+    transfer_id,
+    entry_type,
+    description
 ) VALUES (
-    $1, $2
-) RETURNING id, account_id, amount, created_at
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING id, account_id, amount, created_at, prev_hash, hash, transfer_id, entry_type, description
 `
 
 type CreateEntryParams struct {
-	AccountID int64 `json:"account_id"`
-	Amount    int64 `json:"amount"`
+	AccountID   int64         `json:"account_id"`
+	Amount      int64         `json:"amount"`
+	CreatedAt   time.Time     `json:"created_at"`
+	PrevHash    string        `json:"prev_hash"`
+	Hash        string        `json:"hash"`
+	TransferID  sql.NullInt64 `json:"transfer_id"`
+	EntryType   EntryType     `json:"entry_type"`
+	Description string        `json:"description"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createEntry,
+		arg.AccountID,
+		arg.Amount,
+		arg.CreatedAt,
+		arg.PrevHash,
+		arg.Hash,
+		arg.TransferID,
+		arg.EntryType,
+		arg.Description,
+	)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.PrevHash,
+		&i.Hash,
+		&i.TransferID,
+		&i.EntryType,
+		&i.Description,
 	)
 	return i, err
 }
 
-const deleteEntry = `-- name: DeleteEntry :exec
-DELETE FROM entries
-WHERE id = $1
-`
-
-func (q *Queries) DeleteEntry(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteEntry, id)
-	return err
-}
-
 const getAccountEntries = `-- name: GetAccountEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, prev_hash, hash, transfer_id, entry_type, description FROM entries
 WHERE account_id = $1
 ORDER BY created_at DESC
 `
@@ -65,6 +83,11 @@ func (q *Queries) GetAccountEntries(ctx context.Context, accountID int64) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.PrevHash,
+			&i.Hash,
+			&i.TransferID,
+			&i.EntryType,
+			&i.Description,
 		); err != nil {
 			return nil, err
 		}
@@ -80,7 +103,7 @@ func (q *Queries) GetAccountEntries(ctx context.Context, accountID int64) ([]Ent
 }
 
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, prev_hash, hash, transfer_id, entry_type, description FROM entries
 WHERE id = $1 LIMIT 1
 `
 
@@ -92,12 +115,45 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.PrevHash,
+		&i.Hash,
+		&i.TransferID,
+		&i.EntryType,
+		&i.Description,
 	)
 	return i, err
 }
 
+const getLastEntryHash = `-- name: GetLastEntryHash :one
+SELECT COALESCE((
+    SELECT hash FROM entries
+    WHERE account_id = $1
+    ORDER BY id DESC
+    LIMIT 1
+), '')::varchar
+`
+
+func (q *Queries) GetLastEntryHash(ctx context.Context, accountID int64) (string, error) {
+	row := q.db.QueryRowContext(ctx, getLastEntryHash, accountID)
+	var column_1 string
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const getLastEntryID = `-- name: GetLastEntryID :one
+SELECT COALESCE(MAX(id), 0)::bigint FROM entries
+WHERE account_id = $1
+`
+
+func (q *Queries) GetLastEntryID(ctx context.Context, accountID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getLastEntryID, accountID)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, prev_hash, hash, transfer_id, entry_type, description FROM entries
 WHERE account_id = $1
 ORDER BY id
 LIMIT $2
@@ -124,6 +180,96 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.PrevHash,
+			&i.Hash,
+			&i.TransferID,
+			&i.EntryType,
+			&i.Description,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntriesAfter = `-- name: ListEntriesAfter :many
+SELECT id, account_id, amount, created_at, prev_hash, hash, transfer_id, entry_type, description FROM entries
+WHERE account_id = $1 AND id > $2
+ORDER BY id
+LIMIT $3
+`
+
+type ListEntriesAfterParams struct {
+	AccountID int64 `json:"account_id"`
+	ID        int64 `json:"id"`
+	Limit     int32 `json:"limit"`
+}
+
+func (q *Queries) ListEntriesAfter(ctx context.Context, arg ListEntriesAfterParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listEntriesAfter, arg.AccountID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.PrevHash,
+			&i.Hash,
+			&i.TransferID,
+			&i.EntryType,
+			&i.Description,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransferEntries = `-- name: ListTransferEntries :many
+SELECT id, account_id, amount, created_at, prev_hash, hash, transfer_id, entry_type, description FROM entries
+WHERE transfer_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListTransferEntries(ctx context.Context, transferID sql.NullInt64) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listTransferEntries, transferID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.PrevHash,
+			&i.Hash,
+			&i.TransferID,
+			&i.EntryType,
+			&i.Description,
 		); err != nil {
 			return nil, err
 		}
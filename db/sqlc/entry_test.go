@@ -2,7 +2,6 @@ package db
 
 import (
 	"context"
-	"database/sql"
 	"testing"
 	"time"
 
@@ -11,20 +10,27 @@ import (
 )
 
 func createRandomEntry(t *testing.T, account Account) Entry {
-	arg := CreateEntryParams{
-		AccountID: account.ID,
-		Amount:    util.RandomMoney(),
-	}
-
-	entry, err := testQueries.CreateEntry(context.Background(), arg)
+	// the ledger rejects zero amounts
+	amount := util.RandomMoney() + 1
+
+	entry, err := createChainedEntry(context.Background(), testQueries, chainedEntryParams{
+		AccountID:   account.ID,
+		Amount:      amount,
+		EntryType:   EntryTypeDeposit,
+		Description: "deposit",
+	})
 	require.NoError(t, err)
 	require.NotEmpty(t, entry)
 
-	require.Equal(t, arg.AccountID, entry.AccountID)
-	require.Equal(t, arg.Amount, entry.Amount)
+	require.Equal(t, account.ID, entry.AccountID)
+	require.Equal(t, amount, entry.Amount)
+	require.Equal(t, EntryTypeDeposit, entry.EntryType)
+	require.Equal(t, "deposit", entry.Description)
+	require.False(t, entry.TransferID.Valid)
 
 	require.NotZero(t, entry.ID)
 	require.NotZero(t, entry.CreatedAt)
+	require.Equal(t, EntryHash(entry), entry.Hash)
 
 	return entry
 }
@@ -71,17 +77,24 @@ func TestListEntries(t *testing.T) {
 	}
 }
 
-func TestDeleteEntry(t *testing.T) {
+func TestEntryChain(t *testing.T) {
 	account := createRandomAccount(t)
+
 	entry1 := createRandomEntry(t, account)
+	entry2 := createRandomEntry(t, account)
+	entry3 := createRandomEntry(t, account)
+
+	require.Empty(t, entry1.PrevHash)
+	require.Equal(t, entry1.Hash, entry2.PrevHash)
+	require.Equal(t, entry2.Hash, entry3.PrevHash)
 
-	err := testQueries.DeleteEntry(context.Background(), entry1.ID)
+	stored, err := testQueries.GetEntry(context.Background(), entry2.ID)
 	require.NoError(t, err)
+	require.Equal(t, entry2.Hash, EntryHash(stored))
 
-	entry2, err := testQueries.GetEntry(context.Background(), entry1.ID)
-	require.Error(t, err)
-	require.EqualError(t, err, sql.ErrNoRows.Error())
-	require.Empty(t, entry2)
+	lastHash, err := testQueries.GetLastEntryHash(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, entry3.Hash, lastHash)
 }
 
 func TestGetAccountEntries(t *testing.T) {
@@ -106,3 +119,30 @@ func TestGetAccountEntries(t *testing.T) {
 			entries[i-1].CreatedAt.Equal(entries[i].CreatedAt))
 	}
 }
+
+func TestListEntriesAfter(t *testing.T) {
+	account := createRandomAccount(t)
+
+	lastID, err := testQueries.GetLastEntryID(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Zero(t, lastID)
+
+	var entries []Entry
+	for i := 0; i < 5; i++ {
+		entries = append(entries, createRandomEntry(t, account))
+	}
+
+	lastID, err = testQueries.GetLastEntryID(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, entries[4].ID, lastID)
+
+	after, err := testQueries.ListEntriesAfter(context.Background(), ListEntriesAfterParams{
+		AccountID: account.ID,
+		ID:        entries[1].ID,
+		Limit:     2,
+	})
+	require.NoError(t, err)
+	require.Len(t, after, 2)
+	require.Equal(t, entries[2].ID, after[0].ID)
+	require.Equal(t, entries[3].ID, after[1].ID)
+}
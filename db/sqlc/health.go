@@ -0,0 +1,28 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SchemaVersion is the migration version this binary's queries are written
+// against. Bump it with every new migration in db/migration.
+const SchemaVersion = 18
+
+// Ping checks that a connection to the database can be established
+func (store *SqlStore) Ping(ctx context.Context) error {
+	return store.db.PingContext(ctx)
+}
+
+// MigrationVersion returns the version recorded by golang-migrate and
+// whether the last migration left the schema dirty
+func (store *SqlStore) MigrationVersion(ctx context.Context) (version int64, dirty bool, err error) {
+	row := store.db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1")
+	err = row.Scan(&version, &dirty)
+	return
+}
+
+// PoolStats returns the connection pool statistics
+func (store *SqlStore) PoolStats() sql.DBStats {
+	return store.db.Stats()
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSchemaVersionMatchesMigrations(t *testing.T) {
+	files, err := os.ReadDir("../migration")
+	require.NoError(t, err)
+
+	var latest int64
+	for _, file := range files {
+		prefix, _, _ := strings.Cut(file.Name(), "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		require.NoError(t, err)
+		latest = max(latest, version)
+	}
+	require.Equal(t, int64(SchemaVersion), latest)
+}
+
+func TestMigrationVersion(t *testing.T) {
+	store := NewSqlStore(testDB)
+
+	require.NoError(t, store.Ping(context.Background()))
+
+	version, dirty, err := store.MigrationVersion(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(SchemaVersion), version)
+	require.False(t, dirty)
+
+	stats := store.PoolStats()
+	require.Positive(t, stats.OpenConnections)
+}
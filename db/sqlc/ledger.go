@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Entries and transfers are hash-chained per account: each row stores the
+// hash of the previous row of its chain and a hash over its own content and
+// that previous hash, so editing or deleting a row breaks every later link.
+// Entries chain per account, transfers per source account. A chain is only
+// appended to while its account row is locked, so ids follow commit order.
+//
+// Entry hashes are versioned so that their content can grow: version 2
+// added transfer_id, entry_type and description, and the 000015 migration
+// rehashed the older rows with it, failing on any row that did not match its
+// version 1 hash. Only description may contain the
+// separator, and it is followed by prev_hash, which never does, so the hashed
+// content cannot be read two ways.
+//
+// The ledger is append-only: the 000012 migration rejects updates and
+// deletes of entries and transfers, and the queries that write them or move
+// a balance are left out of StoreQuerier, so the Store can only change the
+// ledger through its transactions.
+
+// entryHashVersion is the version of the content hashed by EntryHash
+const entryHashVersion = 2
+
+// EntryHash returns the hash an entry must store given its PrevHash. The
+// 000015 migration computes the same hash in SQL for older rows.
+func EntryHash(entry Entry) string {
+	transferID := ""
+	if entry.TransferID.Valid {
+		transferID = strconv.FormatInt(entry.TransferID.Int64, 10)
+	}
+	return ledgerHash(fmt.Sprintf("entry|v%d|%d|%d|%d|%s|%s|%s|%s",
+		entryHashVersion, entry.AccountID, entry.Amount, entry.CreatedAt.UnixMicro(),
+		transferID, entry.EntryType, entry.Description, entry.PrevHash))
+}
+
+// TransferHash returns the hash a transfer must store given its PrevHash
+func TransferHash(transfer Transfer) string {
+	return ledgerHash(fmt.Sprintf("transfer|%d|%d|%d|%d|%s",
+		transfer.FromAccountID, transfer.ToAccountID, transfer.Amount, transfer.CreatedAt.UnixMicro(), transfer.PrevHash))
+}
+
+func ledgerHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ledgerTime returns the current time at the precision Postgres stores, so
+// that the hash computed before the insert matches the stored row
+func ledgerTime() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+type chainedEntryParams struct {
+	AccountID   int64
+	Amount      int64
+	TransferID  sql.NullInt64
+	EntryType   EntryType
+	Description string
+}
+
+// createChainedEntry appends an entry to its account's chain. The caller
+// must hold the account's row lock.
+func createChainedEntry(ctx context.Context, q *Queries, arg chainedEntryParams) (Entry, error) {
+	prevHash, err := q.GetLastEntryHash(ctx, arg.AccountID)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	entry := Entry{
+		AccountID:   arg.AccountID,
+		Amount:      arg.Amount,
+		CreatedAt:   ledgerTime(),
+		PrevHash:    prevHash,
+		TransferID:  arg.TransferID,
+		EntryType:   arg.EntryType,
+		Description: arg.Description,
+	}
+	return q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:   entry.AccountID,
+		Amount:      entry.Amount,
+		CreatedAt:   entry.CreatedAt,
+		PrevHash:    entry.PrevHash,
+		Hash:        EntryHash(entry),
+		TransferID:  entry.TransferID,
+		EntryType:   entry.EntryType,
+		Description: entry.Description,
+	})
+}
+
+// createChainedTransfer appends a transfer to its source account's chain.
+// The caller must hold the source account's row lock.
+func createChainedTransfer(ctx context.Context, q *Queries, fromAccountID, toAccountID, amount int64) (Transfer, error) {
+	prevHash, err := q.GetLastTransferHash(ctx, fromAccountID)
+	if err != nil {
+		return Transfer{}, err
+	}
+
+	transfer := Transfer{
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		Amount:        amount,
+		CreatedAt:     ledgerTime(),
+		PrevHash:      prevHash,
+	}
+	return q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: transfer.FromAccountID,
+		ToAccountID:   transfer.ToAccountID,
+		Amount:        transfer.Amount,
+		CreatedAt:     transfer.CreatedAt,
+		PrevHash:      transfer.PrevHash,
+		Hash:          TransferHash(transfer),
+	})
+}
+
+// createAdjustmentEntry locks an account and appends an adjustment entry
+// moving its balance by amount, the only way to correct a balance
+func createAdjustmentEntry(ctx context.Context, q *Queries, accountID, amount int64) (Account, Entry, error) {
+	account, err := q.AddAccountBalance(ctx, AddAccountBalanceParams{
+		ID:     accountID,
+		Amount: amount,
+	})
+	if err != nil {
+		return Account{}, Entry{}, err
+	}
+	entry, err := createChainedEntry(ctx, q, chainedEntryParams{
+		AccountID:   accountID,
+		Amount:      amount,
+		EntryType:   EntryTypeAdjustment,
+		Description: "balance adjustment",
+	})
+	return account, entry, err
+}
@@ -0,0 +1,327 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/julkar-naim/simple-bank/money"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferTxChainsLedger(t *testing.T) {
+	store := NewSqlStore(testDB)
+	account1, account2 := createTransferAccounts(t)
+
+	transfer := func(from, to Account) TransferTxResult {
+		result, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: from.ID,
+			ToAccountID:   to.ID,
+			Amount:        money.New(10, from.Currency),
+		})
+		require.NoError(t, err)
+		return result
+	}
+
+	result1 := transfer(account1, account2)
+	result2 := transfer(account2, account1)
+	result3 := transfer(account1, account2)
+
+	require.Empty(t, result1.FromEntry.PrevHash)
+	require.Empty(t, result1.ToEntry.PrevHash)
+	require.Equal(t, result1.ToEntry.Hash, result2.FromEntry.PrevHash)
+	require.Equal(t, result1.FromEntry.Hash, result2.ToEntry.PrevHash)
+	require.Equal(t, result2.ToEntry.Hash, result3.FromEntry.PrevHash)
+
+	// transfers chain per source account
+	require.Empty(t, result1.Transfer.PrevHash)
+	require.Empty(t, result2.Transfer.PrevHash)
+	require.Equal(t, result1.Transfer.Hash, result3.Transfer.PrevHash)
+
+	stored, err := testQueries.GetTransfer(context.Background(), result3.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, result3.Transfer, stored)
+	require.Equal(t, stored.Hash, TransferHash(stored))
+
+	// both entries of a transfer are linked to it
+	linked, err := testQueries.ListTransferEntries(context.Background(), sql.NullInt64{Int64: result2.Transfer.ID, Valid: true})
+	require.NoError(t, err)
+	require.Equal(t, []Entry{result2.FromEntry, result2.ToEntry}, linked)
+	for _, entry := range linked {
+		require.Equal(t, EntryTypeTransfer, entry.EntryType)
+	}
+	require.Equal(t, fmt.Sprintf("transfer to account %d", account1.ID), result2.FromEntry.Description)
+	require.Equal(t, fmt.Sprintf("transfer from account %d", account2.ID), result2.ToEntry.Description)
+
+	for _, entry := range []Entry{result1.FromEntry, result2.ToEntry, result3.ToEntry} {
+		stored, err := testQueries.GetEntry(context.Background(), entry.ID)
+		require.NoError(t, err)
+		require.Equal(t, entry.Hash, EntryHash(stored))
+	}
+}
+
+// TestLedgerHashMatchesMigration checks that the SQL used by the 000011 and
+// 000015 migrations to chain older rows computes the same hashes as the store
+func TestLedgerHashMatchesMigration(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	entry := createRandomEntry(t, account1)
+	transfer := createRandomTransfer(t, account1, account2)
+	linkedEntry, err := createChainedEntry(context.Background(), testQueries, chainedEntryParams{
+		AccountID:   account2.ID,
+		Amount:      -transfer.Amount,
+		TransferID:  sql.NullInt64{Int64: transfer.ID, Valid: true},
+		EntryType:   EntryTypeReversal,
+		Description: "reversal | duplicate transfer",
+	})
+	require.NoError(t, err)
+
+	const micros = `(extract(epoch FROM date_trunc('second', created_at))::bigint * 1000000
+		+ extract(microseconds FROM created_at)::bigint % 1000000)`
+
+	for _, entry := range []Entry{entry, linkedEntry} {
+		var entryHash string
+		err := testDB.QueryRowContext(context.Background(), `
+			SELECT encode(sha256(convert_to(format('entry|v2|%s|%s|%s|%s|%s|%s|%s',
+				account_id, amount, `+micros+`, transfer_id, entry_type, description, prev_hash), 'UTF8')), 'hex')
+			FROM entries WHERE id = $1`, entry.ID).Scan(&entryHash)
+		require.NoError(t, err)
+		require.Equal(t, entry.Hash, entryHash)
+	}
+
+	var transferHash string
+	err = testDB.QueryRowContext(context.Background(), `
+		SELECT encode(sha256(convert_to(format('transfer|%s|%s|%s|%s|%s',
+			from_account_id, to_account_id, amount, `+micros+`, prev_hash), 'UTF8')), 'hex')
+		FROM transfers WHERE id = $1`, transfer.ID).Scan(&transferHash)
+	require.NoError(t, err)
+	require.Equal(t, transfer.Hash, transferHash)
+}
+
+func TestLedgerIsAppendOnly(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	entry := createRandomEntry(t, account1)
+	transfer := createRandomTransfer(t, account1, account2)
+
+	testCases := []struct {
+		name      string
+		statement string
+		id        int64
+	}{
+		{"UpdateEntry", "UPDATE entries SET amount = amount + 1 WHERE id = $1", entry.ID},
+		{"DeleteEntry", "DELETE FROM entries WHERE id = $1", entry.ID},
+		{"UpdateTransfer", "UPDATE transfers SET to_account_id = from_account_id WHERE id = $1", transfer.ID},
+		{"DeleteTransfer", "DELETE FROM transfers WHERE id = $1", transfer.ID},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := testDB.ExecContext(context.Background(), tc.statement, tc.id)
+			var pqErr *pq.Error
+			require.ErrorAs(t, err, &pqErr)
+			require.Equal(t, "insufficient_privilege", pqErr.Code.Name())
+		})
+	}
+
+	// entries reference transfers, so a plain TRUNCATE of transfers is
+	// refused before its trigger runs, and with CASCADE by the triggers
+	truncates := []struct {
+		statement string
+		code      string
+	}{
+		{"TRUNCATE entries", "insufficient_privilege"},
+		{"TRUNCATE transfers", "feature_not_supported"},
+		{"TRUNCATE transfers CASCADE", "insufficient_privilege"},
+	}
+	for _, truncate := range truncates {
+		_, err := testDB.ExecContext(context.Background(), truncate.statement)
+		var pqErr *pq.Error
+		require.ErrorAs(t, err, &pqErr, truncate.statement)
+		require.Equal(t, truncate.code, pqErr.Code.Name(), truncate.statement)
+	}
+
+	storedEntry, err := testQueries.GetEntry(context.Background(), entry.ID)
+	require.NoError(t, err)
+	require.Equal(t, entry, storedEntry)
+
+	storedTransfer, err := testQueries.GetTransfer(context.Background(), transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, transfer, storedTransfer)
+}
+
+func TestLedgerConstraints(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	validEntry := func() CreateEntryParams {
+		entry := Entry{AccountID: account1.ID, Amount: 10, CreatedAt: ledgerTime(), EntryType: EntryTypeAdjustment}
+		return CreateEntryParams{
+			AccountID: entry.AccountID,
+			Amount:    entry.Amount,
+			CreatedAt: entry.CreatedAt,
+			Hash:      EntryHash(entry),
+			EntryType: entry.EntryType,
+		}
+	}
+	validTransfer := func() CreateTransferParams {
+		transfer := Transfer{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10, CreatedAt: ledgerTime()}
+		return CreateTransferParams{
+			FromAccountID: transfer.FromAccountID,
+			ToAccountID:   transfer.ToAccountID,
+			Amount:        transfer.Amount,
+			CreatedAt:     transfer.CreatedAt,
+			Hash:          TransferHash(transfer),
+		}
+	}
+
+	testCases := []struct {
+		name       string
+		insert     func() error
+		constraint string
+	}{
+		{
+			"ZeroEntry",
+			func() error {
+				arg := validEntry()
+				arg.Amount = 0
+				_, err := testQueries.CreateEntry(context.Background(), arg)
+				return err
+			},
+			"entries_amount_check",
+		},
+		{
+			"EntryHash",
+			func() error {
+				arg := validEntry()
+				arg.Hash = "forged"
+				_, err := testQueries.CreateEntry(context.Background(), arg)
+				return err
+			},
+			"entries_hash_check",
+		},
+		{
+			"EntryPrevHash",
+			func() error {
+				arg := validEntry()
+				arg.PrevHash = "forged"
+				_, err := testQueries.CreateEntry(context.Background(), arg)
+				return err
+			},
+			"entries_prev_hash_check",
+		},
+		{
+			"TransferEntryWithoutTransfer",
+			func() error {
+				arg := validEntry()
+				arg.EntryType = EntryTypeTransfer
+				_, err := testQueries.CreateEntry(context.Background(), arg)
+				return err
+			},
+			"entries_transfer_id_check",
+		},
+		{
+			"ZeroTransfer",
+			func() error {
+				arg := validTransfer()
+				arg.Amount = 0
+				_, err := testQueries.CreateTransfer(context.Background(), arg)
+				return err
+			},
+			"transfers_amount_check",
+		},
+		{
+			"NegativeTransfer",
+			func() error {
+				arg := validTransfer()
+				arg.Amount = -10
+				_, err := testQueries.CreateTransfer(context.Background(), arg)
+				return err
+			},
+			"transfers_amount_check",
+		},
+		{
+			"TransferToSameAccount",
+			func() error {
+				arg := validTransfer()
+				arg.ToAccountID = arg.FromAccountID
+				_, err := testQueries.CreateTransfer(context.Background(), arg)
+				return err
+			},
+			"transfers_accounts_check",
+		},
+		{
+			"TransferHash",
+			func() error {
+				arg := validTransfer()
+				arg.Hash = strings.ToUpper(arg.Hash)
+				_, err := testQueries.CreateTransfer(context.Background(), arg)
+				return err
+			},
+			"transfers_hash_check",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.insert()
+			var pqErr *pq.Error
+			require.ErrorAs(t, err, &pqErr)
+			require.Equal(t, "check_violation", pqErr.Code.Name())
+			require.Equal(t, tc.constraint, pqErr.Constraint)
+		})
+	}
+}
+
+func TestUpdateAccountTxAppendsAdjustment(t *testing.T) {
+	store := NewSqlStore(testDB)
+	account := createRandomAccount(t)
+	previous := createRandomEntry(t, account)
+
+	updated, err := store.UpdateAccountTx(context.Background(), UpdateAccountTxParams{
+		ID:      account.ID,
+		Owner:   account.Owner,
+		Balance: account.Balance + 250,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+250, updated.Balance)
+
+	// an unchanged balance appends nothing
+	_, err = store.UpdateAccountTx(context.Background(), UpdateAccountTxParams{
+		ID:      account.ID,
+		Owner:   account.Owner,
+		Balance: updated.Balance,
+	})
+	require.NoError(t, err)
+
+	entries, err := testQueries.ListEntriesAfter(context.Background(), ListEntriesAfterParams{
+		AccountID: account.ID,
+		ID:        previous.ID,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, int64(250), entries[0].Amount)
+	require.Equal(t, EntryTypeAdjustment, entries[0].EntryType)
+	require.False(t, entries[0].TransferID.Valid)
+	require.Equal(t, previous.Hash, entries[0].PrevHash)
+	require.Equal(t, EntryHash(entries[0]), entries[0].Hash)
+}
+
+func TestStoreHidesLedgerWrites(t *testing.T) {
+	querier := reflect.TypeOf((*Querier)(nil)).Elem()
+	store := reflect.TypeOf((*Store)(nil)).Elem()
+
+	var hidden []string
+	for i := 0; i < querier.NumMethod(); i++ {
+		name := querier.Method(i).Name
+		if _, ok := store.MethodByName(name); !ok {
+			hidden = append(hidden, name)
+		}
+	}
+	require.Equal(t, []string{"AddAccountBalance", "CreateEntry", "CreateTransfer"}, hidden)
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// loggingDBTX logs each query at debug level, and failed queries at warn
+// level, with the query name and duration. Loggers built by util.NewLogger
+// add the request ID carried by the context.
+type loggingDBTX struct {
+	db     DBTX
+	logger *slog.Logger
+}
+
+func newLoggingDBTX(db DBTX, logger *slog.Logger) DBTX {
+	return &loggingDBTX{db: db, logger: logger}
+}
+
+func (l *loggingDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	startTime := time.Now()
+	result, err := l.db.ExecContext(ctx, query, args...)
+	l.log(ctx, query, startTime, err)
+	return result, err
+}
+
+func (l *loggingDBTX) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	startTime := time.Now()
+	stmt, err := l.db.PrepareContext(ctx, query)
+	l.log(ctx, query, startTime, err)
+	return stmt, err
+}
+
+func (l *loggingDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	startTime := time.Now()
+	rows, err := l.db.QueryContext(ctx, query, args...)
+	l.log(ctx, query, startTime, err)
+	return rows, err
+}
+
+func (l *loggingDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	startTime := time.Now()
+	row := l.db.QueryRowContext(ctx, query, args...)
+	l.log(ctx, query, startTime, row.Err())
+	return row
+}
+
+func (l *loggingDBTX) log(ctx context.Context, query string, startTime time.Time, err error) {
+	level := slog.LevelDebug
+	attrs := []slog.Attr{
+		slog.String("query", queryName(query)),
+		slog.Duration("duration", time.Since(startTime)),
+	}
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	l.logger.LogAttrs(ctx, level, "db query", attrs...)
+}
+
+// queryName returns the sqlc query name from the "-- name: Foo :one" header,
+// or the first line of the query when there is none
+func queryName(query string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(query), "\n")
+	if name, ok := strings.CutPrefix(line, "-- name: "); ok {
+		name, _, _ = strings.Cut(name, " ")
+		return name
+	}
+	return line
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryName(t *testing.T) {
+	require.Equal(t, "GetAccount", queryName(getAccount))
+	require.Equal(t, "ListAPIKeys", queryName(listAPIKeys))
+	require.Equal(t, "SELECT 1", queryName("SELECT 1"))
+}
+
+func TestLoggingDBTX(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := util.NewLogger(&buf, "debug", util.LogFormatJSON)
+	require.NoError(t, err)
+
+	ctx := util.WithRequestID(context.Background(), "req-42")
+	queries := New(newLoggingDBTX(testDB, logger))
+
+	_, err = queries.GetAccount(ctx, createRandomAccount(t).ID)
+	require.NoError(t, err)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "db query", record["msg"])
+	require.Equal(t, "GetAccount", record["query"])
+	require.Equal(t, "req-42", record["request_id"])
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// AccountEventsChannel is the Postgres channel that NotifyAccountEvent
+// notifies on
+const AccountEventsChannel = "account_events"
+
+// AccountNotification tells listeners on AccountEventsChannel that an
+// account's balance changed. Entry is the entry that changed it, if any.
+type AccountNotification struct {
+	AccountID int64  `json:"account_id"`
+	Balance   int64  `json:"balance"`
+	Currency  string `json:"currency"`
+	Entry     *Entry `json:"entry,omitempty"`
+}
+
+// notifyAccount sends an AccountNotification for account. Postgres delivers
+// it when the transaction commits and drops it on rollback, so listeners
+// only hear about committed changes.
+func notifyAccount(ctx context.Context, q *Queries, account Account, entry *Entry) error {
+	payload, err := json.Marshal(AccountNotification{
+		AccountID: account.ID,
+		Balance:   account.Balance,
+		Currency:  account.Currency,
+		Entry:     entry,
+	})
+	if err != nil {
+		return err
+	}
+	return q.NotifyAccountEvent(ctx, string(payload))
+}
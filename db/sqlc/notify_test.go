@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/julkar-naim/simple-bank/money"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// listenAccountEvents returns a channel of the notifications sent on
+// AccountEventsChannel for the given accounts
+func listenAccountEvents(t *testing.T, accountIDs ...int64) <-chan AccountNotification {
+	config, err := util.LoadConfig()
+	require.NoError(t, err)
+
+	listener := pq.NewListener(config.DBSource, time.Second, time.Second, nil)
+	t.Cleanup(func() { listener.Close() })
+	require.NoError(t, listener.Listen(AccountEventsChannel))
+
+	wanted := make(map[int64]bool)
+	for _, id := range accountIDs {
+		wanted[id] = true
+	}
+
+	notifications := make(chan AccountNotification, 10)
+	go func() {
+		for n := range listener.Notify {
+			if n == nil {
+				continue
+			}
+			var notification AccountNotification
+			if json.Unmarshal([]byte(n.Extra), &notification) == nil && wanted[notification.AccountID] {
+				notifications <- notification
+			}
+		}
+	}()
+	return notifications
+}
+
+func receiveNotification(t *testing.T, notifications <-chan AccountNotification) AccountNotification {
+	select {
+	case notification := <-notifications:
+		return notification
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification")
+		return AccountNotification{}
+	}
+}
+
+func TestTransferTxNotifiesAccounts(t *testing.T) {
+	store := NewSqlStore(testDB)
+	account1, account2 := createTransferAccounts(t)
+	notifications := listenAccountEvents(t, account1.ID, account2.ID)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.New(10, account1.Currency),
+	})
+	require.NoError(t, err)
+
+	received := map[int64]AccountNotification{}
+	for i := 0; i < 2; i++ {
+		notification := receiveNotification(t, notifications)
+		received[notification.AccountID] = notification
+	}
+
+	from := received[account1.ID]
+	require.Equal(t, result.FromAccount.Balance, from.Balance)
+	require.Equal(t, account1.Currency, from.Currency)
+	require.Equal(t, result.FromEntry.ID, from.Entry.ID)
+	require.Equal(t, int64(-10), from.Entry.Amount)
+
+	to := received[account2.ID]
+	require.Equal(t, result.ToAccount.Balance, to.Balance)
+	require.Equal(t, result.ToEntry.ID, to.Entry.ID)
+}
+
+func TestUpdateAccountTxNotifies(t *testing.T) {
+	store := NewSqlStore(testDB)
+	account := createRandomAccount(t)
+	notifications := listenAccountEvents(t, account.ID)
+
+	_, err := store.UpdateAccountTx(context.Background(), UpdateAccountTxParams{
+		ID:      account.ID,
+		Owner:   account.Owner,
+		Balance: account.Balance + 42,
+	})
+	require.NoError(t, err)
+
+	notification := receiveNotification(t, notifications)
+	require.Equal(t, account.Balance+42, notification.Balance)
+	require.NotNil(t, notification.Entry)
+	require.Equal(t, int64(42), notification.Entry.Amount)
+}
+
+func TestRolledBackTransferDoesNotNotify(t *testing.T) {
+	store := NewSqlStore(testDB)
+	account1 := createRandomAccount(t)
+	notifications := listenAccountEvents(t, account1.ID)
+
+	// the destination does not exist, so the transaction rolls back
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   -1,
+		Amount:        money.New(10, account1.Currency),
+	})
+	require.Error(t, err)
+
+	select {
+	case notification := <-notifications:
+		t.Fatalf("unexpected notification %+v", notification)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+)
+
+// event types written to the outbox
+const (
+	EventAccountCreated    = "AccountCreated"
+	EventAccountUpdated    = "AccountUpdated"
+	EventAccountDeleted    = "AccountDeleted"
+	EventAccountFrozen     = "AccountFrozen"
+	EventAccountUnfrozen   = "AccountUnfrozen"
+	EventTransferCompleted = "TransferCompleted"
+)
+
+// EventTypes lists every event type written to the outbox
+var EventTypes = []string{
+	EventAccountCreated,
+	EventAccountUpdated,
+	EventAccountDeleted,
+	EventAccountFrozen,
+	EventAccountUnfrozen,
+	EventTransferCompleted,
+}
+
+// IsSupportedEventType returns true if eventType is written to the outbox
+func IsSupportedEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// aggregate types of outbox events
+const (
+	AggregateAccount  = "account"
+	AggregateTransfer = "transfer"
+)
+
+// TransferCompletedPayload is the payload of a TransferCompleted event.
+// Amount is in minor units of Currency.
+type TransferCompletedPayload struct {
+	Transfer
+	Currency string `json:"currency"`
+}
+
+// recordEvent writes an event to the outbox. It must be called
+// within the transaction that makes the change the event describes, so that
+// the event is stored if and only if the change is committed.
+//
+// It must be called after the transaction has locked the rows it changes,
+// so that the events of transactions changing the same rows draw their ids,
+// and are delivered, in the order the transactions commit.
+func recordEvent(ctx context.Context, q *Queries, eventType, aggregateType string, aggregateID int64, payload any) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		EventType:     eventType,
+		AggregateType: aggregateType,
+		AggregateID:   strconv.FormatInt(aggregateID, 10),
+		Payload:       payloadJSON,
+	})
+	return err
+}
@@ -0,0 +1,255 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/julkar-naim/simple-bank/money"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// relayAll delivers every unsent event, including those left by other
+// tests, and returns them in delivery order
+func relayAll(t *testing.T, store *SqlStore) []Outbox {
+	var events []Outbox
+	for {
+		result, err := store.RelayOutboxTx(context.Background(), RelayOutboxTxParams{
+			Limit: 100,
+			Lease: time.Minute,
+			Publish: func(ctx context.Context, event Outbox) error {
+				events = append(events, event)
+				return nil
+			},
+		})
+		require.NoError(t, err)
+		if !result.Pending {
+			return events
+		}
+	}
+}
+
+// requireEvent finds the only event of eventType for the aggregate
+func requireEvent(t *testing.T, events []Outbox, eventType string, aggregateID int64) Outbox {
+	var found []Outbox
+	for _, event := range events {
+		if event.EventType == eventType && event.AggregateID == strconv.FormatInt(aggregateID, 10) {
+			found = append(found, event)
+		}
+	}
+	require.Len(t, found, 1)
+	return found[0]
+}
+
+func TestAccountTxRecordsEvents(t *testing.T) {
+	store := NewSqlStore(testDB)
+	relayAll(t, store)
+
+	user := createRandomUser(t)
+	account, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Currency: "USD",
+	})
+	require.NoError(t, err)
+
+	frozen, err := store.SetAccountFrozenTx(context.Background(), SetAccountFrozenParams{ID: account.ID, IsFrozen: true})
+	require.NoError(t, err)
+	require.True(t, frozen.IsFrozen)
+
+	_, err = store.SetAccountFrozenTx(context.Background(), SetAccountFrozenParams{ID: account.ID, IsFrozen: false})
+	require.NoError(t, err)
+
+	// an account with ledger entries cannot be deleted, so only its owner changes
+	newOwner := createRandomUser(t)
+	updated, err := store.UpdateAccountTx(context.Background(), UpdateAccountTxParams{
+		ID:      account.ID,
+		Owner:   newOwner.Username,
+		Balance: account.Balance,
+	})
+	require.NoError(t, err)
+
+	err = store.DeleteAccountTx(context.Background(), account.ID)
+	require.NoError(t, err)
+
+	events := relayAll(t, store)
+
+	created := requireEvent(t, events, EventAccountCreated, account.ID)
+	require.Equal(t, AggregateAccount, created.AggregateType)
+	var payload Account
+	require.NoError(t, json.Unmarshal(created.Payload, &payload))
+	require.Equal(t, account.ID, payload.ID)
+	require.Equal(t, account.Owner, payload.Owner)
+
+	requireEvent(t, events, EventAccountFrozen, account.ID)
+	requireEvent(t, events, EventAccountUnfrozen, account.ID)
+
+	updatedEvent := requireEvent(t, events, EventAccountUpdated, account.ID)
+	require.NoError(t, json.Unmarshal(updatedEvent.Payload, &payload))
+	require.Equal(t, updated.Owner, payload.Owner)
+
+	deleted := requireEvent(t, events, EventAccountDeleted, account.ID)
+	require.Less(t, created.ID, deleted.ID)
+
+	// events are delivered in the order they were written
+	for i := 1; i < len(events); i++ {
+		require.Less(t, events[i-1].ID, events[i].ID)
+	}
+}
+
+func TestAccountTxWithoutChangeRecordsNoEvent(t *testing.T) {
+	store := NewSqlStore(testDB)
+	relayAll(t, store)
+
+	_, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    util.RandomOwner(),
+		Currency: "USD",
+	})
+	require.Error(t, err)
+
+	err = store.DeleteAccountTx(context.Background(), math.MaxInt64)
+	require.Error(t, err)
+
+	require.Empty(t, relayAll(t, store))
+}
+
+func TestTransferTxRecordsEvent(t *testing.T) {
+	store := NewSqlStore(testDB)
+	relayAll(t, store)
+
+	account1, account2 := createTransferAccounts(t)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        money.New(10, account1.Currency),
+	})
+	require.NoError(t, err)
+
+	event := requireEvent(t, relayAll(t, store), EventTransferCompleted, result.Transfer.ID)
+	require.Equal(t, AggregateTransfer, event.AggregateType)
+
+	var payload TransferCompletedPayload
+	require.NoError(t, json.Unmarshal(event.Payload, &payload))
+	require.Equal(t, result.Transfer.ID, payload.ID)
+	require.Equal(t, int64(10), payload.Amount)
+	require.Equal(t, account1.Currency, payload.Currency)
+}
+
+func TestRelayOutboxTxPublishFailure(t *testing.T) {
+	store := NewSqlStore(testDB)
+	relayAll(t, store)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	events := relayAll(t, store)
+	first := requireEvent(t, events, EventAccountCreated, account1.ID)
+	second := requireEvent(t, events, EventAccountCreated, account2.ID)
+
+	// put both events back in the outbox to relay them again
+	_, err := testDB.Exec("UPDATE outbox SET sent_at = NULL WHERE id = ANY($1)", pq.Array([]int64{first.ID, second.ID}))
+	require.NoError(t, err)
+
+	errPublish := errors.New("publish failed")
+	var published []int64
+	result, err := store.RelayOutboxTx(context.Background(), RelayOutboxTxParams{
+		Limit: 100,
+		Lease: time.Minute,
+		Publish: func(ctx context.Context, event Outbox) error {
+			if event.ID == second.ID {
+				return errPublish
+			}
+			published = append(published, event.ID)
+			return nil
+		},
+	})
+	require.ErrorIs(t, err, errPublish)
+	require.Equal(t, 1, result.Sent)
+	require.True(t, result.Pending)
+	require.Equal(t, []int64{first.ID}, published)
+
+	stored, err := testQueries.GetOutboxEvent(context.Background(), first.ID)
+	require.NoError(t, err)
+	require.True(t, stored.SentAt.Valid)
+
+	stored, err = testQueries.GetOutboxEvent(context.Background(), second.ID)
+	require.NoError(t, err)
+	require.False(t, stored.SentAt.Valid)
+	require.False(t, stored.ClaimedUntil.Valid)
+
+	// the failed event is delivered by the next relay
+	requireEvent(t, relayAll(t, store), EventAccountCreated, account2.ID)
+}
+
+func TestRelayOutboxTxPublishesOutsideTransaction(t *testing.T) {
+	store := NewSqlStore(testDB)
+	relayAll(t, store)
+
+	account := createRandomAccount(t)
+
+	// a second relay running while the first publishes neither waits for
+	// its locks nor delivers the events it claimed
+	var published, publishedAgain []int64
+	result, err := store.RelayOutboxTx(context.Background(), RelayOutboxTxParams{
+		Limit: 100,
+		Lease: time.Minute,
+		Publish: func(ctx context.Context, event Outbox) error {
+			published = append(published, event.ID)
+			require.True(t, event.ClaimedUntil.Valid)
+
+			again, err := store.RelayOutboxTx(ctx, RelayOutboxTxParams{
+				Limit: 100,
+				Lease: time.Minute,
+				Publish: func(ctx context.Context, event Outbox) error {
+					publishedAgain = append(publishedAgain, event.ID)
+					return nil
+				},
+			})
+			require.NoError(t, err)
+			require.Zero(t, again.Sent)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Sent)
+	require.Len(t, published, 1)
+	require.Empty(t, publishedAgain)
+
+	stored, err := testQueries.GetOutboxEvent(context.Background(), published[0])
+	require.NoError(t, err)
+	require.Equal(t, strconv.FormatInt(account.ID, 10), stored.AggregateID)
+	require.True(t, stored.SentAt.Valid)
+	require.False(t, stored.ClaimedUntil.Valid)
+}
+
+func TestRelayWaitsForOlderTransactions(t *testing.T) {
+	ctx := context.Background()
+	store := NewSqlStore(testDB)
+	relayAll(t, store)
+	// aggregate ids no account has, so requireEvent never finds these events
+	const aggregate1, aggregate2 = -1, -2
+
+	tx1, err := testDB.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer tx1.Rollback()
+	require.NoError(t, recordEvent(ctx, New(tx1), EventAccountCreated, AggregateAccount, aggregate1, nil))
+
+	tx2, err := testDB.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, recordEvent(ctx, New(tx2), EventAccountCreated, AggregateAccount, aggregate2, nil))
+	require.NoError(t, tx2.Commit())
+
+	// the second event is committed, but the first transaction is older and
+	// still in flight
+	require.Empty(t, relayAll(t, store))
+
+	require.NoError(t, tx1.Commit())
+	events := relayAll(t, store)
+	require.Len(t, events, 2)
+	require.Equal(t, strconv.Itoa(aggregate1), events[0].AggregateID)
+	require.Equal(t, strconv.Itoa(aggregate2), events[1].AggregateID)
+}
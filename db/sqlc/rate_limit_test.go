@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdateRateLimitBucketTx(t *testing.T) {
+	store := NewSqlStore(testDB)
+	key := util.RandomString(12)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	take := func(bucket RateLimitBucket) RateLimitBucket {
+		bucket.Tokens--
+		bucket.UpdatedAt = now
+		bucket.FullAt = now.Add(time.Minute)
+		return bucket
+	}
+
+	// a missing bucket starts from the initial state
+	bucket, err := store.UpdateRateLimitBucketTx(context.Background(), UpdateRateLimitBucketTxParams{
+		Key:     key,
+		Initial: RateLimitBucket{Tokens: 10, UpdatedAt: now, FullAt: now},
+		Update:  take,
+	})
+	require.NoError(t, err)
+	require.Equal(t, key, bucket.Key)
+	require.Equal(t, float64(9), bucket.Tokens)
+
+	// concurrent updates are serialized by the row lock
+	n := 5
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.UpdateRateLimitBucketTx(context.Background(), UpdateRateLimitBucketTxParams{
+				Key:     key,
+				Initial: RateLimitBucket{Tokens: 10, UpdatedAt: now, FullAt: now},
+				Update:  take,
+			})
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	stored, err := testQueries.GetRateLimitBucketForUpdate(context.Background(), key)
+	require.NoError(t, err)
+	require.Equal(t, float64(9-n), stored.Tokens)
+	require.WithinDuration(t, now, stored.UpdatedAt, time.Second)
+	require.WithinDuration(t, now.Add(time.Minute), stored.FullAt, time.Second)
+}
+
+func TestDeleteFullRateLimitBuckets(t *testing.T) {
+	key := util.RandomString(12)
+	now := time.Now()
+
+	err := testQueries.CreateRateLimitBucket(context.Background(), CreateRateLimitBucketParams{
+		Key:       key,
+		Tokens:    1,
+		UpdatedAt: now.Add(-time.Hour),
+		FullAt:    now.Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	err = testQueries.DeleteFullRateLimitBuckets(context.Background(), now)
+	require.NoError(t, err)
+
+	_, err = testQueries.GetRateLimitBucketForUpdate(context.Background(), key)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
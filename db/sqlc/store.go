@@ -3,49 +3,231 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julkar-naim/simple-bank/apperr"
+	"github.com/julkar-naim/simple-bank/money"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// StoreQuerier is the Querier without the queries that append to the ledger
+// or move a balance. Those only run inside the Store's transactions, which
+// keep the hash chains and balances in step.
+type StoreQuerier interface {
+	BlockSession(ctx context.Context, id uuid.UUID) (Session, error)
+	BlockUserSessions(ctx context.Context, username string) ([]Session, error)
+	ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]Outbox, error)
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
+	CreateAPIKeyNonce(ctx context.Context, arg CreateAPIKeyNonceParams) error
+	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error)
+	CreateCurrency(ctx context.Context, arg CreateCurrencyParams) (Currency, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (Outbox, error)
+	CreateRateLimitBucket(ctx context.Context, arg CreateRateLimitBucketParams) error
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	CreateWebhookAttempt(ctx context.Context, arg CreateWebhookAttemptParams) (WebhookAttempt, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) error
+	DeleteAPIKeyNonces(ctx context.Context, createdAt time.Time) error
+	DeleteAccount(ctx context.Context, id int64) error
+	DeleteFullRateLimitBuckets(ctx context.Context, fullAt time.Time) error
+	GetAPIKey(ctx context.Context, keyID string) (ApiKey, error)
+	GetAccount(ctx context.Context, id int64) (Account, error)
+	GetAccountEntries(ctx context.Context, accountID int64) ([]Entry, error)
+	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetCurrency(ctx context.Context, code string) (Currency, error)
+	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetLastEntryHash(ctx context.Context, accountID int64) (string, error)
+	GetLastEntryID(ctx context.Context, accountID int64) (int64, error)
+	GetLastTransferHash(ctx context.Context, fromAccountID int64) (string, error)
+	GetOutboxEvent(ctx context.Context, id int64) (Outbox, error)
+	GetRateLimitBucketForUpdate(ctx context.Context, key string) (RateLimitBucket, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
+	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetUser(ctx context.Context, username string) (User, error)
+	GetWebhook(ctx context.Context, id int64) (Webhook, error)
+	GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	ListAPIKeys(ctx context.Context, arg ListAPIKeysParams) ([]ApiKey, error)
+	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListAllAccounts(ctx context.Context, arg ListAllAccountsParams) ([]Account, error)
+	ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]AuditLog, error)
+	ListCurrencies(ctx context.Context) ([]Currency, error)
+	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListEntriesAfter(ctx context.Context, arg ListEntriesAfterParams) ([]Entry, error)
+	ListTransferEntries(ctx context.Context, transferID sql.NullInt64) ([]Entry, error)
+	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	ListTransfersFromAccountAfter(ctx context.Context, arg ListTransfersFromAccountAfterParams) ([]Transfer, error)
+	ListUnreconciledAccounts(ctx context.Context, arg ListUnreconciledAccountsParams) ([]ListUnreconciledAccountsRow, error)
+	ListWebhookAttempts(ctx context.Context, deliveryID int64) ([]WebhookAttempt, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhooks(ctx context.Context, arg ListWebhooksParams) ([]Webhook, error)
+	ListWebhooksForEvent(ctx context.Context, eventType string) ([]Webhook, error)
+	LockOutboxClaims(ctx context.Context) error
+	MarkOutboxEventsSent(ctx context.Context, ids []int64) error
+	NotifyAccountEvent(ctx context.Context, payload string) error
+	ReleaseOutboxEvents(ctx context.Context, ids []int64) error
+	RetryWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	RevokeAPIKey(ctx context.Context, keyID string) (ApiKey, error)
+	SetAccountFrozen(ctx context.Context, arg SetAccountFrozenParams) (Account, error)
+	SetCurrencyEnabled(ctx context.Context, arg SetCurrencyEnabledParams) (Currency, error)
+	SetWebhookActive(ctx context.Context, arg SetWebhookActiveParams) (Webhook, error)
+	UpdateAccountOwner(ctx context.Context, arg UpdateAccountOwnerParams) (Account, error)
+	UpdateRateLimitBucket(ctx context.Context, arg UpdateRateLimitBucketParams) error
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UpdateWebhookDelivery(ctx context.Context, arg UpdateWebhookDeliveryParams) (WebhookDelivery, error)
+}
+
+// Store runs queries and the transactions built from them. *Tx methods are
+// retried up to maxTxAttempts times on serialization failures and deadlocks,
+// so side effects outside the database may repeat.
 type Store interface {
-	Querier
+	StoreQuerier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error)
+	UpdateAccountTx(ctx context.Context, arg UpdateAccountTxParams) (Account, error)
+	DeleteAccountTx(ctx context.Context, id int64) error
+	SetAccountFrozenTx(ctx context.Context, arg SetAccountFrozenParams) (Account, error)
+	RelayOutboxTx(ctx context.Context, arg RelayOutboxTxParams) (RelayOutboxTxResult, error)
+	RecordWebhookAttemptTx(ctx context.Context, arg RecordWebhookAttemptTxParams) (RecordWebhookAttemptTxResult, error)
+	CreateUserTx(ctx context.Context, arg CreateUserParams) (User, error)
+	UpdateUserRoleTx(ctx context.Context, arg UpdateUserRoleTxParams) (UpdateUserRoleTxResult, error)
+	CreateAPIKeyTx(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
+	RevokeAPIKeyTx(ctx context.Context, keyID string) (ApiKey, error)
+	CreateSessionTx(ctx context.Context, arg CreateSessionParams) (Session, error)
+	BlockSessionTx(ctx context.Context, id uuid.UUID) (Session, error)
+	BlockUserSessionsTx(ctx context.Context, username string) ([]Session, error)
+	CreateCurrencyTx(ctx context.Context, arg CreateCurrencyParams) (Currency, error)
+	SetCurrencyEnabledTx(ctx context.Context, arg SetCurrencyEnabledParams) (Currency, error)
+	CreateWebhookTx(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	SetWebhookActiveTx(ctx context.Context, arg SetWebhookActiveParams) (Webhook, error)
+	RetryWebhookDeliveryTx(ctx context.Context, id int64) (WebhookDelivery, error)
+	UpdateRateLimitBucketTx(ctx context.Context, arg UpdateRateLimitBucketTxParams) (RateLimitBucket, error)
+	Ping(ctx context.Context) error
+	MigrationVersion(ctx context.Context) (version int64, dirty bool, err error)
+	PoolStats() sql.DBStats
+}
+
+// TxObserver is notified when execTx retries or rolls back a transaction
+type TxObserver interface {
+	ObserveTxRetry(ctx context.Context, err error)
+	ObserveTxRollback(ctx context.Context, err error)
 }
 
+type noopTxObserver struct{}
+
+func (noopTxObserver) ObserveTxRetry(context.Context, error)    {}
+func (noopTxObserver) ObserveTxRollback(context.Context, error) {}
+
+// maxTxAttempts bounds how often execTx runs a transaction that fails with a
+// serialization failure or deadlock
+const maxTxAttempts = 3
+
 // SqlStore provides all necessary function for db query and transactions
 type SqlStore struct {
 	*Queries
-	db *sql.DB
+	db         *sql.DB
+	logger     *slog.Logger
+	txObserver TxObserver
 }
 
-// NewSqlStore creates a new SqlStore
+// NewSqlStore creates a new SqlStore that logs its queries and transactions
+// with slog.Default() and traces them with the global tracer provider
 func NewSqlStore(db *sql.DB) *SqlStore {
-	return &SqlStore{
-		db:      db,
-		Queries: New(db),
+	store := &SqlStore{
+		db:         db,
+		logger:     slog.Default(),
+		txObserver: noopTxObserver{},
 	}
+	store.Queries = New(store.wrapDBTX(db))
+	return store
 }
 
+// SetTxObserver registers the observer notified of transaction retries and rollbacks
+func (store *SqlStore) SetTxObserver(observer TxObserver) {
+	store.txObserver = observer
+}
+
+// execTx runs callback in a transaction, retrying it from the start when
+// Postgres reports a serialization failure or deadlock. A retried attempt
+// reads again and may see rows changed by the transaction it conflicted
+// with, and only the last attempt is committed. callback must reset any
+// result it sets, since a failed attempt may have set it already, and should
+// not act outside the database, since that is repeated too; RelayOutboxTx
+// publishes between its transactions for that reason.
 func (store *SqlStore) execTx(ctx context.Context, callback func(queries *Queries) error) error {
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		err = store.runTx(ctx, attempt, callback)
+		if err == nil || !isRetryableTxError(err) || attempt == maxTxAttempts {
+			return err
+		}
+		store.txObserver.ObserveTxRetry(ctx, err)
+		store.logger.WarnContext(ctx, "db tx retry", "attempt", attempt, "error", err)
+	}
+	return err
+}
+
+func (store *SqlStore) runTx(ctx context.Context, attempt int, callback func(queries *Queries) error) (err error) {
+	ctx, span := tracer.Start(ctx, "db.tx", trace.WithAttributes(attrTxAttempt.Int(attempt)))
+	defer func() {
+		recordSpanError(span, err)
+		span.End()
+	}()
+
+	startTime := time.Now()
 	tx, err := store.db.BeginTx(ctx, nil)
 	if err != nil {
+		store.logger.WarnContext(ctx, "db tx begin failed", "error", err)
 		return err
 	}
 
-	q := New(tx)
+	q := New(store.wrapDBTX(tx))
 	err = callback(q)
 	if err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			return fmt.Errorf("tx error: %v, rb error: %v", err, rbErr)
+		store.txObserver.ObserveTxRollback(ctx, err)
+		// database/sql has already rolled back when ctx was cancelled
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			store.logger.ErrorContext(ctx, "db tx rollback failed", "error", err, "rollback_error", rbErr)
+			return fmt.Errorf("tx error: %w, rb error: %v", err, rbErr)
 		}
+		store.logger.WarnContext(ctx, "db tx rolled back", "error", err, "duration", time.Since(startTime))
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		store.logger.WarnContext(ctx, "db tx commit failed", "error", err)
 		return err
 	}
-	return tx.Commit()
+	store.logger.DebugContext(ctx, "db tx committed", "duration", time.Since(startTime))
+	return nil
+}
+
+// isRetryableTxError reports whether running the transaction again may succeed
+func isRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code.Name() {
+	case "serialization_failure", "deadlock_detected":
+		return true
+	}
+	return false
 }
 
 type TransferTxParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64        `json:"from_account_id"`
+	ToAccountID   int64        `json:"to_account_id"`
+	Amount        money.Amount `json:"amount"`
 }
 
 type TransferTxResult struct {
@@ -57,53 +239,135 @@ type TransferTxResult struct {
 }
 
 // TransferTx handles money transaction
-// atomic steps are: create transfer, create entry, update balance
+// atomic steps are: lock and check both accounts, update balance, create
+// hash-chained transfer and entries linked to it, notify both accounts'
+// listeners, audit the transfer, record a TransferCompleted event. It
+// returns an *apperr.Error if an account is frozen, does not hold the
+// amount's currency or, for the source, cannot cover the amount.
 func (store *SqlStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
+	ctx, span := tracer.Start(ctx, "db.TransferTx", trace.WithAttributes(
+		attrFromAccountID.Int64(arg.FromAccountID),
+		attrToAccountID.Int64(arg.ToAccountID),
+		attrAmount.Int64(arg.Amount.Value()),
+		attrCurrency.String(arg.Amount.Currency()),
+	))
+	defer span.End()
+
 	var result TransferTxResult
+
 	var err error
+	amount := arg.Amount.Value()
 
 	err = store.execTx(ctx, func(q *Queries) error {
+		result = TransferTxResult{}
 
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			Amount:        arg.Amount,
-		})
+		// lock both accounts first: the ledger chains are appended to under
+		// their locks, and the checks hold until commit
+		err = lockTransferAccounts(ctx, q, arg)
+		if err != nil {
+			return err
+		}
+
+		if arg.FromAccountID < arg.ToAccountID {
+			result.FromAccount, result.ToAccount, err = addMoney(q, ctx, arg.FromAccountID, -amount, arg.ToAccountID, amount)
+		} else {
+			result.ToAccount, result.FromAccount, err = addMoney(q, ctx, arg.ToAccountID, amount, arg.FromAccountID, -amount)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		result.Transfer, err = createChainedTransfer(ctx, q, arg.FromAccountID, arg.ToAccountID, amount)
 		if err != nil {
 			return err
 		}
 
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
+		transferID := sql.NullInt64{Int64: result.Transfer.ID, Valid: true}
+		result.FromEntry, err = createChainedEntry(ctx, q, chainedEntryParams{
+			AccountID:   arg.FromAccountID,
+			Amount:      -amount,
+			TransferID:  transferID,
+			EntryType:   EntryTypeTransfer,
+			Description: fmt.Sprintf("transfer to account %d", arg.ToAccountID),
 		})
 		if err != nil {
 			return err
 		}
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
+		result.ToEntry, err = createChainedEntry(ctx, q, chainedEntryParams{
+			AccountID:   arg.ToAccountID,
+			Amount:      amount,
+			TransferID:  transferID,
+			EntryType:   EntryTypeTransfer,
+			Description: fmt.Sprintf("transfer from account %d", arg.FromAccountID),
 		})
 		if err != nil {
 			return err
 		}
 
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addMoney(q, ctx, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(q, ctx, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+		err = notifyAccount(ctx, q, result.FromAccount, &result.FromEntry)
+		if err != nil {
+			return err
+		}
+		err = notifyAccount(ctx, q, result.ToAccount, &result.ToEntry)
+		if err != nil {
+			return err
 		}
 
+		_, err = recordAudit(ctx, q, AuditActionCreateTransfer, AuditTargetTransfer, strconv.FormatInt(result.Transfer.ID, 10), transferSnapshot{
+			FromBalance: result.FromAccount.Balance + amount,
+			ToBalance:   result.ToAccount.Balance - amount,
+		}, transferSnapshot{
+			Transfer:    &result.Transfer,
+			FromBalance: result.FromAccount.Balance,
+			ToBalance:   result.ToAccount.Balance,
+		})
 		if err != nil {
 			return err
 		}
-		return nil
+
+		return recordEvent(ctx, q, EventTransferCompleted, AggregateTransfer, result.Transfer.ID, TransferCompletedPayload{
+			Transfer: result.Transfer,
+			Currency: result.FromAccount.Currency,
+		})
 	})
 
+	recordSpanError(span, err)
 	return result, err
 }
 
+// lockTransferAccounts locks both accounts of a transfer in id order, so
+// that concurrent transfers between them cannot deadlock, and checks that
+// the transfer may move money between them
+func lockTransferAccounts(ctx context.Context, q *Queries, arg TransferTxParams) error {
+	ids := []int64{arg.FromAccountID, arg.ToAccountID}
+	if ids[0] > ids[1] {
+		ids[0], ids[1] = ids[1], ids[0]
+	}
+
+	for _, id := range ids {
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if account.IsFrozen {
+			return apperr.Newf(apperr.CodePermissionDenied, "account [%d] is frozen", account.ID).
+				WithDetail("account_id", account.ID)
+		}
+		if account.Currency != arg.Amount.Currency() {
+			return apperr.Newf(apperr.CodeInvalidArgument, "account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, arg.Amount.Currency()).
+				WithDetail("account_id", account.ID)
+		}
+		if account.ID == arg.FromAccountID && account.Balance < arg.Amount.Value() {
+			return apperr.Newf(apperr.CodeFailedPrecondition, "account [%d] has insufficient funds", account.ID).
+				WithDetail("account_id", account.ID)
+		}
+	}
+	return nil
+}
+
 func addMoney(q *Queries, ctx context.Context, account1ID, amount1, account2ID, amount2 int64) (account1, account2 Account, err error) {
 	account1, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
 		ID:     account1ID,
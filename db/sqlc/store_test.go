@@ -2,17 +2,22 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"github.com/julkar-naim/simple-bank/apperr"
+	"github.com/julkar-naim/simple-bank/money"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 	"log"
+	"strconv"
 	"testing"
 )
 
 func TestStore_TransferTx(t *testing.T) {
 	store := NewSqlStore(testDB)
 
-	toAccount := createRandomAccount(t)
-	fromAccount := createRandomAccount(t)
+	toAccount, fromAccount := createTransferAccounts(t)
 
 	// test transfer transaction concurrently
 	amount := int64(10)
@@ -28,7 +33,7 @@ func TestStore_TransferTx(t *testing.T) {
 			result, err := store.TransferTx(context.Background(), TransferTxParams{
 				ToAccountID:   toAccount.ID,
 				FromAccountID: fromAccount.ID,
-				Amount:        amount,
+				Amount:        money.New(amount, fromAccount.Currency),
 			})
 			errs <- err
 			results <- result
@@ -111,8 +116,7 @@ func TestStore_TransferTx(t *testing.T) {
 func TestStore_TransferTxDeadlock(t *testing.T) {
 	store := NewSqlStore(testDB)
 
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	account1, account2 := createTransferAccounts(t)
 
 	// test transfer transaction concurrently
 	amount := int64(10)
@@ -133,7 +137,7 @@ func TestStore_TransferTxDeadlock(t *testing.T) {
 			_, err := store.TransferTx(context.Background(), TransferTxParams{
 				FromAccountID: fromAccount.ID,
 				ToAccountID:   toAccount.ID,
-				Amount:        amount,
+				Amount:        money.New(amount, fromAccount.Currency),
 			})
 			errs <- err
 		}()
@@ -157,3 +161,218 @@ func TestStore_TransferTxDeadlock(t *testing.T) {
 	require.Equal(t, account1.Balance, updatedSenderAccount.Balance)
 	require.Equal(t, account2.Balance, updatedReceiverAccount.Balance)
 }
+
+func TestIsRetryableTxError(t *testing.T) {
+	require.True(t, isRetryableTxError(&pq.Error{Code: "40001"}))
+	require.True(t, isRetryableTxError(fmt.Errorf("wrapped: %w", &pq.Error{Code: "40P01"})))
+	require.False(t, isRetryableTxError(&pq.Error{Code: "23505"}))
+	require.False(t, isRetryableTxError(sql.ErrNoRows))
+}
+
+func TestStore_ExecTxRollsBackOnCancel(t *testing.T) {
+	store := NewSqlStore(testDB)
+
+	fromAccount := createRandomAccount(t)
+	toAccount := createRandomAccount(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var transfer Transfer
+	err := store.execTx(ctx, func(queries *Queries) error {
+		var err error
+		transfer, err = createChainedTransfer(ctx, queries, fromAccount.ID, toAccount.ID, 10)
+		if err != nil {
+			return err
+		}
+
+		cancel()
+
+		_, err = createChainedEntry(ctx, queries, chainedEntryParams{
+			AccountID:  fromAccount.ID,
+			Amount:     -10,
+			TransferID: sql.NullInt64{Int64: transfer.ID, Valid: true},
+			EntryType:  EntryTypeTransfer,
+		})
+		return err
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotZero(t, transfer.ID)
+
+	_, err = store.GetTransfer(context.Background(), transfer.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestStore_TransferTxCanceled(t *testing.T) {
+	store := NewSqlStore(testDB)
+
+	fromAccount, toAccount := createTransferAccounts(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := store.TransferTx(ctx, TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        money.New(10, fromAccount.Currency),
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	updatedFromAccount, err := store.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, fromAccount.Balance, updatedFromAccount.Balance)
+
+	updatedToAccount, err := store.GetAccount(context.Background(), toAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, toAccount.Balance, updatedToAccount.Balance)
+}
+
+func TestStore_TransferTxRejected(t *testing.T) {
+	store := NewSqlStore(testDB)
+
+	freeze := func(t *testing.T, account Account) {
+		_, err := testQueries.SetAccountFrozen(context.Background(), SetAccountFrozenParams{ID: account.ID, IsFrozen: true})
+		require.NoError(t, err)
+	}
+
+	testCases := []struct {
+		name   string
+		setup  func(t *testing.T, fromAccount, toAccount Account) money.Amount
+		code   apperr.Code
+		failed func(fromAccount, toAccount Account) int64
+	}{
+		{
+			name: "FromAccountFrozen",
+			setup: func(t *testing.T, fromAccount, toAccount Account) money.Amount {
+				freeze(t, fromAccount)
+				return money.New(10, fromAccount.Currency)
+			},
+			code:   apperr.CodePermissionDenied,
+			failed: func(fromAccount, toAccount Account) int64 { return fromAccount.ID },
+		},
+		{
+			name: "ToAccountFrozen",
+			setup: func(t *testing.T, fromAccount, toAccount Account) money.Amount {
+				freeze(t, toAccount)
+				return money.New(10, fromAccount.Currency)
+			},
+			code:   apperr.CodePermissionDenied,
+			failed: func(fromAccount, toAccount Account) int64 { return toAccount.ID },
+		},
+		{
+			name: "CurrencyMismatch",
+			setup: func(t *testing.T, fromAccount, toAccount Account) money.Amount {
+				for _, currency := range util.SupportedCurrencies() {
+					if currency.Code != fromAccount.Currency {
+						return money.New(10, currency.Code)
+					}
+				}
+				t.Skip("needs a second enabled currency")
+				return money.Amount{}
+			},
+			code: apperr.CodeInvalidArgument,
+			failed: func(fromAccount, toAccount Account) int64 {
+				return min(fromAccount.ID, toAccount.ID)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			setup: func(t *testing.T, fromAccount, toAccount Account) money.Amount {
+				return money.New(fromAccount.Balance+1, fromAccount.Currency)
+			},
+			code:   apperr.CodeFailedPrecondition,
+			failed: func(fromAccount, toAccount Account) int64 { return fromAccount.ID },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fromAccount, toAccount := createTransferAccounts(t)
+			amount := tc.setup(t, fromAccount, toAccount)
+
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccount.ID,
+				Amount:        amount,
+			})
+			appErr := apperr.As(err)
+			require.NotNil(t, appErr)
+			require.Equal(t, tc.code, appErr.Code)
+			require.Equal(t, tc.failed(fromAccount, toAccount), appErr.Details["account_id"])
+
+			for _, account := range []Account{fromAccount, toAccount} {
+				updated, err := store.GetAccount(context.Background(), account.ID)
+				require.NoError(t, err)
+				require.Equal(t, account.Balance, updated.Balance)
+			}
+		})
+	}
+}
+
+// countingTxObserver counts the transaction retries and rollbacks of a store
+type countingTxObserver struct {
+	retries   int
+	rollbacks int
+}
+
+func (o *countingTxObserver) ObserveTxRetry(context.Context, error)    { o.retries++ }
+func (o *countingTxObserver) ObserveTxRollback(context.Context, error) { o.rollbacks++ }
+
+func TestStore_TransferTxRetried(t *testing.T) {
+	store := NewSqlStore(testDB)
+	observer := &countingTxObserver{}
+	store.SetTxObserver(observer)
+
+	fromAccount, toAccount := createTransferAccounts(t)
+
+	// fail the first attempt with a serialization failure once its
+	// balances, transfer and first entry are written. A sequence is not
+	// rolled back, so the second attempt goes through.
+	_, err := testDB.Exec(fmt.Sprintf(`
+		CREATE SEQUENCE retry_once_seq;
+		CREATE FUNCTION retry_once() RETURNS trigger AS $$
+		BEGIN
+		  IF NEW.account_id = %d AND nextval('retry_once_seq') = 1 THEN
+		    RAISE EXCEPTION 'could not serialize access' USING ERRCODE = 'serialization_failure';
+		  END IF;
+		  RETURN NEW;
+		END $$ LANGUAGE plpgsql;
+		CREATE TRIGGER retry_once AFTER INSERT ON entries FOR EACH ROW EXECUTE FUNCTION retry_once();`, toAccount.ID))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := testDB.Exec(`
+			DROP TRIGGER retry_once ON entries;
+			DROP FUNCTION retry_once();
+			DROP SEQUENCE retry_once_seq;`)
+		require.NoError(t, err)
+	})
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        money.New(10, fromAccount.Currency),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, observer.retries)
+	require.Equal(t, 1, observer.rollbacks)
+
+	// only the committed attempt moved money and wrote the ledger
+	require.Equal(t, fromAccount.Balance-10, result.FromAccount.Balance)
+	require.Equal(t, toAccount.Balance+10, result.ToAccount.Balance)
+
+	updatedFromAccount, err := store.GetAccount(context.Background(), fromAccount.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.FromAccount, updatedFromAccount)
+
+	entries, err := store.ListEntries(context.Background(), ListEntriesParams{AccountID: fromAccount.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, []Entry{result.FromEntry}, entries)
+	require.Empty(t, result.FromEntry.PrevHash)
+
+	linked, err := store.ListTransferEntries(context.Background(), sql.NullInt64{Int64: result.Transfer.ID, Valid: true})
+	require.NoError(t, err)
+	require.Equal(t, []Entry{result.FromEntry, result.ToEntry}, linked)
+
+	auditLogs := listTargetAuditLogs(t, AuditTargetTransfer, strconv.FormatInt(result.Transfer.ID, 10))
+	require.Len(t, auditLogs, 1)
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer uses the global provider, so spans are dropped until one is installed
+var tracer = otel.Tracer("github.com/julkar-naim/simple-bank/db/sqlc")
+
+// tracingDBTX starts a client span, named after the sqlc query, for each query
+type tracingDBTX struct {
+	db DBTX
+}
+
+func newTracingDBTX(db DBTX) DBTX {
+	return &tracingDBTX{db: db}
+}
+
+func (t *tracingDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	result, err := t.db.ExecContext(ctx, query, args...)
+	recordSpanError(span, err)
+	return result, err
+}
+
+func (t *tracingDBTX) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	stmt, err := t.db.PrepareContext(ctx, query)
+	recordSpanError(span, err)
+	return stmt, err
+}
+
+func (t *tracingDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	recordSpanError(span, err)
+	return rows, err
+}
+
+func (t *tracingDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	row := t.db.QueryRowContext(ctx, query, args...)
+	recordSpanError(span, row.Err())
+	return row
+}
+
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	name := queryName(query)
+	return tracer.Start(ctx, "db."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(name),
+		),
+	)
+}
+
+// recordSpanError marks the span failed; sql.ErrNoRows is an expected outcome
+// and is left alone
+func recordSpanError(span trace.Span, err error) {
+	if err == nil || err == sql.ErrNoRows {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// wrapDBTX layers tracing and logging over a connection or transaction
+func (store *SqlStore) wrapDBTX(db DBTX) DBTX {
+	return newTracingDBTX(newLoggingDBTX(db, store.logger))
+}
+
+// attribute keys for ledger spans
+var (
+	attrFromAccountID = attribute.Key("bank.from_account_id")
+	attrToAccountID   = attribute.Key("bank.to_account_id")
+	attrAmount        = attribute.Key("bank.amount")
+	attrCurrency      = attribute.Key("bank.currency")
+	attrTxAttempt     = attribute.Key("db.tx.attempt")
+)
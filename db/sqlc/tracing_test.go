@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/julkar-naim/simple-bank/money"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTransferTxSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	store := NewSqlStore(testDB)
+	fromAccount, toAccount := createTransferAccounts(t)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        money.New(10, fromAccount.Currency),
+	})
+	require.NoError(t, err)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	transferSpan, ok := spans["db.TransferTx"]
+	require.True(t, ok)
+	attrs := map[string]int64{}
+	for _, attr := range transferSpan.Attributes() {
+		attrs[string(attr.Key)] = attr.Value.AsInt64()
+	}
+	require.Equal(t, fromAccount.ID, attrs["bank.from_account_id"])
+	require.Equal(t, toAccount.ID, attrs["bank.to_account_id"])
+	require.Equal(t, int64(10), attrs["bank.amount"])
+
+	for _, name := range []string{"db.tx", "db.CreateTransfer", "db.CreateEntry", "db.AddAccountBalance"} {
+		span, ok := spans[name]
+		require.Truef(t, ok, "missing span %s", name)
+		require.Equal(t, transferSpan.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+}
@@ -7,26 +7,40 @@ package db
 
 import (
 	"context"
+	"time"
 )
 
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
     from_account_id,
     to_account_id,
-    amount
+    amount,
+    created_at,
+    prev_hash,
+    hash
 ) VALUES (
-    $1, $2, $3
-) RETURNING id, from_account_id, to_account_id, amount, created_at
+    $1, $2, $3, $4, $5, $6
+) RETURNING id, from_account_id, to_account_id, amount, created_at, prev_hash, hash
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	CreatedAt     time.Time `json:"created_at"`
+	PrevHash      string    `json:"prev_hash"`
+	Hash          string    `json:"hash"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.CreatedAt,
+		arg.PrevHash,
+		arg.Hash,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -34,22 +48,30 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.PrevHash,
+		&i.Hash,
 	)
 	return i, err
 }
 
-const deleteTransfer = `-- name: DeleteTransfer :exec
-DELETE FROM transfers
-WHERE id = $1
+const getLastTransferHash = `-- name: GetLastTransferHash :one
+SELECT COALESCE((
+    SELECT hash FROM transfers
+    WHERE from_account_id = $1
+    ORDER BY id DESC
+    LIMIT 1
+), '')::varchar
 `
 
-func (q *Queries) DeleteTransfer(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteTransfer, id)
-	return err
+func (q *Queries) GetLastTransferHash(ctx context.Context, fromAccountID int64) (string, error) {
+	row := q.db.QueryRowContext(ctx, getLastTransferHash, fromAccountID)
+	var column_1 string
+	err := row.Scan(&column_1)
+	return column_1, err
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, prev_hash, hash FROM transfers
 WHERE id = $1 LIMIT 1
 `
 
@@ -62,12 +84,14 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.PrevHash,
+		&i.Hash,
 	)
 	return i, err
 }
 
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, prev_hash, hash FROM transfers
 ORDER BY id
     LIMIT $1
 OFFSET $2
@@ -93,6 +117,52 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.PrevHash,
+			&i.Hash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransfersFromAccountAfter = `-- name: ListTransfersFromAccountAfter :many
+SELECT id, from_account_id, to_account_id, amount, created_at, prev_hash, hash FROM transfers
+WHERE from_account_id = $1 AND id > $2
+ORDER BY id
+LIMIT $3
+`
+
+type ListTransfersFromAccountAfterParams struct {
+	FromAccountID int64 `json:"from_account_id"`
+	ID            int64 `json:"id"`
+	Limit         int32 `json:"limit"`
+}
+
+func (q *Queries) ListTransfersFromAccountAfter(ctx context.Context, arg ListTransfersFromAccountAfterParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listTransfersFromAccountAfter, arg.FromAccountID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.PrevHash,
+			&i.Hash,
 		); err != nil {
 			return nil, err
 		}
@@ -10,22 +10,20 @@ import (
 )
 
 func createRandomTransfer(t *testing.T, account1, account2 Account) Transfer {
-	arg := CreateTransferParams{
-		FromAccountID: account1.ID,
-		ToAccountID:   account2.ID,
-		Amount:        util.RandomMoney(),
-	}
+	// the ledger rejects zero amounts
+	amount := util.RandomMoney() + 1
 
-	transfer, err := testQueries.CreateTransfer(context.Background(), arg)
+	transfer, err := createChainedTransfer(context.Background(), testQueries, account1.ID, account2.ID, amount)
 	require.NoError(t, err)
 	require.NotEmpty(t, transfer)
 
-	require.Equal(t, arg.FromAccountID, transfer.FromAccountID)
-	require.Equal(t, arg.ToAccountID, transfer.ToAccountID)
-	require.Equal(t, arg.Amount, transfer.Amount)
+	require.Equal(t, account1.ID, transfer.FromAccountID)
+	require.Equal(t, account2.ID, transfer.ToAccountID)
+	require.Equal(t, amount, transfer.Amount)
 
 	require.NotZero(t, transfer.ID)
 	require.NotZero(t, transfer.CreatedAt)
+	require.Equal(t, TransferHash(transfer), transfer.Hash)
 
 	return transfer
 }
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"strconv"
+)
+
+// CreateAccountTx creates an account, audits it and records an
+// AccountCreated event
+func (store *SqlStore) CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	var result Account
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = q.CreateAccount(ctx, arg)
+		if err != nil {
+			return err
+		}
+		_, err = recordAudit(ctx, q, AuditActionCreateAccount, AuditTargetAccount, strconv.FormatInt(result.ID, 10), nil, result)
+		if err != nil {
+			return err
+		}
+		return recordEvent(ctx, q, EventAccountCreated, AggregateAccount, result.ID, result)
+	})
+	return result, err
+}
+
+type UpdateAccountTxParams struct {
+	ID      int64  `json:"id"`
+	Owner   string `json:"owner"`
+	Balance int64  `json:"balance"`
+}
+
+// UpdateAccountTx changes an account's owner and corrects its balance with
+// an adjustment entry for the difference, audits the change, notifies its
+// listeners of the new balance and records an AccountUpdated event. It
+// returns sql.ErrNoRows if there is no such account.
+func (store *SqlStore) UpdateAccountTx(ctx context.Context, arg UpdateAccountTxParams) (Account, error) {
+	var result Account
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetAccountForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		result, err = q.UpdateAccountOwner(ctx, UpdateAccountOwnerParams{
+			ID:    arg.ID,
+			Owner: arg.Owner,
+		})
+		if err != nil {
+			return err
+		}
+
+		var entry *Entry
+		if adjustment := arg.Balance - before.Balance; adjustment != 0 {
+			var adjustmentEntry Entry
+			result, adjustmentEntry, err = createAdjustmentEntry(ctx, q, arg.ID, adjustment)
+			if err != nil {
+				return err
+			}
+			entry = &adjustmentEntry
+		}
+
+		_, err = recordAudit(ctx, q, AuditActionUpdateAccount, AuditTargetAccount, strconv.FormatInt(result.ID, 10), before, result)
+		if err != nil {
+			return err
+		}
+		err = notifyAccount(ctx, q, result, entry)
+		if err != nil {
+			return err
+		}
+		return recordEvent(ctx, q, EventAccountUpdated, AggregateAccount, result.ID, result)
+	})
+	return result, err
+}
+
+// DeleteAccountTx deletes an account, audits it and records an
+// AccountDeleted event carrying its last state. It returns sql.ErrNoRows if
+// there is no such account.
+func (store *SqlStore) DeleteAccountTx(ctx context.Context, id int64) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteAccount(ctx, id)
+		if err != nil {
+			return err
+		}
+		_, err = recordAudit(ctx, q, AuditActionDeleteAccount, AuditTargetAccount, strconv.FormatInt(account.ID, 10), account, nil)
+		if err != nil {
+			return err
+		}
+		return recordEvent(ctx, q, EventAccountDeleted, AggregateAccount, account.ID, account)
+	})
+}
+
+// SetAccountFrozenTx freezes or unfreezes an account, audits the change and
+// records an AccountFrozen or AccountUnfrozen event. It returns
+// sql.ErrNoRows if there is no such account.
+func (store *SqlStore) SetAccountFrozenTx(ctx context.Context, arg SetAccountFrozenParams) (Account, error) {
+	var result Account
+
+	eventType, action := EventAccountUnfrozen, AuditActionUnfreezeAccount
+	if arg.IsFrozen {
+		eventType, action = EventAccountFrozen, AuditActionFreezeAccount
+	}
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetAccountForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		result, err = q.SetAccountFrozen(ctx, arg)
+		if err != nil {
+			return err
+		}
+		_, err = recordAudit(ctx, q, action, AuditTargetAccount, strconv.FormatInt(result.ID, 10), before, result)
+		if err != nil {
+			return err
+		}
+		return recordEvent(ctx, q, eventType, AggregateAccount, result.ID, result)
+	})
+	return result, err
+}
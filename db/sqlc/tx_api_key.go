@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+)
+
+// CreateAPIKeyTx creates an API key and audits it. The encrypted secret is
+// left out of the audit log.
+func (store *SqlStore) CreateAPIKeyTx(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
+	var result ApiKey
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = q.CreateAPIKey(ctx, arg)
+		if err != nil {
+			return err
+		}
+		_, err = recordAudit(ctx, q, AuditActionCreateAPIKey, AuditTargetAPIKey, result.KeyID, nil, newAPIKeySnapshot(result))
+		return err
+	})
+	return result, err
+}
+
+// RevokeAPIKeyTx revokes an API key and audits it. It returns sql.ErrNoRows
+// if there is no such key.
+func (store *SqlStore) RevokeAPIKeyTx(ctx context.Context, keyID string) (ApiKey, error) {
+	var result ApiKey
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetAPIKey(ctx, keyID)
+		if err != nil {
+			return err
+		}
+
+		result, err = q.RevokeAPIKey(ctx, keyID)
+		if err != nil {
+			return err
+		}
+		_, err = recordAudit(ctx, q, AuditActionRevokeAPIKey, AuditTargetAPIKey, result.KeyID, newAPIKeySnapshot(before), newAPIKeySnapshot(result))
+		return err
+	})
+	return result, err
+}
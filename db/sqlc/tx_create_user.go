@@ -0,0 +1,22 @@
+package db
+
+import (
+	"context"
+)
+
+// CreateUserTx creates a user and audits it. The password hash is left out
+// of the audit log.
+func (store *SqlStore) CreateUserTx(ctx context.Context, arg CreateUserParams) (User, error) {
+	var result User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = q.CreateUser(ctx, arg)
+		if err != nil {
+			return err
+		}
+		_, err = recordAudit(ctx, q, AuditActionCreateUser, AuditTargetUser, result.Username, nil, newUserSnapshot(result))
+		return err
+	})
+	return result, err
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+)
+
+// CreateCurrencyTx creates a currency and audits it
+func (store *SqlStore) CreateCurrencyTx(ctx context.Context, arg CreateCurrencyParams) (Currency, error) {
+	var result Currency
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = q.CreateCurrency(ctx, arg)
+		if err != nil {
+			return err
+		}
+		_, err = recordAudit(ctx, q, AuditActionCreateCurrency, AuditTargetCurrency, result.Code, nil, result)
+		return err
+	})
+	return result, err
+}
+
+// SetCurrencyEnabledTx enables or disables a currency and audits the change.
+// It returns sql.ErrNoRows if there is no such currency.
+func (store *SqlStore) SetCurrencyEnabledTx(ctx context.Context, arg SetCurrencyEnabledParams) (Currency, error) {
+	var result Currency
+
+	action := AuditActionDisableCurrency
+	if arg.Enabled {
+		action = AuditActionEnableCurrency
+	}
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetCurrency(ctx, arg.Code)
+		if err != nil {
+			return err
+		}
+
+		result, err = q.SetCurrencyEnabled(ctx, arg)
+		if err != nil {
+			return err
+		}
+		_, err = recordAudit(ctx, q, action, AuditTargetCurrency, result.Code, before, result)
+		return err
+	})
+	return result, err
+}
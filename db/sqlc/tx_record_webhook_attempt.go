@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+)
+
+type RecordWebhookAttemptTxParams struct {
+	Delivery WebhookDelivery `json:"delivery"`
+	// StatusCode is the HTTP status of the response, or 0 if there was none
+	StatusCode int32  `json:"status_code"`
+	Error      string `json:"error"`
+	DurationMs int64  `json:"duration_ms"`
+	// Status is the delivery's status after this attempt
+	Status string `json:"status"`
+	// RetrySeconds is how long a pending delivery waits before its next attempt
+	RetrySeconds float64 `json:"retry_seconds"`
+}
+
+type RecordWebhookAttemptTxResult struct {
+	Delivery WebhookDelivery `json:"delivery"`
+	Attempt  WebhookAttempt  `json:"attempt"`
+}
+
+// RecordWebhookAttemptTx logs an attempt to deliver a webhook and moves the
+// delivery to its next status
+func (store *SqlStore) RecordWebhookAttemptTx(ctx context.Context, arg RecordWebhookAttemptTxParams) (RecordWebhookAttemptTxResult, error) {
+	var result RecordWebhookAttemptTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.Attempt, err = q.CreateWebhookAttempt(ctx, CreateWebhookAttemptParams{
+			DeliveryID: arg.Delivery.ID,
+			Attempt:    arg.Delivery.Attempts + 1,
+			StatusCode: arg.StatusCode,
+			Error:      arg.Error,
+			DurationMs: arg.DurationMs,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Delivery, err = q.UpdateWebhookDelivery(ctx, UpdateWebhookDeliveryParams{
+			ID:           arg.Delivery.ID,
+			Status:       arg.Status,
+			LastError:    arg.Error,
+			RetrySeconds: arg.RetrySeconds,
+		})
+		return err
+	})
+	return result, err
+}
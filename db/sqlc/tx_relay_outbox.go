@@ -0,0 +1,103 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+)
+
+type RelayOutboxTxParams struct {
+	// Limit is the most events relayed by one call
+	Limit int32 `json:"limit"`
+	// Lease is how long the relay may take to publish its batch before
+	// another relay can claim the events again
+	Lease time.Duration `json:"lease"`
+	// Publish delivers an event. The relay stops at the first event it fails
+	// to deliver, so that later events are never delivered before it.
+	Publish func(ctx context.Context, event Outbox) error `json:"-"`
+}
+
+type RelayOutboxTxResult struct {
+	// Sent is how many events were delivered and marked as sent
+	Sent int `json:"sent"`
+	// Pending is whether unsent events are left: the batch was full or
+	// delivery failed
+	Pending bool `json:"pending"`
+}
+
+// RelayOutboxTx claims the oldest unsent events, passes them to arg.Publish
+// in id order and marks the delivered ones as sent. An event is only
+// relayed once its transaction and every older one have ended, so that it is
+// never delivered ahead of an event an older transaction has yet to commit.
+//
+// The events are claimed for arg.Lease in one short transaction, published
+// outside of it, and marked as sent in a second one, so no lock is held
+// while publishing. No relay claims events while another holds a claim, so
+// one relay at a time delivers, in order. Publishing is cut off when the
+// lease runs out. An event delivered by a relay that fails before marking
+// it as sent is delivered again once its claim runs out, so delivery is at
+// least once.
+//
+// If Publish fails, the events delivered before it are still marked as sent,
+// the rest are released for the next call and the error is returned.
+func (store *SqlStore) RelayOutboxTx(ctx context.Context, arg RelayOutboxTxParams) (RelayOutboxTxResult, error) {
+	var events []Outbox
+	err := store.execTx(ctx, func(q *Queries) error {
+		err := q.LockOutboxClaims(ctx)
+		if err != nil {
+			return err
+		}
+		events, err = q.ClaimOutboxEvents(ctx, ClaimOutboxEventsParams{
+			LeaseSeconds: arg.Lease.Seconds(),
+			Limit:        arg.Limit,
+		})
+		return err
+	})
+	if err != nil || len(events) == 0 {
+		return RelayOutboxTxResult{}, err
+	}
+	slices.SortFunc(events, func(a, b Outbox) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	result := RelayOutboxTxResult{Pending: len(events) == int(arg.Limit)}
+	sent := make([]int64, 0, len(events))
+	publishCtx, cancel := context.WithTimeout(ctx, arg.Lease)
+	defer cancel()
+
+	var publishErr error
+	for _, event := range events {
+		publishErr = arg.Publish(publishCtx, event)
+		if publishErr != nil {
+			result.Pending = true
+			break
+		}
+		sent = append(sent, event.ID)
+	}
+	unsent := make([]int64, 0, len(events)-len(sent))
+	for _, event := range events[len(sent):] {
+		unsent = append(unsent, event.ID)
+	}
+
+	// the claim is settled even when ctx is done, so that the next relay
+	// neither waits for the lease nor delivers the sent events again
+	settleCtx := context.WithoutCancel(ctx)
+	err = store.execTx(settleCtx, func(q *Queries) error {
+		if len(sent) > 0 {
+			err := q.MarkOutboxEventsSent(settleCtx, sent)
+			if err != nil {
+				return err
+			}
+		}
+		if len(unsent) > 0 {
+			return q.ReleaseOutboxEvents(settleCtx, unsent)
+		}
+		return nil
+	})
+	if err != nil {
+		return RelayOutboxTxResult{}, err
+	}
+	result.Sent = len(sent)
+	return result, publishErr
+}
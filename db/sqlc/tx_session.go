@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// CreateSessionTx creates a login session and audits it. The refresh token
+// is left out of the audit log.
+func (store *SqlStore) CreateSessionTx(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	var result Session
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = q.CreateSession(ctx, arg)
+		if err != nil {
+			return err
+		}
+		_, err = recordAudit(ctx, q, AuditActionCreateSession, AuditTargetSession, result.ID.String(), nil, newSessionSnapshot(result))
+		return err
+	})
+	return result, err
+}
+
+// BlockSessionTx blocks a session and audits it. It returns sql.ErrNoRows
+// if there is no such session.
+func (store *SqlStore) BlockSessionTx(ctx context.Context, id uuid.UUID) (Session, error) {
+	var result Session
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetSession(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		result, err = q.BlockSession(ctx, id)
+		if err != nil {
+			return err
+		}
+		_, err = recordAudit(ctx, q, AuditActionRevokeSession, AuditTargetSession, result.ID.String(), newSessionSnapshot(before), newSessionSnapshot(result))
+		return err
+	})
+	return result, err
+}
+
+// BlockUserSessionsTx blocks every unblocked session of a user and audits
+// each of them
+func (store *SqlStore) BlockUserSessionsTx(ctx context.Context, username string) ([]Session, error) {
+	var result []Session
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = q.BlockUserSessions(ctx, username)
+		if err != nil {
+			return err
+		}
+		for _, session := range result {
+			before := session
+			before.IsBlocked = false
+			_, err = recordAudit(ctx, q, AuditActionRevokeSession, AuditTargetSession, session.ID.String(), newSessionSnapshot(before), newSessionSnapshot(session))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return result, err
+}
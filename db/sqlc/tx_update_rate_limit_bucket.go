@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type UpdateRateLimitBucketTxParams struct {
+	Key string `json:"key"`
+	// Initial is stored when the bucket does not exist yet
+	Initial RateLimitBucket `json:"initial"`
+	// Update computes the new state of the locked bucket
+	Update func(bucket RateLimitBucket) RateLimitBucket `json:"-"`
+}
+
+// UpdateRateLimitBucketTx locks the bucket for arg.Key, creating it from
+// arg.Initial when it is missing, and stores the state returned by
+// arg.Update. Concurrent callers for the same key are serialized by the row
+// lock, so instances sharing the database share the bucket.
+func (store *SqlStore) UpdateRateLimitBucketTx(ctx context.Context, arg UpdateRateLimitBucketTxParams) (RateLimitBucket, error) {
+	var result RateLimitBucket
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		bucket, err := q.GetRateLimitBucketForUpdate(ctx, arg.Key)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = q.CreateRateLimitBucket(ctx, CreateRateLimitBucketParams{
+				Key:       arg.Key,
+				Tokens:    arg.Initial.Tokens,
+				UpdatedAt: arg.Initial.UpdatedAt,
+				FullAt:    arg.Initial.FullAt,
+			})
+			if err != nil {
+				return err
+			}
+			// another instance may have created the bucket first
+			bucket, err = q.GetRateLimitBucketForUpdate(ctx, arg.Key)
+		}
+		if err != nil {
+			return err
+		}
+
+		result = arg.Update(bucket)
+		result.Key = arg.Key
+		return q.UpdateRateLimitBucket(ctx, UpdateRateLimitBucketParams{
+			Key:       result.Key,
+			Tokens:    result.Tokens,
+			UpdatedAt: result.UpdatedAt,
+			FullAt:    result.FullAt,
+		})
+	})
+	return result, err
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type UpdateUserRoleTxParams struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+type UpdateUserRoleTxResult struct {
+	User     User     `json:"user"`
+	AuditLog AuditLog `json:"audit_log"`
+}
+
+// UpdateUserRoleTx changes the role of a user and records the change
+// in the audit log within the same transaction
+func (store *SqlStore) UpdateUserRoleTx(ctx context.Context, arg UpdateUserRoleTxParams) (UpdateUserRoleTxResult, error) {
+	ctx, span := tracer.Start(ctx, "db.UpdateUserRoleTx", trace.WithAttributes(
+		attribute.String("bank.username", arg.Username),
+		attribute.String("bank.role", arg.Role),
+	))
+	defer span.End()
+
+	var result UpdateUserRoleTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetUser(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.UpdateUserRole(ctx, UpdateUserRoleParams{
+			Username: arg.Username,
+			Role:     arg.Role,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.AuditLog, err = recordAudit(ctx, q, AuditActionUpdateUserRole, AuditTargetUser, arg.Username,
+			map[string]string{"role": before.Role},
+			map[string]string{"role": result.User.Role},
+		)
+		return err
+	})
+
+	recordSpanError(span, err)
+	return result, err
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"strconv"
+)
+
+// CreateWebhookTx creates a webhook and audits it. The secret is left out of
+// the audit log.
+func (store *SqlStore) CreateWebhookTx(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	var result Webhook
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = q.CreateWebhook(ctx, arg)
+		if err != nil {
+			return err
+		}
+		_, err = recordAudit(ctx, q, AuditActionCreateWebhook, AuditTargetWebhook, strconv.FormatInt(result.ID, 10), nil, newWebhookSnapshot(result))
+		return err
+	})
+	return result, err
+}
+
+// SetWebhookActiveTx enables or disables a webhook and audits the change. It
+// returns sql.ErrNoRows if there is no such webhook.
+func (store *SqlStore) SetWebhookActiveTx(ctx context.Context, arg SetWebhookActiveParams) (Webhook, error) {
+	var result Webhook
+
+	action := AuditActionDisableWebhook
+	if arg.IsActive {
+		action = AuditActionEnableWebhook
+	}
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetWebhook(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		result, err = q.SetWebhookActive(ctx, arg)
+		if err != nil {
+			return err
+		}
+		_, err = recordAudit(ctx, q, action, AuditTargetWebhook, strconv.FormatInt(result.ID, 10), newWebhookSnapshot(before), newWebhookSnapshot(result))
+		return err
+	})
+	return result, err
+}
+
+// RetryWebhookDeliveryTx queues a dead delivery for a full set of attempts and
+// audits it. It returns sql.ErrNoRows if there is no such delivery or it is
+// not dead.
+func (store *SqlStore) RetryWebhookDeliveryTx(ctx context.Context, id int64) (WebhookDelivery, error) {
+	var result WebhookDelivery
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		before, err := q.GetWebhookDelivery(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		result, err = q.RetryWebhookDelivery(ctx, id)
+		if err != nil {
+			return err
+		}
+		_, err = recordAudit(ctx, q, AuditActionRetryWebhookDelivery, AuditTargetWebhookDelivery, strconv.FormatInt(result.ID, 10), newWebhookDeliverySnapshot(before), newWebhookDeliverySnapshot(result))
+		return err
+	})
+	return result, err
+}
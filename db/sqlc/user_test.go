@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateUser(t *testing.T) {
+	createRandomUser(t)
+}
+
+func TestCreateUserDuplicate(t *testing.T) {
+	user1 := createRandomUser(t)
+
+	arg := CreateUserParams{
+		Username:       user1.Username,
+		HashedPassword: user1.HashedPassword,
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	}
+
+	user2, err := testQueries.CreateUser(context.Background(), arg)
+	require.Error(t, err)
+	require.Empty(t, user2)
+
+	var pqErr *pq.Error
+	require.ErrorAs(t, err, &pqErr)
+	require.Equal(t, "unique_violation", pqErr.Code.Name())
+}
+
+func TestGetUser(t *testing.T) {
+	user1 := createRandomUser(t)
+	user2, err := testQueries.GetUser(context.Background(), user1.Username)
+	require.NoError(t, err)
+	require.NotEmpty(t, user2)
+
+	require.Equal(t, user1.Username, user2.Username)
+	require.Equal(t, user1.HashedPassword, user2.HashedPassword)
+	require.Equal(t, user1.FullName, user2.FullName)
+	require.Equal(t, user1.Email, user2.Email)
+	require.WithinDuration(t, user1.PasswordChangedAt, user2.PasswordChangedAt, time.Second)
+	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
+}
+
+func TestGetUserNotFound(t *testing.T) {
+	user, err := testQueries.GetUser(context.Background(), util.RandomOwner())
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+	require.Empty(t, user)
+}
+
+func createRandomUser(t *testing.T) User {
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	arg := CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: hashedPassword,
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	}
+
+	user, err := testQueries.CreateUser(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, user)
+
+	require.Equal(t, arg.Username, user.Username)
+	require.Equal(t, arg.HashedPassword, user.HashedPassword)
+	require.Equal(t, arg.FullName, user.FullName)
+	require.Equal(t, arg.Email, user.Email)
+	require.Equal(t, util.CustomerRole, user.Role)
+	require.True(t, user.PasswordChangedAt.IsZero())
+	require.NotZero(t, user.CreatedAt)
+
+	return user
+}
+
+func TestUpdateUserRoleTx(t *testing.T) {
+	store := NewSqlStore(testDB)
+	admin := createRandomUser(t)
+	user := createRandomUser(t)
+
+	ctx := util.WithRequestID(context.Background(), "req-role")
+	ctx = WithAuditActor(ctx, AuditActor{Name: admin.Username, IP: "10.0.0.1"})
+	result, err := store.UpdateUserRoleTx(ctx, UpdateUserRoleTxParams{
+		Username: user.Username,
+		Role:     util.OperatorRole,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.OperatorRole, result.User.Role)
+
+	auditLog := result.AuditLog
+	require.NotZero(t, auditLog.ID)
+	require.Equal(t, admin.Username, auditLog.Actor)
+	require.Equal(t, AuditActionUpdateUserRole, auditLog.Action)
+	require.Equal(t, "user", auditLog.TargetType)
+	require.Equal(t, user.Username, auditLog.TargetID)
+	require.JSONEq(t, `{"role": "customer"}`, string(auditLog.Before))
+	require.JSONEq(t, `{"role": "operator"}`, string(auditLog.After))
+	require.Equal(t, "req-role", auditLog.RequestID)
+	require.Equal(t, "10.0.0.1", auditLog.Ip)
+
+	updated, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, util.OperatorRole, updated.Role)
+}
+
+func TestUpdateUserRoleTxNotFound(t *testing.T) {
+	store := NewSqlStore(testDB)
+
+	_, err := store.UpdateUserRoleTx(context.Background(), UpdateUserRoleTxParams{
+		Username: util.RandomOwner(),
+		Role:     util.AdminRole,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
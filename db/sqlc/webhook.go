@@ -0,0 +1,19 @@
+package db
+
+// statuses of a webhook delivery
+const (
+	// DeliveryPending deliveries are attempted once next_attempt_at has passed
+	DeliveryPending = "pending"
+	// DeliverySucceeded deliveries got a 2xx response
+	DeliverySucceeded = "succeeded"
+	// DeliveryDead deliveries ran out of attempts and are only retried on
+	// request
+	DeliveryDead = "dead"
+)
+
+// WebhookSecretLabel is the label a webhook's secret is sealed under with
+// util.SecretBox, so that the ciphertext only opens for a webhook that
+// delivers to the same url
+func WebhookSecretLabel(url string) string {
+	return "webhook:" + url
+}
@@ -0,0 +1,222 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// createRandomWebhook subscribes to a random event type, so that other tests
+// never queue deliveries for it
+func createRandomWebhook(t *testing.T) Webhook {
+	user := createRandomUser(t)
+
+	arg := CreateWebhookParams{
+		Url:              "https://" + util.RandomString(8) + ".example/hooks",
+		SecretCiphertext: util.RandomString(32),
+		EventTypes:       []string{util.RandomString(12)},
+		CreatedBy:        user.Username,
+	}
+
+	webhook, err := testQueries.CreateWebhook(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, webhook.ID)
+	require.Equal(t, arg.Url, webhook.Url)
+	require.Equal(t, arg.SecretCiphertext, webhook.SecretCiphertext)
+	require.Equal(t, arg.EventTypes, webhook.EventTypes)
+	require.Equal(t, arg.CreatedBy, webhook.CreatedBy)
+	require.True(t, webhook.IsActive)
+	require.NotZero(t, webhook.CreatedAt)
+
+	return webhook
+}
+
+func createRandomWebhookDelivery(t *testing.T, webhook Webhook) WebhookDelivery {
+	event, err := testQueries.CreateOutboxEvent(context.Background(), CreateOutboxEventParams{
+		EventType:     webhook.EventTypes[0],
+		AggregateType: AggregateAccount,
+		AggregateID:   "1",
+		Payload:       json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+
+	arg := CreateWebhookDeliveryParams{
+		WebhookID: webhook.ID,
+		EventID:   event.ID,
+		EventType: event.EventType,
+		Payload:   json.RawMessage(`{"id":1}`),
+	}
+	require.NoError(t, testQueries.CreateWebhookDelivery(context.Background(), arg))
+
+	// queuing the same event again is a no-op
+	require.NoError(t, testQueries.CreateWebhookDelivery(context.Background(), arg))
+
+	deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		WebhookID: webhook.ID,
+		Limit:     100,
+	})
+	require.NoError(t, err)
+	for _, delivery := range deliveries {
+		if delivery.EventID == event.ID {
+			require.Equal(t, DeliveryPending, delivery.Status)
+			require.Zero(t, delivery.Attempts)
+			return delivery
+		}
+	}
+	t.Fatal("delivery not found")
+	return WebhookDelivery{}
+}
+
+func TestGetWebhook(t *testing.T) {
+	webhook1 := createRandomWebhook(t)
+
+	webhook2, err := testQueries.GetWebhook(context.Background(), webhook1.ID)
+	require.NoError(t, err)
+	require.Equal(t, webhook1.Url, webhook2.Url)
+	require.Equal(t, webhook1.EventTypes, webhook2.EventTypes)
+
+	_, err = testQueries.GetWebhook(context.Background(), -1)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestListWebhooksForEvent(t *testing.T) {
+	webhook := createRandomWebhook(t)
+	eventType := webhook.EventTypes[0]
+
+	webhooks, err := testQueries.ListWebhooksForEvent(context.Background(), eventType)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	require.Equal(t, webhook.ID, webhooks[0].ID)
+
+	disabled, err := testQueries.SetWebhookActive(context.Background(), SetWebhookActiveParams{ID: webhook.ID, IsActive: false})
+	require.NoError(t, err)
+	require.False(t, disabled.IsActive)
+
+	webhooks, err = testQueries.ListWebhooksForEvent(context.Background(), eventType)
+	require.NoError(t, err)
+	require.Empty(t, webhooks)
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	webhook := createRandomWebhook(t)
+	delivery1 := createRandomWebhookDelivery(t, webhook)
+	delivery2 := createRandomWebhookDelivery(t, webhook)
+
+	deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		WebhookID: webhook.ID,
+		Limit:     5,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	require.Equal(t, delivery2.ID, deliveries[0].ID)
+	require.Equal(t, delivery1.ID, deliveries[1].ID)
+
+	_, err = testQueries.UpdateWebhookDelivery(context.Background(), UpdateWebhookDeliveryParams{
+		ID:     delivery1.ID,
+		Status: DeliveryDead,
+	})
+	require.NoError(t, err)
+
+	deliveries, err = testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		WebhookID: webhook.ID,
+		Status:    sql.NullString{String: DeliveryDead, Valid: true},
+		Limit:     5,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, delivery1.ID, deliveries[0].ID)
+}
+
+func claimedIDs(t *testing.T, leaseSeconds float64) map[int64]WebhookDelivery {
+	deliveries, err := testQueries.ClaimWebhookDeliveries(context.Background(), ClaimWebhookDeliveriesParams{
+		LeaseSeconds: leaseSeconds,
+		Limit:        1000,
+	})
+	require.NoError(t, err)
+
+	claimed := make(map[int64]WebhookDelivery, len(deliveries))
+	for _, delivery := range deliveries {
+		claimed[delivery.ID] = delivery
+	}
+	return claimed
+}
+
+func TestClaimWebhookDeliveries(t *testing.T) {
+	webhook := createRandomWebhook(t)
+	delivery := createRandomWebhookDelivery(t, webhook)
+
+	claimed := claimedIDs(t, 60)
+	require.Contains(t, claimed, delivery.ID)
+	require.WithinDuration(t, time.Now().Add(time.Minute), claimed[delivery.ID].NextAttemptAt, 5*time.Second)
+
+	// a claimed delivery is not due until its lease runs out
+	require.NotContains(t, claimedIDs(t, 60), delivery.ID)
+
+	// deliveries of a disabled webhook wait until it is enabled again
+	delivery2 := createRandomWebhookDelivery(t, webhook)
+	_, err := testQueries.SetWebhookActive(context.Background(), SetWebhookActiveParams{ID: webhook.ID, IsActive: false})
+	require.NoError(t, err)
+	require.NotContains(t, claimedIDs(t, 60), delivery2.ID)
+
+	_, err = testQueries.SetWebhookActive(context.Background(), SetWebhookActiveParams{ID: webhook.ID, IsActive: true})
+	require.NoError(t, err)
+	require.Contains(t, claimedIDs(t, 60), delivery2.ID)
+}
+
+func TestRecordWebhookAttemptTx(t *testing.T) {
+	store := NewSqlStore(testDB)
+	webhook := createRandomWebhook(t)
+	delivery := createRandomWebhookDelivery(t, webhook)
+
+	result, err := store.RecordWebhookAttemptTx(context.Background(), RecordWebhookAttemptTxParams{
+		Delivery:     delivery,
+		StatusCode:   500,
+		Error:        "unexpected status 500",
+		DurationMs:   12,
+		Status:       DeliveryPending,
+		RetrySeconds: 30,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), result.Attempt.Attempt)
+	require.Equal(t, int32(500), result.Attempt.StatusCode)
+	require.Equal(t, DeliveryPending, result.Delivery.Status)
+	require.Equal(t, int32(1), result.Delivery.Attempts)
+	require.Equal(t, "unexpected status 500", result.Delivery.LastError)
+	require.WithinDuration(t, time.Now().Add(30*time.Second), result.Delivery.NextAttemptAt, 5*time.Second)
+
+	result, err = store.RecordWebhookAttemptTx(context.Background(), RecordWebhookAttemptTxParams{
+		Delivery: result.Delivery,
+		Error:    "connection refused",
+		Status:   DeliveryDead,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), result.Attempt.Attempt)
+	require.Equal(t, DeliveryDead, result.Delivery.Status)
+
+	attempts, err := testQueries.ListWebhookAttempts(context.Background(), delivery.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	require.Equal(t, int32(500), attempts[0].StatusCode)
+	require.Zero(t, attempts[1].StatusCode)
+	require.Equal(t, "connection refused", attempts[1].Error)
+
+	// only a dead delivery can be retried, and a retry starts its attempts
+	// over while keeping their history
+	retried, err := testQueries.RetryWebhookDelivery(context.Background(), delivery.ID)
+	require.NoError(t, err)
+	require.Equal(t, DeliveryPending, retried.Status)
+	require.Zero(t, retried.Attempts)
+	require.Empty(t, retried.LastError)
+
+	attempts, err = testQueries.ListWebhookAttempts(context.Background(), delivery.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+
+	_, err = testQueries.RetryWebhookDelivery(context.Background(), delivery.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,84 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/pb"
+	"github.com/julkar-naim/simple-bank/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"strings"
+)
+
+const (
+	authorizationHeaderKey  = "authorization"
+	authorizationTypeBearer = "bearer"
+)
+
+type authPayloadKey struct{}
+
+// authInterceptor rejects SimpleBank calls without a valid bearer token in the
+// metadata and stores the token payload in the context. Other services, such
+// as reflection, pass through.
+func (server *Server) authInterceptor(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if !strings.HasPrefix(info.FullMethod, "/"+pb.SimpleBank_ServiceDesc.ServiceName+"/") {
+		return handler(ctx, req)
+	}
+
+	payload, err := server.authorizeUser(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "unauthorized: %s", err)
+	}
+	ctx = context.WithValue(ctx, authPayloadKey{}, payload)
+	return handler(db.WithAuditActor(ctx, auditActor(ctx, payload.Username)), req)
+}
+
+// auditActor identifies the caller and their address for the audit log
+func auditActor(ctx context.Context, username string) db.AuditActor {
+	actor := db.AuditActor{Name: username}
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			actor.IP = host
+		}
+	}
+	return actor
+}
+
+func (server *Server) authorizeUser(ctx context.Context) (*token.Payload, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("missing metadata")
+	}
+
+	values := md.Get(authorizationHeaderKey)
+	if len(values) == 0 {
+		return nil, fmt.Errorf("authorization header is not provided")
+	}
+
+	fields := strings.Fields(values[0])
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid authorization header format")
+	}
+
+	authorizationType := strings.ToLower(fields[0])
+	if authorizationType != authorizationTypeBearer {
+		return nil, fmt.Errorf("unsupported authorization type %s", authorizationType)
+	}
+
+	return server.tokenMaker.VerifyToken(fields[1], token.TokenTypeAccessToken)
+}
+
+// authPayload returns the payload stored by authInterceptor
+func authPayload(ctx context.Context) *token.Payload {
+	return ctx.Value(authPayloadKey{}).(*token.Payload)
+}
@@ -0,0 +1,33 @@
+package gapi
+
+import (
+	"github.com/julkar-naim/simple-bank/apperr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var grpcCodes = map[apperr.Code]codes.Code{
+	apperr.CodeInvalidArgument:    codes.InvalidArgument,
+	apperr.CodeUnauthenticated:    codes.Unauthenticated,
+	apperr.CodePermissionDenied:   codes.PermissionDenied,
+	apperr.CodeNotFound:           codes.NotFound,
+	apperr.CodeAlreadyExists:      codes.AlreadyExists,
+	apperr.CodeConflict:           codes.Aborted,
+	apperr.CodeFailedPrecondition: codes.FailedPrecondition,
+	apperr.CodeRateLimited:        codes.ResourceExhausted,
+	apperr.CodeCanceled:           codes.Canceled,
+	apperr.CodeInternal:           codes.Internal,
+	apperr.CodeUnavailable:        codes.Unavailable,
+	apperr.CodeDeadlineExceeded:   codes.DeadlineExceeded,
+}
+
+// storeError translates a store error with apperr, the same way the HTTP
+// API does, and reports it as a gRPC status with the client-safe message
+func storeError(err error) error {
+	appErr := apperr.Translate(err, "")
+	code, ok := grpcCodes[appErr.Code]
+	if !ok {
+		code = codes.Internal
+	}
+	return status.Error(code, appErr.Message)
+}
@@ -0,0 +1,38 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestStoreError(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		code codes.Code
+	}{
+		{"Canceled", fmt.Errorf("tx: %w", context.Canceled), codes.Canceled},
+		{"DeadlineExceeded", context.DeadlineExceeded, codes.DeadlineExceeded},
+		{"QueryCanceled", &pq.Error{Code: "57014"}, codes.DeadlineExceeded},
+		{"NotFound", sql.ErrNoRows, codes.NotFound},
+		{"UniqueViolation", &pq.Error{Code: "23505"}, codes.AlreadyExists},
+		{"ForeignKeyViolation", &pq.Error{Code: "23503"}, codes.FailedPrecondition},
+		{"SerializationFailure", &pq.Error{Code: "40001"}, codes.Aborted},
+		{"Internal", errors.New("boom"), codes.Internal},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := storeError(tc.err)
+			require.Equal(t, tc.code, status.Code(err))
+			require.NotContains(t, status.Convert(err).Message(), "boom")
+		})
+	}
+}
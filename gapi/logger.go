@@ -0,0 +1,52 @@
+package gapi
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/julkar-naim/simple-bank/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"time"
+)
+
+const requestIDHeaderKey = "x-request-id"
+
+// loggerInterceptor takes the request ID from the x-request-id metadata or
+// generates one, stores it in the context and writes one structured record
+// per call
+func (server *Server) loggerInterceptor(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDHeaderKey); len(values) > 0 && len(values[0]) <= 128 {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+	ctx = util.WithRequestID(ctx, requestID)
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDHeaderKey, requestID))
+
+	startTime := time.Now()
+	result, err := handler(ctx, req)
+
+	level := slog.LevelInfo
+	attrs := []slog.Attr{
+		slog.String("method", info.FullMethod),
+		slog.String("code", status.Code(err).String()),
+		slog.Duration("duration", time.Since(startTime)),
+	}
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	server.logger.LogAttrs(ctx, level, "grpc request", attrs...)
+	return result, err
+}
@@ -0,0 +1,83 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/pb"
+	"github.com/julkar-naim/simple-bank/ratelimit"
+	"github.com/julkar-naim/simple-bank/token"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+	"time"
+)
+
+func testConfig() util.Config {
+	return util.Config{
+		TokenType:           token.TypePasetoV4,
+		TokenSymmetricKey:   util.RandomString(32),
+		AccessTokenDuration: time.Minute,
+	}
+}
+
+// newTestClient serves the store over an in-memory bufconn listener and
+// returns a client connected to it
+func newTestClient(t *testing.T, store db.Store) (pb.SimpleBankClient, *Server) {
+	server, err := NewServer(testConfig(), store, ratelimit.NewMemoryStore())
+	require.NoError(t, err)
+
+	return pb.NewSimpleBankClient(newTestConn(t, server)), server
+}
+
+func newTestConn(t *testing.T, server *Server) *grpc.ClientConn {
+	listener := bufconn.Listen(1024 * 1024)
+	go server.grpcServer.Serve(listener)
+	t.Cleanup(server.grpcServer.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func newContextWithBearerToken(t *testing.T, tokenMaker token.Maker, username string, duration time.Duration) context.Context {
+	accessToken, _, err := tokenMaker.CreateToken(username, util.CustomerRole, token.TokenTypeAccessToken, duration)
+	require.NoError(t, err)
+
+	bearerToken := fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken)
+	md := metadata.MD{
+		authorizationHeaderKey: []string{bearerToken},
+	}
+	return metadata.NewOutgoingContext(context.Background(), md)
+}
+
+func randomAccount(owner string) db.Account {
+	return db.Account{
+		ID:       util.RandomInt(1000) + 1,
+		Owner:    owner,
+		Balance:  util.RandomMoney(),
+		Currency: util.RandomCurrency(),
+	}
+}
+
+func requireAccountMatch(t *testing.T, account db.Account, pbAccount *pb.Account) {
+	require.NotNil(t, pbAccount)
+	require.Equal(t, account.ID, pbAccount.GetId())
+	require.Equal(t, account.Owner, pbAccount.GetOwner())
+	require.Equal(t, account.Balance, pbAccount.GetBalance())
+	require.Equal(t, account.Currency, pbAccount.GetCurrency())
+	require.Equal(t, account.IsFrozen, pbAccount.GetIsFrozen())
+}
@@ -0,0 +1,101 @@
+package gapi
+
+import (
+	"context"
+	"github.com/julkar-naim/simple-bank/pb"
+	"github.com/julkar-naim/simple-bank/ratelimit"
+	"github.com/julkar-naim/simple-bank/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	rateLimitLimitHeaderKey     = "ratelimit-limit"
+	rateLimitRemainingHeaderKey = "ratelimit-remaining"
+	rateLimitResetHeaderKey     = "ratelimit-reset"
+	retryAfterHeaderKey         = "retry-after"
+)
+
+// rateLimitRoutes maps the methods that have an HTTP counterpart to the
+// route of that counterpart, so a caller shares one bucket, and one
+// configured limit, whichever API they call
+var rateLimitRoutes = map[string]string{
+	pb.SimpleBank_CreateAccount_FullMethodName: ratelimit.RouteKey(http.MethodPost, "/accounts"),
+	pb.SimpleBank_GetAccount_FullMethodName:    ratelimit.RouteKey(http.MethodGet, "/accounts/:id"),
+	pb.SimpleBank_ListAccounts_FullMethodName:  ratelimit.RouteKey(http.MethodGet, "/accounts"),
+	pb.SimpleBank_Transfer_FullMethodName:      ratelimit.RouteKey(http.MethodPost, "/transfers"),
+}
+
+// rateLimitRoute returns the route whose limit and bucket apply to a method.
+// Methods without an HTTP counterpart are keyed "GRPC <full method>".
+func rateLimitRoute(fullMethod string) string {
+	if route, ok := rateLimitRoutes[fullMethod]; ok {
+		return route
+	}
+	return ratelimit.RouteKey("GRPC", fullMethod)
+}
+
+// rateLimitInterceptor takes a token from the bucket of the caller for the
+// called method, with the same limits and bucket keys as the HTTP rate limit
+// middleware. Callers are identified by user, then peer IP, so it must run
+// after authInterceptor. When the store fails the call is let through.
+func (server *Server) rateLimitInterceptor(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	route := rateLimitRoute(info.FullMethod)
+	limit, ok := server.routeRateLimits[route]
+	if !ok {
+		limit = server.defaultRateLimit
+	}
+	if limit.Unlimited() {
+		return handler(ctx, req)
+	}
+
+	result, err := server.rateLimitStore.Take(ctx, route+"|"+rateLimitIdentity(ctx), limit)
+	if err != nil {
+		server.logger.WarnContext(ctx, "rate limiter unavailable", "route", route, "error", err)
+		return handler(ctx, req)
+	}
+
+	md := metadata.Pairs(
+		rateLimitLimitHeaderKey, strconv.Itoa(result.Limit),
+		rateLimitRemainingHeaderKey, strconv.Itoa(result.Remaining),
+		rateLimitResetHeaderKey, ceilSeconds(result.Reset),
+	)
+	if !result.Allowed {
+		md.Set(retryAfterHeaderKey, ceilSeconds(result.RetryAfter))
+		grpc.SetHeader(ctx, md)
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	grpc.SetHeader(ctx, md)
+	return handler(ctx, req)
+}
+
+// rateLimitIdentity returns the key of the caller whose bucket is used
+func rateLimitIdentity(ctx context.Context) string {
+	if payload, ok := ctx.Value(authPayloadKey{}).(*token.Payload); ok {
+		return "user:" + payload.Username
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return "ip:" + host
+		}
+		return "ip:" + p.Addr.String()
+	}
+	return "ip:"
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
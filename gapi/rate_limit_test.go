@@ -0,0 +1,80 @@
+package gapi
+
+import (
+	"context"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	"github.com/julkar-naim/simple-bank/pb"
+	"github.com/julkar-naim/simple-bank/ratelimit"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestRateLimitInterceptor(t *testing.T) {
+	account := randomAccount("alice")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(account, nil)
+
+	config := testConfig()
+	config.RateLimitRoutes = "GET /accounts/:id=1/m"
+	server, err := NewServer(config, store, ratelimit.NewMemoryStore())
+	require.NoError(t, err)
+	client := pb.NewSimpleBankClient(newTestConn(t, server))
+	ctx := newContextWithBearerToken(t, server.tokenMaker, account.Owner, time.Minute)
+
+	var header metadata.MD
+	_, err = client.GetAccount(ctx, &pb.GetAccountRequest{Id: account.ID}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, []string{"1"}, header.Get(rateLimitLimitHeaderKey))
+	require.Equal(t, []string{"0"}, header.Get(rateLimitRemainingHeaderKey))
+	require.Equal(t, []string{"60"}, header.Get(rateLimitResetHeaderKey))
+
+	_, err = client.GetAccount(ctx, &pb.GetAccountRequest{Id: account.ID}, grpc.Header(&header))
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, []string{"60"}, header.Get(retryAfterHeaderKey))
+
+	// another user has a bucket of their own
+	ctx = newContextWithBearerToken(t, server.tokenMaker, "bob", time.Minute)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(account, nil)
+	_, err = client.GetAccount(ctx, &pb.GetAccountRequest{Id: account.ID})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestRateLimitInterceptorSharesHTTPBuckets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the bucket of alice is already spent through the HTTP API
+	limit := ratelimit.Limit{Rate: 1.0 / 60, Burst: 1}
+	rateLimitStore := ratelimit.NewMemoryStore()
+	_, err := rateLimitStore.Take(context.Background(), "POST /transfers|user:alice", limit)
+	require.NoError(t, err)
+
+	config := testConfig()
+	config.RateLimitRoutes = "POST /transfers=1/m"
+	server, err := NewServer(config, mockdb.NewMockStore(ctrl), rateLimitStore)
+	require.NoError(t, err)
+	client := pb.NewSimpleBankClient(newTestConn(t, server))
+	ctx := newContextWithBearerToken(t, server.tokenMaker, "alice", time.Minute)
+
+	_, err = client.Transfer(ctx, &pb.TransferRequest{FromAccountId: 1, ToAccountId: 2, Amount: 10, Currency: "USD"})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestRateLimitRoute(t *testing.T) {
+	require.Equal(t, "POST /transfers", rateLimitRoute(pb.SimpleBank_Transfer_FullMethodName))
+	require.Equal(t, "GRPC /pb.SimpleBank/ListEntries", rateLimitRoute(pb.SimpleBank_ListEntries_FullMethodName))
+}
@@ -0,0 +1,95 @@
+package gapi
+
+import (
+	"context"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/pb"
+	"github.com/julkar-naim/simple-bank/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (server *Server) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
+	if !util.IsSupportedCurrency(req.GetCurrency()) {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported currency %q", req.GetCurrency())
+	}
+
+	arg := db.CreateAccountParams{
+		Owner:    authPayload(ctx).Username,
+		Currency: req.GetCurrency(),
+		Balance:  0,
+	}
+
+	account, err := server.store.CreateAccountTx(ctx, arg)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	rsp := &pb.CreateAccountResponse{
+		Account: convertAccount(account),
+	}
+	return rsp, nil
+}
+
+func (server *Server) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountResponse, error) {
+	if req.GetId() < 1 {
+		return nil, status.Error(codes.InvalidArgument, "id must be positive")
+	}
+
+	account, err := server.ownedAccount(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := &pb.GetAccountResponse{
+		Account: convertAccount(account),
+	}
+	return rsp, nil
+}
+
+func (server *Server) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	if err := validatePage(req.GetPageId(), req.GetPageSize(), 10); err != nil {
+		return nil, err
+	}
+
+	arg := db.ListAccountsParams{
+		Owner:  authPayload(ctx).Username,
+		Limit:  req.GetPageSize(),
+		Offset: (req.GetPageId() - 1) * req.GetPageSize(),
+	}
+
+	accounts, err := server.store.ListAccounts(ctx, arg)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	rsp := &pb.ListAccountsResponse{}
+	for _, account := range accounts {
+		rsp.Accounts = append(rsp.Accounts, convertAccount(account))
+	}
+	return rsp, nil
+}
+
+// ownedAccount loads the account and checks it belongs to the authenticated user
+func (server *Server) ownedAccount(ctx context.Context, accountID int64) (db.Account, error) {
+	account, err := server.store.GetAccount(ctx, accountID)
+	if err != nil {
+		return account, storeError(err)
+	}
+
+	if account.Owner != authPayload(ctx).Username {
+		return account, status.Error(codes.PermissionDenied, "account doesn't belong to the authenticated user")
+	}
+	return account, nil
+}
+
+// validatePage checks paging arguments against the same bounds as the HTTP API
+func validatePage(pageID, pageSize, maxPageSize int32) error {
+	if pageID < 1 {
+		return status.Error(codes.InvalidArgument, "page_id must be at least 1")
+	}
+	if pageSize < 5 || pageSize > maxPageSize {
+		return status.Errorf(codes.InvalidArgument, "page_size must be between 5 and %d", maxPageSize)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package gapi
+
+import (
+	"context"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (server *Server) ListEntries(ctx context.Context, req *pb.ListEntriesRequest) (*pb.ListEntriesResponse, error) {
+	if req.GetAccountId() < 1 {
+		return nil, status.Error(codes.InvalidArgument, "account_id must be positive")
+	}
+	if err := validatePage(req.GetPageId(), req.GetPageSize(), 100); err != nil {
+		return nil, err
+	}
+
+	if _, err := server.ownedAccount(ctx, req.GetAccountId()); err != nil {
+		return nil, err
+	}
+
+	arg := db.ListEntriesParams{
+		AccountID: req.GetAccountId(),
+		Limit:     req.GetPageSize(),
+		Offset:    (req.GetPageId() - 1) * req.GetPageSize(),
+	}
+
+	entries, err := server.store.ListEntries(ctx, arg)
+	if err != nil {
+		return nil, storeError(err)
+	}
+
+	rsp := &pb.ListEntriesResponse{}
+	for _, entry := range entries {
+		rsp.Entries = append(rsp.Entries, convertEntry(entry))
+	}
+	return rsp, nil
+}
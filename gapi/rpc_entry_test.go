@@ -0,0 +1,87 @@
+package gapi
+
+import (
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/pb"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestListEntriesRPC(t *testing.T) {
+	account := randomAccount("alice")
+	entries := []db.Entry{
+		{ID: 1, AccountID: account.ID, Amount: util.RandomMoney()},
+		{ID: 2, AccountID: account.ID, Amount: -util.RandomMoney()},
+	}
+
+	testCases := []struct {
+		name          string
+		req           *pb.ListEntriesRequest
+		username      string
+		buildStub     func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, rsp *pb.ListEntriesResponse, err error)
+	}{
+		{
+			"OK",
+			&pb.ListEntriesRequest{AccountId: account.ID, PageId: 1, PageSize: 5},
+			account.Owner,
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				arg := db.ListEntriesParams{AccountID: account.ID, Limit: 5, Offset: 0}
+				store.EXPECT().ListEntries(gomock.Any(), gomock.Eq(arg)).Times(1).Return(entries, nil)
+			},
+			func(t *testing.T, rsp *pb.ListEntriesResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, rsp.GetEntries(), len(entries))
+				for i, entry := range entries {
+					require.Equal(t, entry.ID, rsp.GetEntries()[i].GetId())
+					require.Equal(t, entry.Amount, rsp.GetEntries()[i].GetAmount())
+				}
+			},
+		},
+		{
+			"UnauthorizedUser",
+			&pb.ListEntriesRequest{AccountId: account.ID, PageId: 1, PageSize: 5},
+			"mallory",
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListEntries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			func(t *testing.T, rsp *pb.ListEntriesResponse, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+			},
+		},
+		{
+			"InvalidPage",
+			&pb.ListEntriesRequest{AccountId: account.ID, PageId: 0, PageSize: 5},
+			account.Owner,
+			func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			func(t *testing.T, rsp *pb.ListEntriesResponse, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+
+			client, server := newTestClient(t, store)
+			ctx := newContextWithBearerToken(t, server.tokenMaker, tc.username, time.Minute)
+			rsp, err := client.ListEntries(ctx, tc.req)
+			tc.checkResponse(t, rsp, err)
+		})
+	}
+}
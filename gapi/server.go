@@ -0,0 +1,94 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/pb"
+	"github.com/julkar-naim/simple-bank/ratelimit"
+	"github.com/julkar-naim/simple-bank/token"
+	"github.com/julkar-naim/simple-bank/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"log/slog"
+	"net"
+)
+
+// Server serves gRPC requests for the banking service
+type Server struct {
+	pb.UnimplementedSimpleBankServer
+	config     util.Config
+	store      db.Store
+	tokenMaker token.Maker
+	logger     *slog.Logger
+	grpcServer *grpc.Server
+
+	rateLimitStore   ratelimit.Store
+	defaultRateLimit ratelimit.Limit
+	routeRateLimits  map[string]ratelimit.Limit
+}
+
+// NewServer creates a gRPC server whose calls take their rate limit tokens
+// from rateLimitStore, which should be the store of the HTTP API so that
+// both APIs share the buckets of a caller
+func NewServer(config util.Config, store db.Store, rateLimitStore ratelimit.Store) (*Server, error) {
+	tokenMaker, err := token.NewMaker(config.TokenType, config.TokenSymmetricKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create token maker: %w", err)
+	}
+	defaultRateLimit, err := ratelimit.ParseLimit(config.RateLimitDefault)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse default rate limit: %w", err)
+	}
+	routeRateLimits, err := ratelimit.ParseRouteLimits(config.RateLimitRoutes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse route rate limits: %w", err)
+	}
+
+	server := &Server{
+		config:           config,
+		store:            store,
+		tokenMaker:       tokenMaker,
+		logger:           slog.Default(),
+		rateLimitStore:   rateLimitStore,
+		defaultRateLimit: defaultRateLimit,
+		routeRateLimits:  routeRateLimits,
+	}
+	server.grpcServer = server.NewGRPCServer()
+	return server, nil
+}
+
+// NewGRPCServer registers the service, with request logging, authentication,
+// rate limiting and reflection, on a new grpc.Server
+func (server *Server) NewGRPCServer() *grpc.Server {
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(server.loggerInterceptor, server.authInterceptor, server.rateLimitInterceptor))
+	pb.RegisterSimpleBankServer(grpcServer, server)
+	reflection.Register(grpcServer)
+	return grpcServer
+}
+
+func (server *Server) Start(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("cannot create listener: %w", err)
+	}
+	return server.grpcServer.Serve(listener)
+}
+
+// Shutdown stops accepting connections and waits for in-flight RPCs to
+// finish. If ctx is done first the remaining RPCs are cancelled.
+func (server *Server) Shutdown(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		server.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		server.grpcServer.Stop()
+		return ctx.Err()
+	}
+}
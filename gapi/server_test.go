@@ -0,0 +1,131 @@
+package gapi
+
+import (
+	"context"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/pb"
+	"github.com/julkar-naim/simple-bank/ratelimit"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"testing"
+	"time"
+)
+
+func TestReflection(t *testing.T) {
+	server, err := NewServer(testConfig(), nil, ratelimit.NewMemoryStore())
+	require.NoError(t, err)
+
+	conn := newTestConn(t, server)
+
+	// reflection must work without an access token
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	require.NoError(t, err)
+
+	err = stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	require.NoError(t, err)
+
+	rsp, err := stream.Recv()
+	require.NoError(t, err)
+
+	var services []string
+	for _, service := range rsp.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	require.Contains(t, services, pb.SimpleBank_ServiceDesc.ServiceName)
+}
+
+func TestRequestIDInterceptor(t *testing.T) {
+	account := randomAccount("alice")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, id int64) (db.Account, error) {
+			require.Equal(t, "trace-me", util.RequestIDFromContext(ctx))
+			return account, nil
+		})
+
+	client, server := newTestClient(t, store)
+	ctx := newContextWithBearerToken(t, server.tokenMaker, account.Owner, time.Minute)
+	ctx = metadata.AppendToOutgoingContext(ctx, requestIDHeaderKey, "trace-me")
+
+	var header metadata.MD
+	_, err := client.GetAccount(ctx, &pb.GetAccountRequest{Id: account.ID}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, []string{"trace-me"}, header.Get(requestIDHeaderKey))
+}
+
+func TestServerShutdown(t *testing.T) {
+	account := randomAccount("alice")
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the first call finishes once shutdown has begun, the second outlives
+	// the shutdown deadline and is cancelled
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+			Times(1).
+			DoAndReturn(func(ctx context.Context, id int64) (db.Account, error) {
+				started <- struct{}{}
+				<-release
+				return account, nil
+			}),
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+			Times(1).
+			DoAndReturn(func(ctx context.Context, id int64) (db.Account, error) {
+				started <- struct{}{}
+				<-ctx.Done()
+				return db.Account{}, ctx.Err()
+			}),
+	)
+
+	client, server := newTestClient(t, store)
+	ctx := newContextWithBearerToken(t, server.tokenMaker, account.Owner, time.Minute)
+
+	errs := make(chan error, 2)
+	getAccount := func() {
+		_, err := client.GetAccount(ctx, &pb.GetAccountRequest{Id: account.ID})
+		errs <- err
+	}
+
+	// drained: the in-flight RPC completes before Shutdown returns
+	go getAccount()
+	<-started
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- server.Shutdown(shutdownCtx) }()
+
+	close(release)
+	require.NoError(t, <-errs)
+	require.NoError(t, <-shutdownErr)
+
+	// forced: an RPC that outlives the deadline is cancelled
+	server, err := NewServer(testConfig(), store, ratelimit.NewMemoryStore())
+	require.NoError(t, err)
+	client = pb.NewSimpleBankClient(newTestConn(t, server))
+	ctx = newContextWithBearerToken(t, server.tokenMaker, account.Owner, time.Minute)
+
+	go getAccount()
+	<-started
+
+	expiredCtx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, server.Shutdown(expiredCtx), context.DeadlineExceeded)
+	require.Error(t, <-errs)
+}
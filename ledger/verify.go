@@ -0,0 +1,154 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+)
+
+// chains verified for each account
+const (
+	ChainEntries   = "entries"
+	ChainTransfers = "transfers"
+)
+
+// Break is the first link of a chain that does not verify
+type Break struct {
+	Chain     string `json:"chain"`
+	AccountID int64  `json:"account_id"`
+	RowID     int64  `json:"row_id"`
+	Reason    string `json:"reason"`
+}
+
+func (b *Break) String() string {
+	return fmt.Sprintf("%s of account %d broken at id %d: %s", b.Chain, b.AccountID, b.RowID, b.Reason)
+}
+
+// Report summarises a verification. Break is nil if every chain verified.
+type Report struct {
+	Accounts  int    `json:"accounts"`
+	Entries   int    `json:"entries"`
+	Transfers int    `json:"transfers"`
+	Break     *Break `json:"break,omitempty"`
+}
+
+// Verifier recomputes the hash chains of the entries and transfers written
+// by db.TransferTx. An entry's hash covers its transfer, type and description
+// too, so relinking or relabelling an entry breaks its chain.
+type Verifier struct {
+	store    db.Store
+	pageSize int32
+}
+
+// NewVerifier creates a Verifier that reads pageSize rows at a time
+func NewVerifier(store db.Store, pageSize int32) *Verifier {
+	return &Verifier{
+		store:    store,
+		pageSize: pageSize,
+	}
+}
+
+// Verify walks the chains of every account in id order and stops at the
+// first broken link
+func (v *Verifier) Verify(ctx context.Context) (Report, error) {
+	var report Report
+	for offset := int32(0); ; offset += v.pageSize {
+		accounts, err := v.store.ListAllAccounts(ctx, db.ListAllAccountsParams{
+			Limit:  v.pageSize,
+			Offset: offset,
+		})
+		if err != nil {
+			return report, err
+		}
+
+		for _, account := range accounts {
+			err = v.verifyAccount(ctx, account.ID, &report)
+			if err != nil || report.Break != nil {
+				return report, err
+			}
+		}
+		if len(accounts) < int(v.pageSize) {
+			return report, nil
+		}
+	}
+}
+
+// VerifyAccount walks the chains of one account
+func (v *Verifier) VerifyAccount(ctx context.Context, accountID int64) (Report, error) {
+	var report Report
+	err := v.verifyAccount(ctx, accountID, &report)
+	return report, err
+}
+
+func (v *Verifier) verifyAccount(ctx context.Context, accountID int64, report *Report) error {
+	report.Accounts++
+
+	var err error
+	report.Break, err = verifyChain(ctx, ChainEntries, accountID, &report.Entries, func(afterID int64) ([]link, error) {
+		entries, err := v.store.ListEntriesAfter(ctx, db.ListEntriesAfterParams{
+			AccountID: accountID,
+			ID:        afterID,
+			Limit:     v.pageSize,
+		})
+		links := make([]link, len(entries))
+		for i, entry := range entries {
+			links[i] = link{entry.ID, entry.PrevHash, entry.Hash, db.EntryHash(entry)}
+		}
+		return links, err
+	})
+	if err != nil || report.Break != nil {
+		return err
+	}
+
+	report.Break, err = verifyChain(ctx, ChainTransfers, accountID, &report.Transfers, func(afterID int64) ([]link, error) {
+		transfers, err := v.store.ListTransfersFromAccountAfter(ctx, db.ListTransfersFromAccountAfterParams{
+			FromAccountID: accountID,
+			ID:            afterID,
+			Limit:         v.pageSize,
+		})
+		links := make([]link, len(transfers))
+		for i, transfer := range transfers {
+			links[i] = link{transfer.ID, transfer.PrevHash, transfer.Hash, db.TransferHash(transfer)}
+		}
+		return links, err
+	})
+	return err
+}
+
+// link is a row of a chain with the hash recomputed from its content
+type link struct {
+	id       int64
+	prevHash string
+	hash     string
+	wantHash string
+}
+
+// verifyChain pages through a chain in id order, counting the rows it checks
+func verifyChain(ctx context.Context, chain string, accountID int64, count *int, page func(afterID int64) ([]link, error)) (*Break, error) {
+	var afterID int64
+	prevHash := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		links, err := page(afterID)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range links {
+			*count++
+			switch {
+			case l.prevHash != prevHash:
+				return &Break{chain, accountID, l.id, "prev_hash does not match the hash of the previous row"}, nil
+			case l.hash != l.wantHash:
+				return &Break{chain, accountID, l.id, "hash does not match the row's content"}, nil
+			}
+			prevHash = l.hash
+			afterID = l.id
+		}
+		if len(links) == 0 {
+			return nil, nil
+		}
+	}
+}
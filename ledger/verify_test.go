@@ -0,0 +1,256 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+// chainEntries returns n correctly chained entries of an account
+func chainEntries(accountID int64, n int) []db.Entry {
+	entries := make([]db.Entry, n)
+	prevHash := ""
+	for i := range entries {
+		entry := db.Entry{
+			ID:          int64(i+1) * 10,
+			AccountID:   accountID,
+			Amount:      int64(i+1) * 100,
+			CreatedAt:   time.Unix(1700000000, int64(i)*1000).UTC(),
+			PrevHash:    prevHash,
+			TransferID:  sql.NullInt64{Int64: int64(i+1) * 10, Valid: true},
+			EntryType:   db.EntryTypeTransfer,
+			Description: fmt.Sprintf("transfer from account %d", accountID+1),
+		}
+		entry.Hash = db.EntryHash(entry)
+		prevHash = entry.Hash
+		entries[i] = entry
+	}
+	return entries
+}
+
+// chainTransfers returns n correctly chained transfers from an account
+func chainTransfers(fromAccountID int64, n int) []db.Transfer {
+	transfers := make([]db.Transfer, n)
+	prevHash := ""
+	for i := range transfers {
+		transfer := db.Transfer{
+			ID:            int64(i+1) * 10,
+			FromAccountID: fromAccountID,
+			ToAccountID:   fromAccountID + 1,
+			Amount:        int64(i+1) * 100,
+			CreatedAt:     time.Unix(1700000000, int64(i)*1000).UTC(),
+			PrevHash:      prevHash,
+		}
+		transfer.Hash = db.TransferHash(transfer)
+		prevHash = transfer.Hash
+		transfers[i] = transfer
+	}
+	return transfers
+}
+
+// expectEntries serves entries through ListEntriesAfter like the SqlStore
+func expectEntries(store *mockdb.MockStore, entries []db.Entry) {
+	store.EXPECT().ListEntriesAfter(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, arg db.ListEntriesAfterParams) ([]db.Entry, error) {
+			page := []db.Entry{}
+			for _, entry := range entries {
+				if entry.AccountID == arg.AccountID && entry.ID > arg.ID && len(page) < int(arg.Limit) {
+					page = append(page, entry)
+				}
+			}
+			return page, nil
+		}).
+		AnyTimes()
+}
+
+// expectTransfers serves transfers through ListTransfersFromAccountAfter like the SqlStore
+func expectTransfers(store *mockdb.MockStore, transfers []db.Transfer) {
+	store.EXPECT().ListTransfersFromAccountAfter(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, arg db.ListTransfersFromAccountAfterParams) ([]db.Transfer, error) {
+			page := []db.Transfer{}
+			for _, transfer := range transfers {
+				if transfer.FromAccountID == arg.FromAccountID && transfer.ID > arg.ID && len(page) < int(arg.Limit) {
+					page = append(page, transfer)
+				}
+			}
+			return page, nil
+		}).
+		AnyTimes()
+}
+
+func TestVerifyAccount(t *testing.T) {
+	testCases := []struct {
+		name        string
+		tamper      func(entries []db.Entry, transfers []db.Transfer)
+		checkReport func(t *testing.T, report Report)
+	}{
+		{
+			"OK",
+			func(entries []db.Entry, transfers []db.Transfer) {},
+			func(t *testing.T, report Report) {
+				require.Nil(t, report.Break)
+				require.Equal(t, 1, report.Accounts)
+				require.Equal(t, 7, report.Entries)
+				require.Equal(t, 4, report.Transfers)
+			},
+		},
+		{
+			"EditedAmount",
+			func(entries []db.Entry, transfers []db.Transfer) {
+				entries[3].Amount = 1
+			},
+			func(t *testing.T, report Report) {
+				require.Equal(t, &Break{ChainEntries, 1, 40, "hash does not match the row's content"}, report.Break)
+				require.Equal(t, 4, report.Entries)
+				require.Zero(t, report.Transfers)
+			},
+		},
+		{
+			"RehashedRow",
+			func(entries []db.Entry, transfers []db.Transfer) {
+				// rewriting a row's hash breaks the link from the next row
+				entries[2].Amount = 1
+				entries[2].Hash = db.EntryHash(entries[2])
+			},
+			func(t *testing.T, report Report) {
+				require.Equal(t, &Break{ChainEntries, 1, 40, "prev_hash does not match the hash of the previous row"}, report.Break)
+			},
+		},
+		{
+			"DeletedRow",
+			func(entries []db.Entry, transfers []db.Transfer) {
+				// the fake store leaves out rows of other accounts
+				entries[3].AccountID = 0
+			},
+			func(t *testing.T, report Report) {
+				require.Equal(t, &Break{ChainEntries, 1, 50, "prev_hash does not match the hash of the previous row"}, report.Break)
+			},
+		},
+		{
+			"RelinkedEntry",
+			func(entries []db.Entry, transfers []db.Transfer) {
+				entries[3].TransferID.Int64 = 99
+			},
+			func(t *testing.T, report Report) {
+				require.Equal(t, &Break{ChainEntries, 1, 40, "hash does not match the row's content"}, report.Break)
+			},
+		},
+		{
+			"RetypedEntry",
+			func(entries []db.Entry, transfers []db.Transfer) {
+				entries[3].EntryType = db.EntryTypeFee
+			},
+			func(t *testing.T, report Report) {
+				require.Equal(t, &Break{ChainEntries, 1, 40, "hash does not match the row's content"}, report.Break)
+			},
+		},
+		{
+			"EditedDescription",
+			func(entries []db.Entry, transfers []db.Transfer) {
+				entries[3].Description = "refund"
+			},
+			func(t *testing.T, report Report) {
+				require.Equal(t, &Break{ChainEntries, 1, 40, "hash does not match the row's content"}, report.Break)
+			},
+		},
+		{
+			"EditedTransfer",
+			func(entries []db.Entry, transfers []db.Transfer) {
+				transfers[3].ToAccountID = 99
+			},
+			func(t *testing.T, report Report) {
+				require.Equal(t, &Break{ChainTransfers, 1, 40, "hash does not match the row's content"}, report.Break)
+				require.Equal(t, 7, report.Entries)
+				require.Equal(t, 4, report.Transfers)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			entries := chainEntries(1, 7)
+			transfers := chainTransfers(1, 4)
+			tc.tamper(entries, transfers)
+
+			store := mockdb.NewMockStore(ctrl)
+			expectEntries(store, entries)
+			expectTransfers(store, transfers)
+
+			// a page size of 3 makes the chains span pages
+			report, err := NewVerifier(store, 3).VerifyAccount(context.Background(), 1)
+			require.NoError(t, err)
+			tc.checkReport(t, report)
+		})
+	}
+}
+
+func TestVerifyStopsAtFirstBreak(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	accounts := []db.Account{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+	entries := append(chainEntries(1, 2), chainEntries(2, 2)...)
+	entries = append(entries, chainEntries(3, 2)...)
+	entries[5].PrevHash = "forged"
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListAllAccounts(gomock.Any(), gomock.Eq(db.ListAllAccountsParams{Limit: 2, Offset: 0})).
+		Times(1).
+		Return(accounts[:2], nil)
+	store.EXPECT().ListAllAccounts(gomock.Any(), gomock.Eq(db.ListAllAccountsParams{Limit: 2, Offset: 2})).
+		Times(1).
+		Return(accounts[2:], nil)
+	expectEntries(store, entries)
+	expectTransfers(store, nil)
+
+	report, err := NewVerifier(store, 2).Verify(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, &Break{ChainEntries, 3, 20, "prev_hash does not match the hash of the previous row"}, report.Break)
+	require.Equal(t, 3, report.Accounts)
+	require.Equal(t, 6, report.Entries)
+}
+
+func TestVerifyAllAccounts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListAllAccounts(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.Account{{ID: 1}, {ID: 2}}, nil)
+	expectEntries(store, chainEntries(1, 3))
+	expectTransfers(store, chainTransfers(2, 2))
+
+	report, err := NewVerifier(store, 5).Verify(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, report.Break)
+	require.Equal(t, Report{Accounts: 2, Entries: 3, Transfers: 2}, report)
+}
+
+func TestVerifyStoreError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListEntriesAfter(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil, errors.New("connection reset"))
+
+	_, err := NewVerifier(store, 5).VerifyAccount(context.Background(), 1)
+	require.EqualError(t, err, "connection reset")
+}
+
+func TestBreakString(t *testing.T) {
+	b := &Break{ChainTransfers, 7, 42, "hash does not match the row's content"}
+	require.Equal(t, "transfers of account 7 broken at id 42: hash does not match the row's content", b.String())
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"golang.org/x/sync/errgroup"
+	"log/slog"
+	"time"
+)
+
+// component is a long-lived part of the process that must be stopped before
+// it exits
+type component struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// shutdown stops the components in order, sharing ctx's deadline between
+// them, and logs how each one went. Every component is stopped even if an
+// earlier one fails.
+func shutdown(ctx context.Context, logger *slog.Logger, components []component) error {
+	var errs []error
+	for _, c := range components {
+		start := time.Now()
+		err := c.stop(ctx)
+		if err != nil {
+			logger.Error("component shutdown failed", "component", c.name, "duration", time.Since(start), "error", err)
+			errs = append(errs, err)
+			continue
+		}
+		logger.Info("component stopped", "component", c.name, "duration", time.Since(start))
+	}
+	return errors.Join(errs...)
+}
+
+// worker is a background loop run in the errgroup under its own context, so
+// that shutdown stops it in order instead of when the group's context ends
+type worker struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startWorker runs run in group until the worker is stopped. An error from
+// run still fails the group.
+func startWorker(group *errgroup.Group, run func(ctx context.Context) error) *worker {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &worker{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	group.Go(func() error {
+		defer close(w.done)
+		return run(ctx)
+	})
+	return w
+}
+
+// stop cancels the worker and waits for it to return, or for ctx to be done
+func (w *worker) stop(ctx context.Context) error {
+	w.cancel()
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
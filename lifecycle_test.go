@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	var stopped []string
+	stop := func(name string, err error) component {
+		return component{name, func(ctx context.Context) error {
+			stopped = append(stopped, name)
+			return err
+		}}
+	}
+
+	failure := errors.New("boom")
+	err := shutdown(context.Background(), logger, []component{
+		stop("http", nil),
+		stop("grpc", failure),
+		stop("database", nil),
+	})
+	require.ErrorIs(t, err, failure)
+	require.Equal(t, []string{"http", "grpc", "database"}, stopped)
+
+	require.Contains(t, buf.String(), `msg="component stopped" component=http`)
+	require.Contains(t, buf.String(), `msg="component shutdown failed" component=grpc`)
+	require.Contains(t, buf.String(), `msg="component stopped" component=database`)
+}
+
+func TestWorkerStop(t *testing.T) {
+	var group errgroup.Group
+
+	stopped := false
+	w := startWorker(&group, func(ctx context.Context) error {
+		<-ctx.Done()
+		stopped = true
+		return nil
+	})
+	require.NoError(t, w.stop(context.Background()))
+	require.True(t, stopped)
+
+	// a worker that ignores cancellation is given up on at the deadline
+	release := make(chan struct{})
+	w = startWorker(&group, func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, w.stop(ctx), context.DeadlineExceeded)
+
+	close(release)
+	require.NoError(t, group.Wait())
+}
+
+func TestWorkerErrorFailsGroup(t *testing.T) {
+	group, groupCtx := errgroup.WithContext(context.Background())
+
+	failure := errors.New("boom")
+	w := startWorker(group, func(ctx context.Context) error {
+		return failure
+	})
+
+	<-groupCtx.Done()
+	require.NoError(t, w.stop(context.Background()))
+	require.ErrorIs(t, group.Wait(), failure)
+}
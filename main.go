@@ -1,12 +1,25 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"github.com/julkar-naim/simple-bank/api"
+	"github.com/julkar-naim/simple-bank/currency"
 	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/gapi"
+	"github.com/julkar-naim/simple-bank/metrics"
+	"github.com/julkar-naim/simple-bank/outbox"
+	"github.com/julkar-naim/simple-bank/stream"
+	"github.com/julkar-naim/simple-bank/telemetry"
 	"github.com/julkar-naim/simple-bank/util"
+	"github.com/julkar-naim/simple-bank/webhook"
 	_ "github.com/lib/pq"
+	"golang.org/x/sync/errgroup"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
@@ -16,15 +29,105 @@ func main() {
 	}
 	util.NewConfig(config)
 
+	logger, err := util.NewLogger(os.Stdout, config.LogLevel, config.LogFormat)
+	if err != nil {
+		log.Fatal("cannot create logger", err)
+	}
+	slog.SetDefault(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := telemetry.SetupTracing(ctx, config)
+	if err != nil {
+		log.Fatal("cannot set up tracing", err)
+	}
+
 	conn, err := sql.Open(config.DBDriver, config.DBSource)
 	if err != nil {
 		log.Fatal("cannot connect to database", err)
 	}
-	store := db.NewSqlStore(conn)
-	server := api.NewServer(store)
+	err = metrics.RegisterDB(conn)
+	if err != nil {
+		log.Fatal("cannot register database metrics", err)
+	}
+	store := metrics.NewStore(db.NewSqlStore(conn))
+
+	// until the first refresh succeeds only the seeded currencies are accepted
+	err = currency.Refresh(ctx, store)
+	if err != nil {
+		slog.Warn("cannot load currencies", "error", err)
+	}
+
+	publisher, err := outbox.NewPublisher(config.OutboxPublisher, logger)
+	if err != nil {
+		log.Fatal("cannot create outbox publisher", err)
+	}
+	publisher = outbox.MultiPublisher{webhook.NewDispatcher(store), publisher}
+
+	webhookSecretBox, err := util.NewSecretBox(config.WebhookEncryptionKey)
+	if err != nil {
+		log.Fatal("cannot create webhook secret box", err)
+	}
+	deliverer := webhook.NewDeliverer(store, webhookSecretBox, config.WebhookTimeout, webhook.RetryPolicy{
+		MaxAttempts: config.WebhookMaxAttempts,
+		BaseDelay:   config.WebhookBackoffBase,
+		MaxDelay:    config.WebhookBackoffMax,
+	}, config.WebhookInterval, config.WebhookBatchSize, logger)
+
+	httpServer, err := api.NewServer(config, store)
+	if err != nil {
+		log.Fatal("cannot create server", err)
+	}
+	grpcServer, err := gapi.NewServer(config, store, httpServer.RateLimitStore())
+	if err != nil {
+		log.Fatal("cannot create gRPC server", err)
+	}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		slog.Info("start gRPC server", "address", config.GRPCAddress)
+		return grpcServer.Start(config.GRPCAddress)
+	})
+	group.Go(func() error {
+		slog.Info("start HTTP server", "address", config.Address)
+		return httpServer.Start(config.Address)
+	})
+	// workers keep running while the servers drain, since in-flight
+	// requests still write outbox events and account updates
+	refresher := startWorker(group, currency.NewRefresher(store, config.CurrencyRefresh, logger).Run)
+	relay := startWorker(group, outbox.NewRelay(store, publisher, config.OutboxRelayInterval, config.OutboxBatchSize, logger).Run)
+	webhooks := startWorker(group, deliverer.Run)
+	listener := startWorker(group, stream.NewListener(config.DBSource, httpServer.AccountHub(), logger).Run)
+	nonces := startWorker(group, api.NewNonceCleaner(store, config.APISignatureMaxSkew, logger).Run)
+	group.Go(func() error {
+		// wait for a signal, or for one of the servers to fail
+		<-groupCtx.Done()
+		slog.Info("shutting down", "timeout", config.ShutdownTimeout)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		defer cancel()
+
+		// servers drain first so in-flight requests can still reach the
+		// database, then the workers stop before it is closed, and tracing
+		// goes last to flush their spans
+		return shutdown(shutdownCtx, logger, []component{
+			{"http server", httpServer.Shutdown},
+			{"grpc server", grpcServer.Shutdown},
+			{"stream listener", listener.stop},
+			{"outbox relay", relay.stop},
+			{"webhook deliverer", webhooks.stop},
+			{"currency refresher", refresher.stop},
+			{"api key nonce cleaner", nonces.stop},
+			{"database", func(context.Context) error { return conn.Close() }},
+			{"tracing", shutdownTracing},
+		})
+	})
 
-	err = server.Start(config.Address)
+	err = group.Wait()
 	if err != nil {
-		log.Fatal("cannot start server", err)
+		slog.Error("server stopped with error", "error", err)
+		os.Exit(1)
 	}
+	slog.Info("shutdown complete")
 }
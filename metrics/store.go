@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/lib/pq"
+)
+
+// Store decorates a db.Store with ledger metrics
+type Store struct {
+	db.Store
+}
+
+// NewStore wraps store so transfers are counted. When store is a
+// *db.SqlStore its transaction retries and rollbacks are counted too.
+func NewStore(store db.Store) db.Store {
+	if sqlStore, ok := store.(*db.SqlStore); ok {
+		sqlStore.SetTxObserver(txObserver{})
+	}
+	return &Store{Store: store}
+}
+
+func (store *Store) TransferTx(ctx context.Context, arg db.TransferTxParams) (db.TransferTxResult, error) {
+	result, err := store.Store.TransferTx(ctx, arg)
+	if err != nil {
+		TransfersFailed.WithLabelValues(failureReason(err)).Inc()
+		return result, err
+	}
+
+	TransfersCreated.Inc()
+	TransferVolume.WithLabelValues(arg.Amount.Currency()).Add(float64(arg.Amount.Value()))
+	return result, nil
+}
+
+// failureReason maps a TransferTx error to a low-cardinality label
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, sql.ErrNoRows):
+		return "account_not_found"
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "foreign_key_violation":
+			return "account_not_found"
+		case "check_violation":
+			return "constraint_violation"
+		case "serialization_failure", "deadlock_detected":
+			return "conflict"
+		}
+	}
+	return "internal"
+}
+
+type txObserver struct{}
+
+func (txObserver) ObserveTxRetry(context.Context, error) {
+	TxRetries.Inc()
+}
+
+func (txObserver) ObserveTxRollback(context.Context, error) {
+	TxRollbacks.Inc()
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/money"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestStoreTransferTx(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mockdb.NewMockStore(ctrl)
+	store := NewStore(mockStore)
+
+	arg := db.TransferTxParams{FromAccountID: 1, ToAccountID: 2, Amount: money.New(25, util.EUR)}
+	result := db.TransferTxResult{
+		FromAccount: db.Account{ID: 1, Currency: util.EUR},
+		ToAccount:   db.Account{ID: 2, Currency: util.EUR},
+	}
+
+	created := testutil.ToFloat64(TransfersCreated)
+	volume := testutil.ToFloat64(TransferVolume.WithLabelValues(util.EUR))
+	failed := testutil.ToFloat64(TransfersFailed.WithLabelValues("conflict"))
+
+	mockStore.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).
+		Times(1).
+		Return(result, nil)
+	_, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	require.Equal(t, created+1, testutil.ToFloat64(TransfersCreated))
+	require.Equal(t, volume+25, testutil.ToFloat64(TransferVolume.WithLabelValues(util.EUR)))
+
+	mockStore.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).
+		Times(1).
+		Return(db.TransferTxResult{}, &pq.Error{Code: "40001"})
+	_, err = store.TransferTx(context.Background(), arg)
+	require.Error(t, err)
+
+	require.Equal(t, created+1, testutil.ToFloat64(TransfersCreated))
+	require.Equal(t, failed+1, testutil.ToFloat64(TransfersFailed.WithLabelValues("conflict")))
+}
+
+func TestFailureReason(t *testing.T) {
+	require.Equal(t, "canceled", failureReason(context.Canceled))
+	require.Equal(t, "timeout", failureReason(context.DeadlineExceeded))
+	require.Equal(t, "account_not_found", failureReason(sql.ErrNoRows))
+	require.Equal(t, "account_not_found", failureReason(&pq.Error{Code: "23503"}))
+	require.Equal(t, "constraint_violation", failureReason(&pq.Error{Code: "23514"}))
+	require.Equal(t, "conflict", failureReason(&pq.Error{Code: "40P01"}))
+	require.Equal(t, "internal", failureReason(errors.New("boom")))
+}
+
+func TestTxObserver(t *testing.T) {
+	retries := testutil.ToFloat64(TxRetries)
+	rollbacks := testutil.ToFloat64(TxRollbacks)
+
+	txObserver{}.ObserveTxRetry(context.Background(), nil)
+	txObserver{}.ObserveTxRollback(context.Background(), nil)
+
+	require.Equal(t, retries+1, testutil.ToFloat64(TxRetries))
+	require.Equal(t, rollbacks+1, testutil.ToFloat64(TxRollbacks))
+}
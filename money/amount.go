@@ -0,0 +1,198 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/julkar-naim/simple-bank/util"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrOverflow         = errors.New("amount out of range")
+)
+
+// Amount is a sum of money in the minor units of its currency: 1234 USD is
+// $12.34 and 1234 JPY is ¥1234. How many minor units a major unit has comes
+// from the currency registry in util.
+type Amount struct {
+	value    int64
+	currency string
+}
+
+// New returns value minor units of currency
+func New(value int64, currency string) Amount {
+	return Amount{value: value, currency: currency}
+}
+
+// Value returns the amount in minor units
+func (a Amount) Value() int64 {
+	return a.value
+}
+
+func (a Amount) Currency() string {
+	return a.currency
+}
+
+func (a Amount) IsZero() bool {
+	return a.value == 0
+}
+
+func (a Amount) IsPositive() bool {
+	return a.value > 0
+}
+
+func (a Amount) IsNegative() bool {
+	return a.value < 0
+}
+
+// Neg returns -a
+func (a Amount) Neg() (Amount, error) {
+	if a.value == math.MinInt64 {
+		return Amount{}, ErrOverflow
+	}
+	return New(-a.value, a.currency), nil
+}
+
+// Add returns a + b. Both must be in the same currency.
+func (a Amount) Add(b Amount) (Amount, error) {
+	if a.currency != b.currency {
+		return Amount{}, fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, a.currency, b.currency)
+	}
+	if (b.value > 0 && a.value > math.MaxInt64-b.value) || (b.value < 0 && a.value < math.MinInt64-b.value) {
+		return Amount{}, ErrOverflow
+	}
+	return New(a.value+b.value, a.currency), nil
+}
+
+// Sub returns a - b. Both must be in the same currency.
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if a.currency != b.currency {
+		return Amount{}, fmt.Errorf("%w: %s vs %s", ErrCurrencyMismatch, a.currency, b.currency)
+	}
+	if (b.value < 0 && a.value > math.MaxInt64+b.value) || (b.value > 0 && a.value < math.MinInt64+b.value) {
+		return Amount{}, ErrOverflow
+	}
+	return New(a.value-b.value, a.currency), nil
+}
+
+// exponent returns the number of decimals of currency
+func exponent(currency string) (int, error) {
+	c, ok := util.LookupCurrency(currency)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+	}
+	return int(c.Exponent), nil
+}
+
+// Parse parses a decimal string in major units, such as "12.34" or "-5",
+// into an Amount of currency. It accepts at most as many decimals as the
+// currency has minor units and never rounds.
+func Parse(s string, currency string) (Amount, error) {
+	exp, err := exponent(currency)
+	if err != nil {
+		return Amount{}, err
+	}
+
+	digits, negative := strings.CutPrefix(s, "-")
+	whole, fraction, hasPoint := strings.Cut(digits, ".")
+	if whole == "" || (hasPoint && fraction == "") || !isDigits(whole) || !isDigits(fraction) {
+		return Amount{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if len(fraction) > exp {
+		return Amount{}, fmt.Errorf("%w: %q has more than %d decimals for %s", ErrInvalidAmount, s, exp, currency)
+	}
+
+	// parse as an unsigned number of minor units, so that the most negative
+	// amount doesn't overflow before the sign is applied
+	minorUnits, err := strconv.ParseUint(whole+fraction+strings.Repeat("0", exp-len(fraction)), 10, 64)
+	if err != nil {
+		return Amount{}, fmt.Errorf("%w: %q", ErrOverflow, s)
+	}
+
+	switch {
+	case !negative && minorUnits <= math.MaxInt64:
+		return New(int64(minorUnits), currency), nil
+	case negative && minorUnits <= math.MaxInt64:
+		return New(-int64(minorUnits), currency), nil
+	case negative && minorUnits == math.MaxInt64+1:
+		return New(math.MinInt64, currency), nil
+	default:
+		return Amount{}, fmt.Errorf("%w: %q", ErrOverflow, s)
+	}
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Decimal formats a in major units with all the decimals of its currency,
+// such as "12.30". An amount of an unknown currency is formatted in minor
+// units.
+func (a Amount) Decimal() string {
+	exp, err := exponent(a.currency)
+	if err != nil {
+		exp = 0
+	}
+
+	var magnitude uint64
+	if a.value < 0 {
+		magnitude = uint64(-(a.value + 1)) + 1
+	} else {
+		magnitude = uint64(a.value)
+	}
+
+	digits := strconv.FormatUint(magnitude, 10)
+	if exp > 0 {
+		if len(digits) <= exp {
+			digits = strings.Repeat("0", exp-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+	}
+	if a.value < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// String formats a as "12.34 USD"
+func (a Amount) String() string {
+	return a.Decimal() + " " + a.currency
+}
+
+// amountJSON is the JSON form of an Amount. The value is a decimal string so
+// that clients never round it through a float.
+type amountJSON struct {
+	Value    string `json:"value"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON encodes a with its Decimal value, so an amount of a currency
+// missing from the registry is rendered in minor units rather than failing
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(amountJSON{Value: a.Decimal(), Currency: a.currency})
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	var v amountJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	amount, err := Parse(v.Value, v.Currency)
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
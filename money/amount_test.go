@@ -0,0 +1,149 @@
+package money
+
+import (
+	"encoding/json"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+)
+
+func setJPY(t *testing.T) {
+	defaults := util.SupportedCurrencies()
+	t.Cleanup(func() { util.SetCurrencies(defaults) })
+
+	util.SetCurrencies(append(defaults, util.Currency{Code: "JPY", Exponent: 0, Symbol: "¥", Enabled: true}))
+}
+
+func TestParse(t *testing.T) {
+	setJPY(t)
+
+	testCases := []struct {
+		name     string
+		input    string
+		currency string
+		value    int64
+		err      error
+	}{
+		{"Cents", "12.34", util.USD, 1234, nil},
+		{"FewerDecimals", "12.3", util.USD, 1230, nil},
+		{"Whole", "12", util.EUR, 1200, nil},
+		{"Negative", "-0.05", util.CAD, -5, nil},
+		{"NoMinorUnits", "1234", "JPY", 1234, nil},
+		{"Max", "92233720368547758.07", util.USD, math.MaxInt64, nil},
+		{"Min", "-92233720368547758.08", util.USD, math.MinInt64, nil},
+		{"TooManyDecimals", "12.345", util.USD, 0, ErrInvalidAmount},
+		{"DecimalsWithoutMinorUnits", "12.5", "JPY", 0, ErrInvalidAmount},
+		{"Empty", "", util.USD, 0, ErrInvalidAmount},
+		{"TrailingPoint", "12.", util.USD, 0, ErrInvalidAmount},
+		{"LeadingPoint", ".5", util.USD, 0, ErrInvalidAmount},
+		{"Plus", "+5", util.USD, 0, ErrInvalidAmount},
+		{"Exponent", "1e3", util.USD, 0, ErrInvalidAmount},
+		{"Spaces", " 12", util.USD, 0, ErrInvalidAmount},
+		{"Overflow", "92233720368547758.08", util.USD, 0, ErrOverflow},
+		{"NegativeOverflow", "-92233720368547758.09", util.USD, 0, ErrOverflow},
+		{"UnknownCurrency", "12", "XYZ", 0, ErrUnknownCurrency},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			amount, err := Parse(tc.input, tc.currency)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.value, amount.Value())
+			require.Equal(t, tc.currency, amount.Currency())
+		})
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	setJPY(t)
+
+	testCases := []struct {
+		amount   Amount
+		expected string
+	}{
+		{New(1234, util.USD), "12.34"},
+		{New(1230, util.USD), "12.30"},
+		{New(5, util.USD), "0.05"},
+		{New(-5, util.USD), "-0.05"},
+		{New(0, util.USD), "0.00"},
+		{New(1234, "JPY"), "1234"},
+		{New(math.MinInt64, util.USD), "-92233720368547758.08"},
+		{New(1234, "XYZ"), "1234"},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, tc.amount.Decimal())
+	}
+	require.Equal(t, "12.34 USD", New(1234, util.USD).String())
+}
+
+func TestArithmetic(t *testing.T) {
+	a := New(1000, util.USD)
+	b := New(250, util.USD)
+
+	sum, err := a.Add(b)
+	require.NoError(t, err)
+	require.Equal(t, New(1250, util.USD), sum)
+
+	diff, err := b.Sub(a)
+	require.NoError(t, err)
+	require.Equal(t, New(-750, util.USD), diff)
+	require.True(t, diff.IsNegative())
+
+	neg, err := a.Neg()
+	require.NoError(t, err)
+	require.Equal(t, New(-1000, util.USD), neg)
+
+	_, err = a.Add(New(1, util.EUR))
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+	_, err = a.Sub(New(1, util.EUR))
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = New(math.MaxInt64, util.USD).Add(New(1, util.USD))
+	require.ErrorIs(t, err, ErrOverflow)
+	_, err = New(math.MinInt64, util.USD).Add(New(-1, util.USD))
+	require.ErrorIs(t, err, ErrOverflow)
+	_, err = New(math.MinInt64, util.USD).Sub(New(1, util.USD))
+	require.ErrorIs(t, err, ErrOverflow)
+	_, err = New(math.MaxInt64, util.USD).Sub(New(-1, util.USD))
+	require.ErrorIs(t, err, ErrOverflow)
+	_, err = New(math.MinInt64, util.USD).Neg()
+	require.ErrorIs(t, err, ErrOverflow)
+
+	largest, err := New(math.MaxInt64-1, util.USD).Add(New(1, util.USD))
+	require.NoError(t, err)
+	require.Equal(t, int64(math.MaxInt64), largest.Value())
+}
+
+func TestJSON(t *testing.T) {
+	setJPY(t)
+
+	data, err := json.Marshal(New(1234, util.USD))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"value":"12.34","currency":"USD"}`, string(data))
+
+	data, err = json.Marshal(New(1234, "JPY"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"value":"1234","currency":"JPY"}`, string(data))
+
+	var amount Amount
+	require.NoError(t, json.Unmarshal([]byte(`{"value":"-0.5","currency":"EUR"}`), &amount))
+	require.Equal(t, New(-50, util.EUR), amount)
+
+	// an unknown currency is rendered in minor units
+	data, err = json.Marshal(New(1234, "XYZ"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"value":"1234","currency":"XYZ"}`, string(data))
+
+	err = json.Unmarshal([]byte(`{"value":"12.345","currency":"USD"}`), &amount)
+	require.ErrorIs(t, err, ErrInvalidAmount)
+
+	// a float would lose precision, so the value must be a string
+	err = json.Unmarshal([]byte(`{"value":12.34,"currency":"USD"}`), &amount)
+	require.Error(t, err)
+}
@@ -0,0 +1,112 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Event is a domain event read from the outbox
+type Event struct {
+	ID            int64           `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+func newEvent(row db.Outbox) Event {
+	return Event{
+		ID:            row.ID,
+		Type:          row.EventType,
+		AggregateType: row.AggregateType,
+		AggregateID:   row.AggregateID,
+		Payload:       row.Payload,
+		CreatedAt:     row.CreatedAt,
+	}
+}
+
+// Publisher delivers events to their consumers. An event may be delivered
+// more than once, so consumers should deduplicate by Event.ID.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// supported publisher types for NewPublisher
+const (
+	PublisherLog = "log"
+)
+
+// NewPublisher creates the Publisher configured by publisherType
+func NewPublisher(publisherType string, logger *slog.Logger) (Publisher, error) {
+	switch publisherType {
+	case PublisherLog, "":
+		return NewLogPublisher(logger), nil
+	default:
+		return nil, fmt.Errorf("unsupported outbox publisher: %q", publisherType)
+	}
+}
+
+// MultiPublisher delivers each event to every publisher in turn and stops
+// at the first that fails. Since a failed event is delivered again, the
+// publishers before it may see it more than once.
+type MultiPublisher []Publisher
+
+func (publishers MultiPublisher) Publish(ctx context.Context, event Event) error {
+	for _, publisher := range publishers {
+		err := publisher.Publish(ctx, event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LogPublisher writes events to a logger, for deployments without a broker
+type LogPublisher struct {
+	logger *slog.Logger
+}
+
+func NewLogPublisher(logger *slog.Logger) *LogPublisher {
+	return &LogPublisher{logger: logger}
+}
+
+func (publisher *LogPublisher) Publish(ctx context.Context, event Event) error {
+	publisher.logger.InfoContext(ctx, "outbox event",
+		"event_id", event.ID,
+		"event_type", event.Type,
+		"aggregate_type", event.AggregateType,
+		"aggregate_id", event.AggregateID,
+		"payload", string(event.Payload),
+	)
+	return nil
+}
+
+// MemoryPublisher keeps the events it is given, in order
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (publisher *MemoryPublisher) Publish(ctx context.Context, event Event) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+	publisher.events = append(publisher.events, event)
+	return nil
+}
+
+// Events returns the events published so far
+func (publisher *MemoryPublisher) Events() []Event {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+	return append([]Event(nil), publisher.events...)
+}
@@ -0,0 +1,72 @@
+package outbox
+
+import (
+	"context"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"log/slog"
+	"time"
+)
+
+// publishLease bounds how long the relay may take to publish a batch before
+// another relay may claim its events again
+const publishLease = time.Minute
+
+// Relay delivers the events written to the outbox to a Publisher, in the
+// order of their ids, and marks them as sent
+type Relay struct {
+	store     db.Store
+	publisher Publisher
+	interval  time.Duration
+	batchSize int32
+	logger    *slog.Logger
+}
+
+// NewRelay creates a Relay that polls the outbox every interval and locks
+// at most batchSize events at a time
+func NewRelay(store db.Store, publisher Publisher, interval time.Duration, batchSize int32, logger *slog.Logger) *Relay {
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		interval:  interval,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// RelayPending delivers batches of events until none are left, returning
+// how many were sent. It stops at the first event that fails to deliver,
+// which is retried by the next call.
+func (relay *Relay) RelayPending(ctx context.Context) (int, error) {
+	sent := 0
+	for {
+		result, err := relay.store.RelayOutboxTx(ctx, db.RelayOutboxTxParams{
+			Limit: relay.batchSize,
+			Lease: publishLease,
+			Publish: func(ctx context.Context, row db.Outbox) error {
+				return relay.publisher.Publish(ctx, newEvent(row))
+			},
+		})
+		sent += result.Sent
+		if err != nil || !result.Pending {
+			return sent, err
+		}
+	}
+}
+
+// Run relays events until ctx is done
+func (relay *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(relay.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			_, err := relay.RelayPending(ctx)
+			if err != nil && ctx.Err() == nil {
+				relay.logger.Warn("cannot relay outbox events", "error", err)
+			}
+		}
+	}
+}
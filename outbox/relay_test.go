@@ -0,0 +1,175 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// relayBatches fakes RelayOutboxTx over the given batches of unsent events,
+// one batch per call, with the same delivery rules as the SqlStore
+func relayBatches(batches ...[]db.Outbox) func(context.Context, db.RelayOutboxTxParams) (db.RelayOutboxTxResult, error) {
+	call := 0
+	return func(ctx context.Context, arg db.RelayOutboxTxParams) (db.RelayOutboxTxResult, error) {
+		events := batches[call]
+		call++
+
+		result := db.RelayOutboxTxResult{Pending: len(events) == int(arg.Limit)}
+		for _, event := range events {
+			if err := arg.Publish(ctx, event); err != nil {
+				result.Pending = true
+				return result, err
+			}
+			result.Sent++
+		}
+		return result, nil
+	}
+}
+
+func outboxRow(id int64, eventType string) db.Outbox {
+	return db.Outbox{
+		ID:            id,
+		EventType:     eventType,
+		AggregateType: db.AggregateAccount,
+		AggregateID:   "1",
+		Payload:       []byte(`{"id":1}`),
+		CreatedAt:     time.Now(),
+	}
+}
+
+func eventIDs(events []Event) []int64 {
+	ids := make([]int64, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	return ids
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestRelayPending(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().RelayOutboxTx(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(relayBatches(
+			[]db.Outbox{outboxRow(1, db.EventAccountCreated), outboxRow(2, db.EventAccountFrozen)},
+			[]db.Outbox{outboxRow(3, db.EventAccountUnfrozen)},
+		))
+
+	publisher := NewMemoryPublisher()
+	relay := NewRelay(store, publisher, time.Second, 2, discardLogger())
+
+	sent, err := relay.RelayPending(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, sent)
+
+	events := publisher.Events()
+	require.Equal(t, []int64{1, 2, 3}, eventIDs(events))
+	require.Equal(t, db.EventAccountCreated, events[0].Type)
+	require.Equal(t, db.AggregateAccount, events[0].AggregateType)
+	require.JSONEq(t, `{"id":1}`, string(events[0].Payload))
+}
+
+// failingPublisher fails to deliver one event
+type failingPublisher struct {
+	*MemoryPublisher
+	failID int64
+}
+
+var errBrokerDown = errors.New("broker down")
+
+func (publisher failingPublisher) Publish(ctx context.Context, event Event) error {
+	if event.ID == publisher.failID {
+		return errBrokerDown
+	}
+	return publisher.MemoryPublisher.Publish(ctx, event)
+}
+
+func TestRelayPendingStopsAtFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().RelayOutboxTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(relayBatches(
+			[]db.Outbox{outboxRow(1, db.EventAccountCreated), outboxRow(2, db.EventAccountCreated), outboxRow(3, db.EventAccountCreated)},
+		))
+
+	publisher := failingPublisher{MemoryPublisher: NewMemoryPublisher(), failID: 2}
+	relay := NewRelay(store, publisher, time.Second, 10, discardLogger())
+
+	// event 3 must wait for event 2, so it is not delivered either
+	sent, err := relay.RelayPending(context.Background())
+	require.ErrorIs(t, err, errBrokerDown)
+	require.Equal(t, 1, sent)
+	require.Equal(t, []int64{1}, eventIDs(publisher.Events()))
+}
+
+func TestRelayRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	publisher := NewMemoryPublisher()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().RelayOutboxTx(gomock.Any(), gomock.Any()).
+		MinTimes(1).
+		DoAndReturn(func(ctx context.Context, arg db.RelayOutboxTxParams) (db.RelayOutboxTxResult, error) {
+			defer cancel()
+			if len(publisher.Events()) > 0 {
+				return db.RelayOutboxTxResult{}, nil
+			}
+			return relayBatches([]db.Outbox{outboxRow(1, db.EventTransferCompleted)})(ctx, arg)
+		})
+
+	relay := NewRelay(store, publisher, time.Millisecond, 10, discardLogger())
+
+	done := make(chan error, 1)
+	go func() { done <- relay.Run(ctx) }()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("relay did not stop after its context was canceled")
+	}
+	require.Equal(t, []int64{1}, eventIDs(publisher.Events()))
+}
+
+func TestNewPublisher(t *testing.T) {
+	publisher, err := NewPublisher(PublisherLog, discardLogger())
+	require.NoError(t, err)
+	require.IsType(t, &LogPublisher{}, publisher)
+	require.NoError(t, publisher.Publish(context.Background(), newEvent(outboxRow(1, db.EventAccountCreated))))
+
+	_, err = NewPublisher("kafka", discardLogger())
+	require.Error(t, err)
+}
+
+func TestMultiPublisher(t *testing.T) {
+	first := NewMemoryPublisher()
+	second := NewMemoryPublisher()
+	publisher := MultiPublisher{first, failingPublisher{MemoryPublisher: second, failID: 2}}
+
+	require.NoError(t, publisher.Publish(context.Background(), newEvent(outboxRow(1, db.EventAccountCreated))))
+	require.ErrorIs(t, publisher.Publish(context.Background(), newEvent(outboxRow(2, db.EventAccountCreated))), errBrokerDown)
+
+	require.Equal(t, []int64{1, 2}, eventIDs(first.Events()))
+	require.Equal(t, []int64{1}, eventIDs(second.Events()))
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket refilled at Rate tokens per second that holds at
+// most Burst tokens. The zero Limit does not limit anything.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Unlimited reports whether the limit lets every request through
+func (limit Limit) Unlimited() bool {
+	return limit.Rate <= 0 || limit.Burst <= 0
+}
+
+// Result describes the outcome of taking a token from a bucket
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket
+	Limit int
+	// Remaining is the number of whole tokens left after this request
+	Remaining int
+	// RetryAfter is how long to wait for the next token when not allowed
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+// take refills a bucket holding tokens as of updatedAt up to now and takes
+// one token from it if it can. It returns the new number of tokens.
+func (limit Limit) take(tokens float64, updatedAt time.Time, now time.Time) (float64, Result) {
+	elapsed := max(now.Sub(updatedAt).Seconds(), 0)
+	tokens = min(float64(limit.Burst), tokens+elapsed*limit.Rate)
+
+	result := Result{Limit: limit.Burst}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = seconds((float64(limit.Burst) - tokens) / limit.Rate)
+	return tokens, result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+var limitUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+// ParseLimit parses a limit written as "<requests>/<s|m|h>", optionally
+// followed by ":<burst>". The burst defaults to the number of requests, so
+// "60/m" allows 60 requests at once and then one per second.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Limit{}, nil
+	}
+
+	spec, burstSpec, hasBurst := strings.Cut(s, ":")
+	countSpec, unitSpec, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<s|m|h>", s)
+	}
+
+	count, err := strconv.Atoi(countSpec)
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: request count must be a positive integer", s)
+	}
+	unit, ok := limitUnits[unitSpec]
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: unit must be s, m or h", s)
+	}
+
+	limit := Limit{
+		Rate:  float64(count) / unit.Seconds(),
+		Burst: count,
+	}
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(burstSpec)
+		if err != nil || limit.Burst <= 0 {
+			return Limit{}, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", s)
+		}
+	}
+	return limit, nil
+}
+
+// ParseRouteLimits parses a comma separated list of "<METHOD> <path>=<limit>"
+// entries, such as "POST /transfers=30/m,POST /accounts=10/m:5", into limits
+// keyed by "<METHOD> <path>"
+func ParseRouteLimits(s string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid route rate limit %q: expected <METHOD> <path>=<limit>", entry)
+		}
+		method, path, ok := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("invalid route rate limit %q: expected <METHOD> <path>=<limit>", entry)
+		}
+
+		limit, err := ParseLimit(spec)
+		if err != nil {
+			return nil, err
+		}
+		limits[RouteKey(strings.ToUpper(method), strings.TrimSpace(path))] = limit
+	}
+	return limits, nil
+}
+
+// RouteKey identifies a route in the map returned by ParseRouteLimits
+func RouteKey(method, path string) string {
+	return method + " " + path
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	testCases := []struct {
+		spec  string
+		limit Limit
+		ok    bool
+	}{
+		{"60/m", Limit{Rate: 1, Burst: 60}, true},
+		{"10/s:20", Limit{Rate: 10, Burst: 20}, true},
+		{" 3600/h ", Limit{Rate: 1, Burst: 3600}, true},
+		{"", Limit{}, true},
+		{"60", Limit{}, false},
+		{"0/m", Limit{}, false},
+		{"ten/m", Limit{}, false},
+		{"60/d", Limit{}, false},
+		{"60/m:0", Limit{}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			limit, err := ParseLimit(tc.spec)
+			if !tc.ok {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.limit, limit)
+		})
+	}
+}
+
+func TestParseRouteLimits(t *testing.T) {
+	limits, err := ParseRouteLimits("POST /transfers=60/m:20, post /accounts=10/s,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]Limit{
+		"POST /transfers": {Rate: 1, Burst: 20},
+		"POST /accounts":  {Rate: 10, Burst: 10},
+	}, limits)
+
+	limits, err = ParseRouteLimits("")
+	require.NoError(t, err)
+	require.Empty(t, limits)
+
+	_, err = ParseRouteLimits("/transfers=60/m")
+	require.Error(t, err)
+	_, err = ParseRouteLimits("POST /transfers")
+	require.Error(t, err)
+	_, err = ParseRouteLimits("POST /transfers=lots")
+	require.Error(t, err)
+}
+
+func TestLimitTake(t *testing.T) {
+	limit := Limit{Rate: 1, Burst: 2}
+	now := time.Now()
+
+	tokens, result := limit.take(2, now, now)
+	require.True(t, result.Allowed)
+	require.Equal(t, 1, result.Remaining)
+	require.Equal(t, 2, result.Limit)
+	require.Equal(t, time.Second, result.Reset)
+
+	tokens, result = limit.take(tokens, now, now)
+	require.True(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+	require.Equal(t, 2*time.Second, result.Reset)
+
+	// an empty bucket refuses and says when the next token arrives
+	tokens, result = limit.take(tokens, now, now.Add(250*time.Millisecond))
+	require.False(t, result.Allowed)
+	require.Equal(t, 750*time.Millisecond, result.RetryAfter)
+	require.InDelta(t, 0.25, tokens, 1e-9)
+
+	// refused requests do not consume the partial token
+	tokens, result = limit.take(tokens, now.Add(250*time.Millisecond), now.Add(time.Second))
+	require.True(t, result.Allowed)
+	require.InDelta(t, 0, tokens, 1e-9)
+
+	// the bucket never holds more than the burst
+	_, result = limit.take(tokens, now, now.Add(time.Hour))
+	require.True(t, result.Allowed)
+	require.Equal(t, 1, result.Remaining)
+}
+
+func TestLimitUnlimited(t *testing.T) {
+	require.True(t, Limit{}.Unlimited())
+	require.False(t, Limit{Rate: 1, Burst: 1}.Unlimited())
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often a store drops buckets that have refilled
+const pruneInterval = time.Minute
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	fullAt    time.Time
+}
+
+// MemoryStore keeps token buckets in memory, so its limits apply to a
+// single instance
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*memoryBucket),
+		lastPrune: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (store *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := store.now()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.prune(now)
+
+	bucket, ok := store.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(limit.Burst), updatedAt: now}
+		store.buckets[key] = bucket
+	}
+
+	tokens, result := limit.take(bucket.tokens, bucket.updatedAt, now)
+	bucket.tokens = tokens
+	bucket.updatedAt = now
+	bucket.fullAt = now.Add(result.Reset)
+	return result, nil
+}
+
+// prune drops buckets that are full again, since a missing bucket is
+// treated as full. It must be called with mu held.
+func (store *MemoryStore) prune(now time.Time) {
+	if now.Sub(store.lastPrune) < pruneInterval {
+		return
+	}
+	store.lastPrune = now
+
+	for key, bucket := range store.buckets {
+		if !bucket.fullAt.After(now) {
+			delete(store.buckets, key)
+		}
+	}
+}
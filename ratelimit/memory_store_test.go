@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	limit := Limit{Rate: 1, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		result, err := store.Take(context.Background(), "alice", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+
+	result, err := store.Take(context.Background(), "alice", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, time.Second, result.RetryAfter)
+
+	// buckets are per key
+	result, err = store.Take(context.Background(), "bob", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	now = now.Add(time.Second)
+	result, err = store.Take(context.Background(), "alice", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+}
+
+func TestMemoryStorePrune(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	_, err := store.Take(context.Background(), "fast", Limit{Rate: 1, Burst: 1})
+	require.NoError(t, err)
+	_, err = store.Take(context.Background(), "slow", Limit{Rate: 1.0 / 3600, Burst: 1})
+	require.NoError(t, err)
+	require.Len(t, store.buckets, 2)
+
+	// after the prune interval only the bucket that refilled is dropped
+	now = now.Add(pruneInterval)
+	_, err = store.Take(context.Background(), "other", Limit{Rate: 1, Burst: 1})
+	require.NoError(t, err)
+	require.Contains(t, store.buckets, "slow")
+	require.NotContains(t, store.buckets, "fast")
+}
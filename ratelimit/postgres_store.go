@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// PostgresStore keeps token buckets in the rate_limit_buckets table, so its
+// limits are shared by every instance using the same database
+type PostgresStore struct {
+	store     db.Store
+	logger    *slog.Logger
+	mu        sync.Mutex
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewPostgresStore creates a PostgresStore that keeps its buckets through store
+func NewPostgresStore(store db.Store) *PostgresStore {
+	return &PostgresStore{
+		store:     store,
+		logger:    slog.Default(),
+		lastPrune: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (store *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := store.now()
+	store.prune(ctx, now)
+
+	var result Result
+	_, err := store.store.UpdateRateLimitBucketTx(ctx, db.UpdateRateLimitBucketTxParams{
+		Key: key,
+		Initial: db.RateLimitBucket{
+			Tokens:    float64(limit.Burst),
+			UpdatedAt: now,
+			FullAt:    now,
+		},
+		Update: func(bucket db.RateLimitBucket) db.RateLimitBucket {
+			bucket.Tokens, result = limit.take(bucket.Tokens, bucket.UpdatedAt, now)
+			bucket.UpdatedAt = now
+			bucket.FullAt = now.Add(result.Reset)
+			return bucket
+		},
+	})
+	return result, err
+}
+
+// prune deletes buckets that are full again, since a missing bucket is
+// treated as full. At most one caller per interval runs the delete.
+func (store *PostgresStore) prune(ctx context.Context, now time.Time) {
+	store.mu.Lock()
+	if now.Sub(store.lastPrune) < pruneInterval {
+		store.mu.Unlock()
+		return
+	}
+	store.lastPrune = now
+	store.mu.Unlock()
+
+	err := store.store.DeleteFullRateLimitBuckets(ctx, now)
+	if err != nil {
+		store.logger.WarnContext(ctx, "cannot prune rate limit buckets", "error", err)
+	}
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestPostgresStore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mockdb.NewMockStore(ctrl)
+	store := NewPostgresStore(mockStore)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	limit := Limit{Rate: 1, Burst: 2}
+
+	// the bucket in the database is empty as of half a second ago
+	mockStore.EXPECT().UpdateRateLimitBucketTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.UpdateRateLimitBucketTxParams) (db.RateLimitBucket, error) {
+			require.Equal(t, "alice", arg.Key)
+			require.Equal(t, float64(2), arg.Initial.Tokens)
+
+			bucket := arg.Update(db.RateLimitBucket{Key: arg.Key, Tokens: 0, UpdatedAt: now.Add(-500 * time.Millisecond)})
+			require.InDelta(t, 0.5, bucket.Tokens, 1e-9)
+			require.Equal(t, now, bucket.UpdatedAt)
+			require.Equal(t, now.Add(1500*time.Millisecond), bucket.FullAt)
+			return bucket, nil
+		})
+
+	result, err := store.Take(context.Background(), "alice", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	mockStore.EXPECT().UpdateRateLimitBucketTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.RateLimitBucket{}, errors.New("connection refused"))
+
+	_, err = store.Take(context.Background(), "alice", limit)
+	require.Error(t, err)
+}
+
+func TestPostgresStorePrune(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStore := mockdb.NewMockStore(ctrl)
+	store := NewPostgresStore(mockStore)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	mockStore.EXPECT().UpdateRateLimitBucketTx(gomock.Any(), gomock.Any()).
+		Times(3).
+		Return(db.RateLimitBucket{}, nil)
+
+	_, err := store.Take(context.Background(), "alice", Limit{Rate: 1, Burst: 1})
+	require.NoError(t, err)
+
+	// full buckets are deleted once per interval
+	now = now.Add(pruneInterval)
+	mockStore.EXPECT().DeleteFullRateLimitBuckets(gomock.Any(), gomock.Eq(now)).
+		Times(1).
+		Return(nil)
+
+	_, err = store.Take(context.Background(), "alice", Limit{Rate: 1, Burst: 1})
+	require.NoError(t, err)
+	_, err = store.Take(context.Background(), "alice", Limit{Rate: 1, Burst: 1})
+	require.NoError(t, err)
+}
+
+func TestNewStore(t *testing.T) {
+	store, err := NewStore(StoreMemory, nil)
+	require.NoError(t, err)
+	require.IsType(t, &MemoryStore{}, store)
+
+	store, err = NewStore(StorePostgres, nil)
+	require.NoError(t, err)
+	require.IsType(t, &PostgresStore{}, store)
+
+	_, err = NewStore("redis", nil)
+	require.Error(t, err)
+}
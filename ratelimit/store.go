@@ -0,0 +1,33 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+)
+
+// Store keeps the token buckets of a rate limiter
+type Store interface {
+	// Take takes a token from the bucket identified by key, creating a full
+	// bucket sized by limit if there is none
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// supported store types for NewStore
+const (
+	StoreMemory   = "memory"
+	StorePostgres = "postgres"
+)
+
+// NewStore creates the Store configured by storeType. The Postgres store
+// keeps its buckets through store, so limits are shared by every instance.
+func NewStore(storeType string, store db.Store) (Store, error) {
+	switch storeType {
+	case StoreMemory, "":
+		return NewMemoryStore(), nil
+	case StorePostgres:
+		return NewPostgresStore(store), nil
+	default:
+		return nil, fmt.Errorf("unsupported rate limit store: %q", storeType)
+	}
+}
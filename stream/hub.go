@@ -0,0 +1,109 @@
+package stream
+
+import (
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"sync"
+)
+
+// subscriptionBuffer is how many notifications a subscriber may fall behind
+// before it is told to resync instead
+const subscriptionBuffer = 16
+
+// Hub fans account notifications out to the subscribers of each account
+type Hub struct {
+	mu   sync.Mutex
+	subs map[int64]map[*Subscription]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[int64]map[*Subscription]struct{})}
+}
+
+// Subscription receives the notifications of one account until it is closed
+type Subscription struct {
+	hub       *Hub
+	accountID int64
+	events    chan db.AccountNotification
+	resync    chan struct{}
+}
+
+// Subscribe starts receiving the notifications of an account
+func (hub *Hub) Subscribe(accountID int64) *Subscription {
+	sub := &Subscription{
+		hub:       hub,
+		accountID: accountID,
+		events:    make(chan db.AccountNotification, subscriptionBuffer),
+		resync:    make(chan struct{}, 1),
+	}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if hub.subs[accountID] == nil {
+		hub.subs[accountID] = make(map[*Subscription]struct{})
+	}
+	hub.subs[accountID][sub] = struct{}{}
+	return sub
+}
+
+// Publish passes a notification to the account's subscribers without
+// blocking. A subscriber whose buffer is full misses it and is told to
+// resync.
+func (hub *Hub) Publish(notification db.AccountNotification) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for sub := range hub.subs[notification.AccountID] {
+		select {
+		case sub.events <- notification:
+		default:
+			sub.signalResync()
+		}
+	}
+}
+
+// Resync tells every subscriber that notifications may have been lost, such
+// as while the listener reconnected
+func (hub *Hub) Resync() {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for _, subs := range hub.subs {
+		for sub := range subs {
+			sub.signalResync()
+		}
+	}
+}
+
+// Subscribers returns how many subscriptions are open for an account
+func (hub *Hub) Subscribers(accountID int64) int {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	return len(hub.subs[accountID])
+}
+
+// Events delivers the account's notifications in commit order
+func (sub *Subscription) Events() <-chan db.AccountNotification {
+	return sub.events
+}
+
+// Resync receives when notifications were missed, after which the
+// subscriber should reload the account's state
+func (sub *Subscription) Resync() <-chan struct{} {
+	return sub.resync
+}
+
+func (sub *Subscription) signalResync() {
+	select {
+	case sub.resync <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops the subscription
+func (sub *Subscription) Close() {
+	hub := sub.hub
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	delete(hub.subs[sub.accountID], sub)
+	if len(hub.subs[sub.accountID]) == 0 {
+		delete(hub.subs, sub.accountID)
+	}
+}
@@ -0,0 +1,97 @@
+package stream
+
+import (
+	"encoding/json"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func requireResync(t *testing.T, sub *Subscription, expected bool) {
+	select {
+	case <-sub.Resync():
+		require.True(t, expected, "unexpected resync")
+	default:
+		require.False(t, expected, "expected a resync")
+	}
+}
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub()
+	sub1 := hub.Subscribe(1)
+	sub2 := hub.Subscribe(1)
+	other := hub.Subscribe(2)
+	require.Equal(t, 2, hub.Subscribers(1))
+
+	notification := db.AccountNotification{AccountID: 1, Balance: 90, Currency: "USD"}
+	hub.Publish(notification)
+
+	require.Equal(t, notification, <-sub1.Events())
+	require.Equal(t, notification, <-sub2.Events())
+	require.Empty(t, other.Events())
+
+	sub1.Close()
+	hub.Publish(notification)
+	require.Empty(t, sub1.Events())
+	require.Equal(t, notification, <-sub2.Events())
+
+	sub2.Close()
+	other.Close()
+	require.Zero(t, hub.Subscribers(1))
+	require.Empty(t, hub.subs)
+}
+
+func TestHubPublishToSlowSubscriber(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe(1)
+	defer sub.Close()
+
+	for i := 0; i < subscriptionBuffer; i++ {
+		hub.Publish(db.AccountNotification{AccountID: 1, Balance: int64(i)})
+	}
+	requireResync(t, sub, false)
+
+	// a full buffer never blocks the publisher
+	hub.Publish(db.AccountNotification{AccountID: 1, Balance: -1})
+	hub.Publish(db.AccountNotification{AccountID: 1, Balance: -2})
+	requireResync(t, sub, true)
+	requireResync(t, sub, false)
+	require.Len(t, sub.Events(), subscriptionBuffer)
+}
+
+func TestHubResync(t *testing.T) {
+	hub := NewHub()
+	sub1 := hub.Subscribe(1)
+	sub2 := hub.Subscribe(2)
+
+	hub.Resync()
+	requireResync(t, sub1, true)
+	requireResync(t, sub2, true)
+}
+
+func TestListenerHandle(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe(1)
+	defer sub.Close()
+	listener := NewListener("", hub, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	entry := db.Entry{ID: 7, AccountID: 1, Amount: -10}
+	payload, err := json.Marshal(db.AccountNotification{AccountID: 1, Balance: 90, Currency: "USD", Entry: &entry})
+	require.NoError(t, err)
+
+	listener.handle(&pq.Notification{Channel: db.AccountEventsChannel, Extra: string(payload)})
+	notification := <-sub.Events()
+	require.Equal(t, int64(90), notification.Balance)
+	require.Equal(t, entry.ID, notification.Entry.ID)
+
+	listener.handle(&pq.Notification{Channel: db.AccountEventsChannel, Extra: "not json"})
+	require.Empty(t, sub.Events())
+	requireResync(t, sub, false)
+
+	// the connection was re-established
+	listener.handle(nil)
+	requireResync(t, sub, true)
+}
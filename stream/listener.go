@@ -0,0 +1,87 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/lib/pq"
+	"log/slog"
+	"time"
+)
+
+// pingInterval is how often an idle listener checks its connection
+const pingInterval = 90 * time.Second
+
+// Listener LISTENs on db.AccountEventsChannel and publishes what it hears to
+// a Hub. Every instance runs one, so a change committed through any instance
+// reaches the streams open on all of them.
+type Listener struct {
+	dbSource string
+	hub      *Hub
+	logger   *slog.Logger
+}
+
+func NewListener(dbSource string, hub *Hub, logger *slog.Logger) *Listener {
+	return &Listener{
+		dbSource: dbSource,
+		hub:      hub,
+		logger:   logger,
+	}
+}
+
+// Run listens until ctx is done, reconnecting whenever the connection is lost
+func (listener *Listener) Run(ctx context.Context) error {
+	conn := pq.NewListener(listener.dbSource, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			listener.logger.Warn("account event listener", "event", event, "error", err)
+		}
+	})
+	defer conn.Close()
+
+	// Listen blocks until it has a connection, which closing the listener
+	// interrupts
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	err := conn.Listen(db.AccountEventsChannel)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification, ok := <-conn.Notify:
+			if !ok {
+				return nil
+			}
+			listener.handle(notification)
+		case <-ticker.C:
+			go conn.Ping()
+		}
+	}
+}
+
+// handle publishes a notification. A nil notification means the connection
+// was re-established, and whatever was sent meanwhile is lost.
+func (listener *Listener) handle(notification *pq.Notification) {
+	if notification == nil {
+		listener.hub.Resync()
+		return
+	}
+
+	var event db.AccountNotification
+	err := json.Unmarshal([]byte(notification.Extra), &event)
+	if err != nil {
+		listener.logger.Warn("cannot decode account notification", "payload", notification.Extra, "error", err)
+		return
+	}
+	listener.hub.Publish(event)
+}
@@ -0,0 +1,76 @@
+// Package telemetry configures OpenTelemetry tracing for the servers
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"github.com/julkar-naim/simple-bank/util"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"io"
+	"os"
+	"strings"
+)
+
+// ServiceName identifies this service in exported traces
+const ServiceName = "simple-bank"
+
+// Trace exporters
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// SetupTracing installs the global tracer provider and W3C trace context
+// propagator for the exporter named in config.TraceExporter. The returned
+// function flushes and stops the provider.
+func SetupTracing(ctx context.Context, config util.Config) (shutdown func(context.Context) error, err error) {
+	exporter, err := newExporter(ctx, config, os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	provider := NewTracerProvider(exporter)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewTracerProvider batches spans to the exporter and tags them with the service name
+func NewTracerProvider(exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(ServiceName))),
+	)
+}
+
+func newExporter(ctx context.Context, config util.Config, stdout io.Writer) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(config.TraceExporter) {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(stdout))
+	case ExporterOTLP:
+		options := []otlptracegrpc.Option{otlptracegrpc.WithInsecure()}
+		if config.OTLPEndpoint != "" {
+			options = append(options, otlptracegrpc.WithEndpoint(config.OTLPEndpoint))
+		}
+		return otlptracegrpc.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter %q", config.TraceExporter)
+	}
+}
@@ -0,0 +1,44 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewExporter(t *testing.T) {
+	var buf bytes.Buffer
+
+	exporter, err := newExporter(context.Background(), util.Config{}, &buf)
+	require.NoError(t, err)
+	require.Nil(t, exporter)
+
+	exporter, err = newExporter(context.Background(), util.Config{TraceExporter: ExporterNone}, &buf)
+	require.NoError(t, err)
+	require.Nil(t, exporter)
+
+	exporter, err = newExporter(context.Background(), util.Config{TraceExporter: ExporterOTLP, OTLPEndpoint: "localhost:4317"}, &buf)
+	require.NoError(t, err)
+	require.NotNil(t, exporter)
+	require.NoError(t, exporter.Shutdown(context.Background()))
+
+	_, err = newExporter(context.Background(), util.Config{TraceExporter: "zipkin"}, &buf)
+	require.Error(t, err)
+}
+
+func TestStdoutExporter(t *testing.T) {
+	var buf bytes.Buffer
+
+	exporter, err := newExporter(context.Background(), util.Config{TraceExporter: ExporterStdout}, &buf)
+	require.NoError(t, err)
+
+	provider := NewTracerProvider(exporter)
+	_, span := provider.Tracer("test").Start(context.Background(), "test-span")
+	span.End()
+	require.NoError(t, provider.Shutdown(context.Background()))
+
+	require.Contains(t, buf.String(), "test-span")
+	require.Contains(t, buf.String(), ServiceName)
+}
@@ -0,0 +1,36 @@
+package token
+
+import (
+	"fmt"
+	"time"
+)
+
+// Maker is an interface for managing tokens
+type Maker interface {
+	// CreateToken creates a new token for a specific username, role, type and duration
+	CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error)
+
+	// VerifyToken checks if the token is valid and of the expected type
+	VerifyToken(token string, tokenType TokenType) (*Payload, error)
+}
+
+// supported token types for NewMaker
+const (
+	TypeJWT      = "jwt"
+	TypePasetoV2 = "paseto_v2"
+	TypePasetoV4 = "paseto_v4"
+)
+
+// NewMaker creates the Maker configured by tokenType
+func NewMaker(tokenType string, symmetricKey string) (Maker, error) {
+	switch tokenType {
+	case TypeJWT:
+		return NewJWTMaker(symmetricKey)
+	case TypePasetoV2:
+		return NewPasetoV2Maker(symmetricKey)
+	case TypePasetoV4, "":
+		return NewPasetoV4Maker(symmetricKey)
+	default:
+		return nil, fmt.Errorf("unsupported token type: %q", tokenType)
+	}
+}
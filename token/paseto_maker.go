@@ -0,0 +1,138 @@
+package token
+
+import (
+	"aidanwoods.dev/go-paseto"
+	"fmt"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/chacha20poly1305"
+	"time"
+)
+
+// PasetoMaker is a PASETO token maker using symmetric (local) encryption
+type PasetoMaker struct {
+	encrypt func(token paseto.Token) string
+	decrypt func(token string) (*paseto.Token, error)
+}
+
+// NewPasetoV2Maker creates a new PasetoMaker issuing v2.local tokens
+func NewPasetoV2Maker(symmetricKey string) (Maker, error) {
+	if len(symmetricKey) != chacha20poly1305.KeySize {
+		return nil, fmt.Errorf("invalid key size: must be exactly %d characters", chacha20poly1305.KeySize)
+	}
+
+	key, err := paseto.V2SymmetricKeyFromBytes([]byte(symmetricKey))
+	if err != nil {
+		return nil, err
+	}
+
+	parser := paseto.NewParserWithoutExpiryCheck()
+	maker := &PasetoMaker{
+		encrypt: func(token paseto.Token) string {
+			return token.V2Encrypt(key)
+		},
+		decrypt: func(token string) (*paseto.Token, error) {
+			return parser.ParseV2Local(key, token)
+		},
+	}
+	return maker, nil
+}
+
+// NewPasetoV4Maker creates a new PasetoMaker issuing v4.local tokens
+func NewPasetoV4Maker(symmetricKey string) (Maker, error) {
+	if len(symmetricKey) != chacha20poly1305.KeySize {
+		return nil, fmt.Errorf("invalid key size: must be exactly %d characters", chacha20poly1305.KeySize)
+	}
+
+	key, err := paseto.V4SymmetricKeyFromBytes([]byte(symmetricKey))
+	if err != nil {
+		return nil, err
+	}
+
+	parser := paseto.NewParserWithoutExpiryCheck()
+	maker := &PasetoMaker{
+		encrypt: func(token paseto.Token) string {
+			return token.V4Encrypt(key, nil)
+		},
+		decrypt: func(token string) (*paseto.Token, error) {
+			return parser.ParseV4Local(key, token, nil)
+		},
+	}
+	return maker, nil
+}
+
+// CreateToken creates a new token for a specific username, role, type and duration
+func (maker *PasetoMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, tokenType, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	token := paseto.NewToken()
+	token.SetJti(payload.ID.String())
+	token.SetSubject(payload.Username)
+	token.SetString("role", payload.Role)
+	token.SetString("token_type", string(payload.Type))
+	token.SetIssuedAt(payload.IssuedAt)
+	token.SetExpiration(payload.ExpiredAt)
+
+	return maker.encrypt(token), payload, nil
+}
+
+// VerifyToken checks if the token is valid and of the expected type
+func (maker *PasetoMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
+	parsed, err := maker.decrypt(token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := payloadFromPaseto(parsed)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if err := payload.validFor(tokenType); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+func payloadFromPaseto(token *paseto.Token) (*Payload, error) {
+	jti, err := token.GetJti()
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(jti)
+	if err != nil {
+		return nil, err
+	}
+	username, err := token.GetSubject()
+	if err != nil {
+		return nil, err
+	}
+	role, err := token.GetString("role")
+	if err != nil {
+		return nil, err
+	}
+	tokenType, err := token.GetString("token_type")
+	if err != nil {
+		return nil, err
+	}
+	issuedAt, err := token.GetIssuedAt()
+	if err != nil {
+		return nil, err
+	}
+	expiredAt, err := token.GetExpiration()
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &Payload{
+		ID:        id,
+		Username:  username,
+		Role:      role,
+		Type:      TokenType(tokenType),
+		IssuedAt:  issuedAt,
+		ExpiredAt: expiredAt,
+	}
+	return payload, nil
+}
@@ -0,0 +1,139 @@
+package token
+
+import (
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var pasetoMakers = map[string]func(symmetricKey string) (Maker, error){
+	"V2": NewPasetoV2Maker,
+	"V4": NewPasetoV4Maker,
+}
+
+func TestPasetoMaker(t *testing.T) {
+	for name, newMaker := range pasetoMakers {
+		t.Run(name, func(t *testing.T) {
+			maker, err := newMaker(util.RandomString(32))
+			require.NoError(t, err)
+
+			username := util.RandomOwner()
+			role := util.OperatorRole
+			duration := time.Minute
+
+			issuedAt := time.Now()
+			expiredAt := issuedAt.Add(duration)
+
+			token, payload, err := maker.CreateToken(username, role, TokenTypeAccessToken, duration)
+			require.NoError(t, err)
+			require.NotEmpty(t, token)
+			require.NotEmpty(t, payload)
+
+			payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
+			require.NoError(t, err)
+			require.NotEmpty(t, payload)
+
+			require.NotZero(t, payload.ID)
+			require.Equal(t, username, payload.Username)
+			require.Equal(t, role, payload.Role)
+			require.Equal(t, TokenTypeAccessToken, payload.Type)
+			require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+			require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+		})
+	}
+}
+
+func TestExpiredPasetoToken(t *testing.T) {
+	for name, newMaker := range pasetoMakers {
+		t.Run(name, func(t *testing.T) {
+			maker, err := newMaker(util.RandomString(32))
+			require.NoError(t, err)
+
+			token, payload, err := maker.CreateToken(util.RandomOwner(), util.CustomerRole, TokenTypeAccessToken, -time.Minute)
+			require.NoError(t, err)
+			require.NotEmpty(t, token)
+			require.NotEmpty(t, payload)
+
+			payload, err = maker.VerifyToken(token, TokenTypeAccessToken)
+			require.Error(t, err)
+			require.EqualError(t, err, ErrExpiredToken.Error())
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestInvalidPasetoToken(t *testing.T) {
+	for name, newMaker := range pasetoMakers {
+		t.Run(name, func(t *testing.T) {
+			maker, err := newMaker(util.RandomString(32))
+			require.NoError(t, err)
+
+			otherMaker, err := newMaker(util.RandomString(32))
+			require.NoError(t, err)
+
+			// a token encrypted with a different key must be rejected
+			token, _, err := otherMaker.CreateToken(util.RandomOwner(), util.CustomerRole, TokenTypeAccessToken, time.Minute)
+			require.NoError(t, err)
+
+			payload, err := maker.VerifyToken(token, TokenTypeAccessToken)
+			require.Error(t, err)
+			require.EqualError(t, err, ErrInvalidToken.Error())
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestPasetoTokenTypeMismatch(t *testing.T) {
+	for name, newMaker := range pasetoMakers {
+		t.Run(name, func(t *testing.T) {
+			maker, err := newMaker(util.RandomString(32))
+			require.NoError(t, err)
+
+			token, _, err := maker.CreateToken(util.RandomOwner(), util.CustomerRole, TokenTypeRefreshToken, time.Minute)
+			require.NoError(t, err)
+
+			payload, err := maker.VerifyToken(token, TokenTypeAccessToken)
+			require.EqualError(t, err, ErrInvalidToken.Error())
+			require.Nil(t, payload)
+
+			payload, err = maker.VerifyToken(token, TokenTypeRefreshToken)
+			require.NoError(t, err)
+			require.Equal(t, TokenTypeRefreshToken, payload.Type)
+		})
+	}
+}
+
+func TestPasetoVersionMismatch(t *testing.T) {
+	key := util.RandomString(32)
+
+	v2Maker, err := NewPasetoV2Maker(key)
+	require.NoError(t, err)
+	v4Maker, err := NewPasetoV4Maker(key)
+	require.NoError(t, err)
+
+	token, _, err := v2Maker.CreateToken(util.RandomOwner(), util.CustomerRole, TokenTypeAccessToken, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := v4Maker.VerifyToken(token, TokenTypeAccessToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestNewMaker(t *testing.T) {
+	key := util.RandomString(32)
+
+	for _, tokenType := range []string{TypeJWT, TypePasetoV2, TypePasetoV4} {
+		maker, err := NewMaker(tokenType, key)
+		require.NoError(t, err)
+		require.NotNil(t, maker)
+	}
+
+	maker, err := NewMaker("unknown", key)
+	require.Error(t, err)
+	require.Nil(t, maker)
+
+	maker, err = NewMaker(TypePasetoV4, util.RandomString(16))
+	require.Error(t, err)
+	require.Nil(t, maker)
+}
@@ -4,12 +4,45 @@ import (
 	"github.com/spf13/viper"
 	"path/filepath"
 	"runtime"
+	"time"
 )
 
 type Config struct {
-	DBDriver string `mapstructure:"DB_DRIVER"`
-	DBSource string `mapstructure:"DB_SOURCE"`
-	Address  string `mapstructure:"ADDRESS"`
+	DBDriver             string        `mapstructure:"DB_DRIVER"`
+	DBSource             string        `mapstructure:"DB_SOURCE"`
+	Address              string        `mapstructure:"ADDRESS"`
+	GRPCAddress          string        `mapstructure:"GRPC_ADDRESS"`
+	TokenType            string        `mapstructure:"TOKEN_TYPE"`
+	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	APIKeyEncryptionKey  string        `mapstructure:"API_KEY_ENCRYPTION_KEY"`
+	WebhookEncryptionKey string        `mapstructure:"WEBHOOK_ENCRYPTION_KEY"`
+	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	APISignatureMaxSkew  time.Duration `mapstructure:"API_SIGNATURE_MAX_SKEW"`
+	LogLevel             string        `mapstructure:"LOG_LEVEL"`
+	LogFormat            string        `mapstructure:"LOG_FORMAT"`
+	RequestTimeout       time.Duration `mapstructure:"REQUEST_TIMEOUT"`
+	TransferTimeout      time.Duration `mapstructure:"TRANSFER_TIMEOUT"`
+	ReportTimeout        time.Duration `mapstructure:"REPORT_TIMEOUT"`
+	ShutdownTimeout      time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	ShutdownDrainDelay   time.Duration `mapstructure:"SHUTDOWN_DRAIN_DELAY"`
+	RateLimitStore       string        `mapstructure:"RATE_LIMIT_STORE"`
+	RateLimitDefault     string        `mapstructure:"RATE_LIMIT_DEFAULT"`
+	RateLimitRoutes      string        `mapstructure:"RATE_LIMIT_ROUTES"`
+	TrustedProxies       string        `mapstructure:"TRUSTED_PROXIES"`
+	CurrencyRefresh      time.Duration `mapstructure:"CURRENCY_REFRESH_INTERVAL"`
+	OutboxPublisher      string        `mapstructure:"OUTBOX_PUBLISHER"`
+	OutboxRelayInterval  time.Duration `mapstructure:"OUTBOX_RELAY_INTERVAL"`
+	OutboxBatchSize      int32         `mapstructure:"OUTBOX_BATCH_SIZE"`
+	WebhookInterval      time.Duration `mapstructure:"WEBHOOK_DELIVERY_INTERVAL"`
+	WebhookBatchSize     int32         `mapstructure:"WEBHOOK_BATCH_SIZE"`
+	WebhookTimeout       time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	WebhookMaxAttempts   int32         `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookBackoffBase   time.Duration `mapstructure:"WEBHOOK_BACKOFF_BASE"`
+	WebhookBackoffMax    time.Duration `mapstructure:"WEBHOOK_BACKOFF_MAX"`
+	StreamHeartbeat      time.Duration `mapstructure:"STREAM_HEARTBEAT_INTERVAL"`
+	TraceExporter        string        `mapstructure:"TRACE_EXPORTER"`
+	OTLPEndpoint         string        `mapstructure:"OTLP_ENDPOINT"`
 }
 
 var AppConfig Config
@@ -0,0 +1,81 @@
+package util
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Currencies seeded by the currencies migration. They are supported until
+// the registry is first loaded from the database.
+const (
+	USD = "USD"
+	EUR = "EUR"
+	CAD = "CAD"
+)
+
+// Currency is an entry of the currency registry
+type Currency struct {
+	// Code is the ISO 4217 code
+	Code string `json:"code"`
+	// Exponent is the number of minor units, 2 for cents
+	Exponent int32  `json:"exponent"`
+	Symbol   string `json:"symbol"`
+	// Enabled currencies are accepted for new accounts and transfers;
+	// disabled ones are only kept to format existing balances
+	Enabled bool `json:"-"`
+}
+
+var currencyRegistry = struct {
+	sync.RWMutex
+	currencies map[string]Currency
+}{
+	currencies: map[string]Currency{
+		USD: {Code: USD, Exponent: 2, Symbol: "$", Enabled: true},
+		EUR: {Code: EUR, Exponent: 2, Symbol: "€", Enabled: true},
+		CAD: {Code: CAD, Exponent: 2, Symbol: "CA$", Enabled: true},
+	},
+}
+
+// SetCurrencies replaces the currencies of the registry
+func SetCurrencies(currencies []Currency) {
+	registry := make(map[string]Currency, len(currencies))
+	for _, currency := range currencies {
+		registry[currency.Code] = currency
+	}
+
+	currencyRegistry.Lock()
+	defer currencyRegistry.Unlock()
+	currencyRegistry.currencies = registry
+}
+
+// LookupCurrency returns the currency with the given code, enabled or not
+func LookupCurrency(code string) (Currency, bool) {
+	currencyRegistry.RLock()
+	defer currencyRegistry.RUnlock()
+	currency, ok := currencyRegistry.currencies[code]
+	return currency, ok
+}
+
+// SupportedCurrencies returns the enabled currencies ordered by code
+func SupportedCurrencies() []Currency {
+	currencyRegistry.RLock()
+	defer currencyRegistry.RUnlock()
+
+	currencies := make([]Currency, 0, len(currencyRegistry.currencies))
+	for _, currency := range currencyRegistry.currencies {
+		if currency.Enabled {
+			currencies = append(currencies, currency)
+		}
+	}
+	slices.SortFunc(currencies, func(a, b Currency) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+	return currencies
+}
+
+// IsSupportedCurrency returns true if the currency is enabled
+func IsSupportedCurrency(currency string) bool {
+	c, ok := LookupCurrency(currency)
+	return ok && c.Enabled
+}
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestIsSupportedCurrency(t *testing.T) {
+	require.True(t, IsSupportedCurrency(USD))
+	require.True(t, IsSupportedCurrency(EUR))
+	require.True(t, IsSupportedCurrency(CAD))
+	require.False(t, IsSupportedCurrency("BDT"))
+	require.False(t, IsSupportedCurrency(""))
+}
+
+func TestSetCurrencies(t *testing.T) {
+	defaults := SupportedCurrencies()
+	t.Cleanup(func() { SetCurrencies(defaults) })
+
+	SetCurrencies([]Currency{
+		{Code: "JPY", Exponent: 0, Symbol: "¥", Enabled: true},
+		{Code: USD, Exponent: 2, Symbol: "$", Enabled: true},
+		{Code: EUR, Exponent: 2, Symbol: "€", Enabled: false},
+	})
+
+	require.True(t, IsSupportedCurrency("JPY"))
+	require.True(t, IsSupportedCurrency(USD))
+	require.False(t, IsSupportedCurrency(EUR))
+	require.False(t, IsSupportedCurrency(CAD))
+
+	// a disabled currency can still be looked up to format old balances
+	_, ok := LookupCurrency(EUR)
+	require.True(t, ok)
+	_, ok = LookupCurrency(CAD)
+	require.False(t, ok)
+
+	currency, ok := LookupCurrency("JPY")
+	require.True(t, ok)
+	require.Equal(t, int32(0), currency.Exponent)
+	require.Equal(t, "¥", currency.Symbol)
+
+	currencies := SupportedCurrencies()
+	require.Len(t, currencies, 2)
+	require.Equal(t, "JPY", currencies[0].Code)
+	require.Equal(t, USD, currencies[1].Code)
+}
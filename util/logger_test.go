@@ -0,0 +1,46 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, "info", LogFormatJSON)
+	require.NoError(t, err)
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	logger.DebugContext(ctx, "hidden")
+	logger.InfoContext(ctx, "shown", "key", "value")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "shown", record["msg"])
+	require.Equal(t, "value", record["key"])
+	require.Equal(t, "req-1", record["request_id"])
+
+	buf.Reset()
+	logger.With("component", "test").Info("no request")
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.NotContains(t, buf.String(), "request_id")
+
+	buf.Reset()
+	logger, err = NewLogger(&buf, "debug", LogFormatText)
+	require.NoError(t, err)
+	logger.DebugContext(ctx, "shown")
+	require.Contains(t, buf.String(), "request_id=req-1")
+
+	_, err = NewLogger(&buf, "loud", LogFormatJSON)
+	require.Error(t, err)
+	_, err = NewLogger(&buf, "info", "xml")
+	require.Error(t, err)
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	require.Empty(t, RequestIDFromContext(context.Background()))
+	require.Equal(t, "abc", RequestIDFromContext(WithRequestID(context.Background(), "abc")))
+}
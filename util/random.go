@@ -1,6 +1,7 @@
 package util
 
 import (
+	"fmt"
 	"math/rand"
 )
 
@@ -27,8 +28,12 @@ func RandomMoney() int64 {
 	return RandomInt(1000)
 }
 
+// RandomCurrency returns the code of a random enabled currency
 func RandomCurrency() string {
-	currencies := []string{"USD", "EUR", "CAD"}
-	n := len(currencies)
-	return currencies[rand.Intn(n)]
+	currencies := SupportedCurrencies()
+	return currencies[rand.Intn(len(currencies))].Code
+}
+
+func RandomEmail() string {
+	return fmt.Sprintf("%s@email.com", RandomString(6))
 }
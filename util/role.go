@@ -0,0 +1,90 @@
+package util
+
+// Roles a user can hold
+const (
+	CustomerRole = "customer"
+	OperatorRole = "operator"
+	AdminRole    = "admin"
+)
+
+// Permission names a privileged operation guarded by role
+type Permission string
+
+const (
+	PermListAllAccounts  Permission = "accounts:list_all"
+	PermAdjustAccounts   Permission = "accounts:adjust"
+	PermFreezeAccounts   Permission = "accounts:freeze"
+	PermReconcile        Permission = "ledger:reconcile"
+	PermManageRoles      Permission = "users:manage_roles"
+	PermManageAPIKeys    Permission = "api_keys:manage"
+	PermManageCurrencies Permission = "currencies:manage"
+	PermManageWebhooks   Permission = "webhooks:manage"
+	PermReadAuditLog     Permission = "audit_log:read"
+)
+
+// rolePermissions lists what each role may do on top of managing its own accounts
+var rolePermissions = map[string][]Permission{
+	CustomerRole: {},
+	OperatorRole: {
+		PermListAllAccounts,
+		PermFreezeAccounts,
+		PermReconcile,
+	},
+	AdminRole: {
+		PermListAllAccounts,
+		PermAdjustAccounts,
+		PermFreezeAccounts,
+		PermReconcile,
+		PermManageRoles,
+		PermManageAPIKeys,
+		PermManageCurrencies,
+		PermManageWebhooks,
+		PermReadAuditLog,
+	},
+}
+
+// serviceScopes lists the permissions an API key may be granted. Managing
+// roles, API keys and webhooks, and reading the audit log, stays with human
+// admins.
+var serviceScopes = []Permission{
+	PermListAllAccounts,
+	PermAdjustAccounts,
+	PermFreezeAccounts,
+	PermReconcile,
+}
+
+// IsSupportedRole returns true if the role is known
+func IsSupportedRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission returns true if the role grants the permission
+func HasPermission(role string, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSupportedScope returns true if the scope can be granted to an API key
+func IsSupportedScope(scope string) bool {
+	for _, p := range serviceScopes {
+		if string(p) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope returns true if the scopes include the permission
+func HasScope(scopes []string, permission Permission) bool {
+	for _, scope := range scopes {
+		if scope == string(permission) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package util
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestHasPermission(t *testing.T) {
+	require.False(t, HasPermission(CustomerRole, PermListAllAccounts))
+	require.False(t, HasPermission("unknown", PermListAllAccounts))
+
+	require.True(t, HasPermission(OperatorRole, PermListAllAccounts))
+	require.True(t, HasPermission(OperatorRole, PermFreezeAccounts))
+	require.False(t, HasPermission(OperatorRole, PermAdjustAccounts))
+	require.False(t, HasPermission(OperatorRole, PermManageRoles))
+
+	for _, permission := range rolePermissions[OperatorRole] {
+		require.True(t, HasPermission(AdminRole, permission))
+	}
+	require.True(t, HasPermission(AdminRole, PermAdjustAccounts))
+	require.True(t, HasPermission(AdminRole, PermManageRoles))
+	require.True(t, HasPermission(AdminRole, PermManageAPIKeys))
+	require.False(t, HasPermission(OperatorRole, PermManageAPIKeys))
+	require.True(t, HasPermission(AdminRole, PermManageCurrencies))
+	require.False(t, HasPermission(OperatorRole, PermManageCurrencies))
+	require.True(t, HasPermission(AdminRole, PermManageWebhooks))
+	require.False(t, HasPermission(OperatorRole, PermManageWebhooks))
+	require.True(t, HasPermission(AdminRole, PermReadAuditLog))
+	require.False(t, HasPermission(OperatorRole, PermReadAuditLog))
+}
+
+func TestIsSupportedRole(t *testing.T) {
+	require.True(t, IsSupportedRole(CustomerRole))
+	require.True(t, IsSupportedRole(OperatorRole))
+	require.True(t, IsSupportedRole(AdminRole))
+	require.False(t, IsSupportedRole("root"))
+}
+
+func TestIsSupportedScope(t *testing.T) {
+	require.True(t, IsSupportedScope(string(PermListAllAccounts)))
+	require.True(t, IsSupportedScope(string(PermReconcile)))
+	require.False(t, IsSupportedScope(string(PermManageRoles)))
+	require.False(t, IsSupportedScope(string(PermManageAPIKeys)))
+	require.False(t, IsSupportedScope(string(PermManageCurrencies)))
+	require.False(t, IsSupportedScope(string(PermManageWebhooks)))
+	require.False(t, IsSupportedScope(string(PermReadAuditLog)))
+	require.False(t, IsSupportedScope("unknown"))
+}
+
+func TestHasScope(t *testing.T) {
+	scopes := []string{string(PermFreezeAccounts)}
+	require.True(t, HasScope(scopes, PermFreezeAccounts))
+	require.False(t, HasScope(scopes, PermReconcile))
+	require.False(t, HasScope(nil, PermFreezeAccounts))
+}
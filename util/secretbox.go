@@ -0,0 +1,62 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// SecretBox encrypts secrets the server has to read back, such as API key
+// signing secrets, with AES-256-GCM under a key held in the server's config
+// and never in the database
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox creates a SecretBox from a 32 byte key
+func NewSecretBox(key string) (*SecretBox, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid key size: must be exactly 32 characters")
+	}
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// Seal encrypts plaintext and returns the base64 of the nonce and
+// ciphertext. label is authenticated but not encrypted, and must be passed
+// to Open again, so that a sealed secret cannot be moved to another record.
+func (box *SecretBox) Seal(plaintext, label string) (string, error) {
+	nonce := make([]byte, box.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %w", err)
+	}
+	sealed := box.aead.Seal(nonce, nonce, []byte(plaintext), []byte(label))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a secret sealed under the same label
+func (box *SecretBox) Open(sealed, label string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < box.aead.NonceSize() {
+		return "", errors.New("sealed secret is too short")
+	}
+	nonce, ciphertext := data[:box.aead.NonceSize()], data[box.aead.NonceSize():]
+	plaintext, err := box.aead.Open(nil, nonce, ciphertext, []byte(label))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
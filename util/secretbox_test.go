@@ -0,0 +1,43 @@
+package util
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSecretBox(t *testing.T) {
+	box, err := NewSecretBox(RandomString(32))
+	require.NoError(t, err)
+
+	secret := RandomString(64)
+	sealed, err := box.Seal(secret, "key1")
+	require.NoError(t, err)
+	require.NotContains(t, sealed, secret)
+
+	opened, err := box.Open(sealed, "key1")
+	require.NoError(t, err)
+	require.Equal(t, secret, opened)
+
+	// sealing twice gives different ciphertexts
+	sealed2, err := box.Seal(secret, "key1")
+	require.NoError(t, err)
+	require.NotEqual(t, sealed, sealed2)
+
+	_, err = box.Open(sealed, "key2")
+	require.Error(t, err)
+
+	otherBox, err := NewSecretBox(RandomString(32))
+	require.NoError(t, err)
+	_, err = otherBox.Open(sealed, "key1")
+	require.Error(t, err)
+
+	_, err = box.Open("not base64!", "key1")
+	require.Error(t, err)
+	_, err = box.Open("", "key1")
+	require.Error(t, err)
+}
+
+func TestNewSecretBoxKeySize(t *testing.T) {
+	_, err := NewSecretBox(RandomString(16))
+	require.Error(t, err)
+}
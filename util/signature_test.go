@@ -0,0 +1,32 @@
+package util
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRandomToken(t *testing.T) {
+	token1, err := RandomToken(16)
+	require.NoError(t, err)
+	require.Len(t, token1, 32)
+
+	token2, err := RandomToken(16)
+	require.NoError(t, err)
+	require.NotEqual(t, token1, token2)
+}
+
+func TestSignRequest(t *testing.T) {
+	key := RandomString(32)
+	body := []byte(`{"amount":10}`)
+
+	signature := SignRequest(key, "post", "/admin/accounts/1/freeze", "1700000000", "abc", body)
+	require.Len(t, signature, 64)
+	require.True(t, CheckSignature(key, signature, "POST", "/admin/accounts/1/freeze", "1700000000", "abc", body))
+
+	require.False(t, CheckSignature("other", signature, "POST", "/admin/accounts/1/freeze", "1700000000", "abc", body))
+	require.False(t, CheckSignature(key, signature, "GET", "/admin/accounts/1/freeze", "1700000000", "abc", body))
+	require.False(t, CheckSignature(key, signature, "POST", "/admin/accounts/2/freeze", "1700000000", "abc", body))
+	require.False(t, CheckSignature(key, signature, "POST", "/admin/accounts/1/freeze", "1700000001", "abc", body))
+	require.False(t, CheckSignature(key, signature, "POST", "/admin/accounts/1/freeze", "1700000000", "abd", body))
+	require.False(t, CheckSignature(key, signature, "POST", "/admin/accounts/1/freeze", "1700000000", "abc", []byte(`{"amount":11}`)))
+}
@@ -0,0 +1,214 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/util"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy decides when a failed delivery is attempted again
+type RetryPolicy struct {
+	// MaxAttempts is how many attempts a delivery gets before it is dead
+	MaxAttempts int32
+	// BaseDelay is the wait after the first failure; it doubles after each
+	// further failure up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Delay returns how long to wait after the given failed attempt, counting
+// from 1
+func (policy RetryPolicy) Delay(attempt int32) time.Duration {
+	delay := policy.BaseDelay
+	for i := int32(1); i < attempt && delay < policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, policy.MaxDelay)
+}
+
+// maxErrorBody bounds how much of a failed response is kept as its error
+const maxErrorBody = 256
+
+// Deliverer POSTs queued webhook deliveries to their endpoints, retrying
+// failures with exponential backoff until they succeed or run out of
+// attempts. Every attempt is logged and stored.
+type Deliverer struct {
+	store     db.Store
+	secretBox *util.SecretBox
+	client    *http.Client
+	timeout   time.Duration
+	retry     RetryPolicy
+	interval  time.Duration
+	batchSize int32
+	logger    *slog.Logger
+}
+
+// NewDeliverer creates a Deliverer that polls for due deliveries every
+// interval, claims at most batchSize at a time and waits at most timeout for
+// each endpoint. secretBox decrypts the webhooks' secrets.
+func NewDeliverer(store db.Store, secretBox *util.SecretBox, timeout time.Duration, retry RetryPolicy, interval time.Duration, batchSize int32, logger *slog.Logger) *Deliverer {
+	return &Deliverer{
+		store:     store,
+		secretBox: secretBox,
+		client: &http.Client{
+			Timeout: timeout,
+			// a redirect is a failed delivery, not a new target
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		timeout:   timeout,
+		retry:     retry,
+		interval:  interval,
+		batchSize: batchSize,
+		logger:    logger,
+	}
+}
+
+// DeliverDue attempts batches of due deliveries until none are left,
+// returning how many attempts were made. Deliveries are claimed for twice
+// the timeout, so another Deliverer only picks one up again if this one
+// stopped before recording its attempt.
+func (deliverer *Deliverer) DeliverDue(ctx context.Context) (int, error) {
+	attempted := 0
+	for {
+		deliveries, err := deliverer.store.ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{
+			LeaseSeconds: (2 * deliverer.timeout).Seconds(),
+			Limit:        deliverer.batchSize,
+		})
+		if err != nil {
+			return attempted, err
+		}
+
+		// one slow endpoint must not hold up the rest of the batch past its
+		// claim, so deliveries are attempted concurrently
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				deliverer.attempt(ctx, delivery)
+			}()
+		}
+		wg.Wait()
+
+		attempted += len(deliveries)
+		if len(deliveries) < int(deliverer.batchSize) {
+			return attempted, nil
+		}
+	}
+}
+
+// attempt sends one delivery and records the outcome
+func (deliverer *Deliverer) attempt(ctx context.Context, delivery db.WebhookDelivery) {
+	webhook, err := deliverer.store.GetWebhook(ctx, delivery.WebhookID)
+	if err != nil {
+		deliverer.logger.Warn("cannot load webhook", "webhook_id", delivery.WebhookID, "error", err)
+		return
+	}
+
+	start := time.Now()
+	statusCode, sendErr := deliverer.send(ctx, webhook, delivery)
+	duration := time.Since(start)
+
+	arg := db.RecordWebhookAttemptTxParams{
+		Delivery:   delivery,
+		StatusCode: int32(statusCode),
+		DurationMs: duration.Milliseconds(),
+		Status:     db.DeliverySucceeded,
+	}
+	if sendErr != nil {
+		arg.Error = sendErr.Error()
+		attempt := delivery.Attempts + 1
+		if attempt >= deliverer.retry.MaxAttempts {
+			arg.Status = db.DeliveryDead
+		} else {
+			arg.Status = db.DeliveryPending
+			arg.RetrySeconds = deliverer.retry.Delay(attempt).Seconds()
+		}
+	}
+
+	deliverer.logger.Info("webhook attempt",
+		"delivery_id", delivery.ID,
+		"webhook_id", webhook.ID,
+		"event_id", delivery.EventID,
+		"event_type", delivery.EventType,
+		"attempt", delivery.Attempts+1,
+		"status_code", statusCode,
+		"duration", duration,
+		"status", arg.Status,
+		"error", arg.Error,
+	)
+
+	_, err = deliverer.store.RecordWebhookAttemptTx(ctx, arg)
+	if err != nil {
+		deliverer.logger.Warn("cannot record webhook attempt", "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+// send POSTs the delivery's payload, signed with the webhook's secret. It
+// returns the response status, or 0 if there was no response, and an error
+// unless the status is 2xx.
+func (deliverer *Deliverer) send(ctx context.Context, webhook db.Webhook, delivery db.WebhookDelivery) (int, error) {
+	secret, err := deliverer.secretBox.Open(webhook.SecretCiphertext, db.WebhookSecretLabel(webhook.Url))
+	if err != nil {
+		return 0, fmt.Errorf("cannot decrypt webhook secret: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "simple-bank-webhooks")
+	req.Header.Set(HeaderEventID, strconv.FormatInt(delivery.EventID, 10))
+	req.Header.Set(HeaderEventType, delivery.EventType)
+	req.Header.Set(HeaderDeliveryID, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(secret, now, delivery.Payload))
+
+	rsp, err := deliverer.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode >= 200 && rsp.StatusCode < 300 {
+		return rsp.StatusCode, nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(rsp.Body, maxErrorBody))
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return rsp.StatusCode, fmt.Errorf("unexpected status %d", rsp.StatusCode)
+	}
+	return rsp.StatusCode, fmt.Errorf("unexpected status %d: %s", rsp.StatusCode, body)
+}
+
+// Run delivers webhooks until ctx is done
+func (deliverer *Deliverer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(deliverer.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			_, err := deliverer.DeliverDue(ctx)
+			if err != nil && ctx.Err() == nil {
+				deliverer.logger.Warn("cannot deliver webhooks", "error", err)
+			}
+		}
+	}
+}
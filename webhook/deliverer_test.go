@@ -0,0 +1,362 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// testSecretBox seals the secrets of the webhooks of every test
+var testSecretBox, _ = util.NewSecretBox(util.RandomString(32))
+
+// randomWebhook creates a webhook delivering to url whose secret is sealed
+// in testSecretBox
+func randomWebhook(t *testing.T, url string, secret string) db.Webhook {
+	secretCiphertext, err := testSecretBox.Seal(secret, db.WebhookSecretLabel(url))
+	require.NoError(t, err)
+
+	return db.Webhook{
+		ID:               util.RandomInt(1000) + 1,
+		Url:              url,
+		SecretCiphertext: secretCiphertext,
+		EventTypes:       []string{db.EventTransferCompleted},
+		IsActive:         true,
+	}
+}
+
+func randomDelivery(webhook db.Webhook, attempts int32) db.WebhookDelivery {
+	return db.WebhookDelivery{
+		ID:        util.RandomInt(1000) + 1,
+		WebhookID: webhook.ID,
+		EventID:   util.RandomInt(1000) + 1,
+		EventType: db.EventTransferCompleted,
+		Payload:   json.RawMessage(`{"id":1,"type":"TransferCompleted"}`),
+		Status:    db.DeliveryPending,
+		Attempts:  attempts,
+	}
+}
+
+func TestDeliverDue(t *testing.T) {
+	testCases := []struct {
+		name          string
+		attempts      int32
+		handler       func(t *testing.T, secret string) http.HandlerFunc
+		checkResponse func(t *testing.T, arg db.RecordWebhookAttemptTxParams)
+	}{
+		{
+			"OK",
+			0,
+			func(t *testing.T, secret string) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					body, err := io.ReadAll(r.Body)
+					require.NoError(t, err)
+					require.Equal(t, http.MethodPost, r.Method)
+					require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+					require.Equal(t, db.EventTransferCompleted, r.Header.Get(HeaderEventType))
+					require.NotEmpty(t, r.Header.Get(HeaderEventID))
+					require.NotEmpty(t, r.Header.Get(HeaderDeliveryID))
+					require.JSONEq(t, `{"id":1,"type":"TransferCompleted"}`, string(body))
+
+					err = Verify(secret, r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), body, time.Minute, time.Now())
+					require.NoError(t, err)
+					w.WriteHeader(http.StatusNoContent)
+				}
+			},
+			func(t *testing.T, arg db.RecordWebhookAttemptTxParams) {
+				require.Equal(t, db.DeliverySucceeded, arg.Status)
+				require.Equal(t, int32(http.StatusNoContent), arg.StatusCode)
+				require.Empty(t, arg.Error)
+				require.Zero(t, arg.RetrySeconds)
+			},
+		},
+		{
+			"ServerError",
+			0,
+			func(t *testing.T, secret string) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					http.Error(w, "try later", http.StatusInternalServerError)
+				}
+			},
+			func(t *testing.T, arg db.RecordWebhookAttemptTxParams) {
+				require.Equal(t, db.DeliveryPending, arg.Status)
+				require.Equal(t, int32(http.StatusInternalServerError), arg.StatusCode)
+				require.Equal(t, "unexpected status 500: try later", arg.Error)
+				require.Equal(t, time.Minute.Seconds(), arg.RetrySeconds)
+			},
+		},
+		{
+			"BackoffGrows",
+			1,
+			func(t *testing.T, secret string) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			},
+			func(t *testing.T, arg db.RecordWebhookAttemptTxParams) {
+				require.Equal(t, db.DeliveryPending, arg.Status)
+				require.Equal(t, "unexpected status 503", arg.Error)
+				require.Equal(t, (2 * time.Minute).Seconds(), arg.RetrySeconds)
+			},
+		},
+		{
+			"LastAttemptIsDead",
+			2,
+			func(t *testing.T, secret string) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusBadRequest)
+				}
+			},
+			func(t *testing.T, arg db.RecordWebhookAttemptTxParams) {
+				require.Equal(t, db.DeliveryDead, arg.Status)
+				require.Equal(t, int32(http.StatusBadRequest), arg.StatusCode)
+				require.Zero(t, arg.RetrySeconds)
+			},
+		},
+		{
+			"RedirectNotFollowed",
+			0,
+			func(t *testing.T, secret string) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					http.Redirect(w, r, "/elsewhere", http.StatusFound)
+				}
+			},
+			func(t *testing.T, arg db.RecordWebhookAttemptTxParams) {
+				require.Equal(t, db.DeliveryPending, arg.Status)
+				require.Equal(t, int32(http.StatusFound), arg.StatusCode)
+			},
+		},
+		{
+			"Timeout",
+			0,
+			func(t *testing.T, secret string) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					select {
+					case <-time.After(time.Second):
+					case <-r.Context().Done():
+					}
+				}
+			},
+			func(t *testing.T, arg db.RecordWebhookAttemptTxParams) {
+				require.Equal(t, db.DeliveryPending, arg.Status)
+				require.Zero(t, arg.StatusCode)
+				require.NotEmpty(t, arg.Error)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			secret := util.RandomString(32)
+			server := httptest.NewServer(tc.handler(t, secret))
+			defer server.Close()
+			webhook := randomWebhook(t, server.URL, secret)
+			delivery := randomDelivery(webhook, tc.attempts)
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().ClaimWebhookDeliveries(gomock.Any(), gomock.Eq(db.ClaimWebhookDeliveriesParams{
+				LeaseSeconds: (200 * time.Millisecond).Seconds(),
+				Limit:        10,
+			})).
+				Times(1).
+				Return([]db.WebhookDelivery{delivery}, nil)
+			store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).
+				Times(1).
+				Return(webhook, nil)
+			store.EXPECT().RecordWebhookAttemptTx(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(ctx context.Context, arg db.RecordWebhookAttemptTxParams) (db.RecordWebhookAttemptTxResult, error) {
+					require.Equal(t, delivery, arg.Delivery)
+					tc.checkResponse(t, arg)
+					return db.RecordWebhookAttemptTxResult{}, nil
+				})
+
+			deliverer := NewDeliverer(store, testSecretBox, 100*time.Millisecond, testRetryPolicy, time.Second, 10, discardLogger())
+			attempted, err := deliverer.DeliverDue(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 1, attempted)
+		})
+	}
+}
+
+func TestDeliverDueConnectionRefused(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	webhook := randomWebhook(t, server.URL, util.RandomString(32))
+	server.Close()
+	delivery := randomDelivery(webhook, 0)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.WebhookDelivery{delivery}, nil)
+	store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).
+		Times(1).
+		Return(webhook, nil)
+	store.EXPECT().RecordWebhookAttemptTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.RecordWebhookAttemptTxParams) (db.RecordWebhookAttemptTxResult, error) {
+			require.Equal(t, db.DeliveryPending, arg.Status)
+			require.Zero(t, arg.StatusCode)
+			require.Contains(t, arg.Error, "connection refused")
+			return db.RecordWebhookAttemptTxResult{}, nil
+		})
+
+	deliverer := NewDeliverer(store, testSecretBox, time.Second, testRetryPolicy, time.Second, 10, discardLogger())
+	_, err := deliverer.DeliverDue(context.Background())
+	require.NoError(t, err)
+}
+
+func TestDeliverDueMovedSecret(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("delivery sent with a secret sealed for another url")
+	}))
+	defer server.Close()
+
+	// a ciphertext copied from a webhook with another url does not open
+	webhook := randomWebhook(t, "https://partner.example/hooks", util.RandomString(32))
+	webhook.Url = server.URL
+	delivery := randomDelivery(webhook, 0)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.WebhookDelivery{delivery}, nil)
+	store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).
+		Times(1).
+		Return(webhook, nil)
+	store.EXPECT().RecordWebhookAttemptTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, arg db.RecordWebhookAttemptTxParams) (db.RecordWebhookAttemptTxResult, error) {
+			require.Equal(t, db.DeliveryPending, arg.Status)
+			require.Zero(t, arg.StatusCode)
+			require.Contains(t, arg.Error, "cannot decrypt webhook secret")
+			return db.RecordWebhookAttemptTxResult{}, nil
+		})
+
+	deliverer := NewDeliverer(store, testSecretBox, time.Second, testRetryPolicy, time.Second, 10, discardLogger())
+	_, err := deliverer.DeliverDue(context.Background())
+	require.NoError(t, err)
+}
+
+func TestDeliverDueRetriedDelivery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	webhook := randomWebhook(t, server.URL, util.RandomString(32))
+
+	// a dead delivery that was retried starts its attempts over, so it is
+	// only dead again after another MaxAttempts failures
+	delivery := randomDelivery(webhook, 0)
+
+	var statuses []string
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(int(testRetryPolicy.MaxAttempts)).
+		DoAndReturn(func(ctx context.Context, arg db.ClaimWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+			return []db.WebhookDelivery{delivery}, nil
+		})
+	store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).
+		Times(int(testRetryPolicy.MaxAttempts)).
+		Return(webhook, nil)
+	store.EXPECT().RecordWebhookAttemptTx(gomock.Any(), gomock.Any()).
+		Times(int(testRetryPolicy.MaxAttempts)).
+		DoAndReturn(func(ctx context.Context, arg db.RecordWebhookAttemptTxParams) (db.RecordWebhookAttemptTxResult, error) {
+			statuses = append(statuses, arg.Status)
+			delivery.Attempts++
+			delivery.Status = arg.Status
+			return db.RecordWebhookAttemptTxResult{}, nil
+		})
+
+	deliverer := NewDeliverer(store, testSecretBox, time.Second, testRetryPolicy, time.Second, 10, discardLogger())
+	for i := int32(0); i < testRetryPolicy.MaxAttempts; i++ {
+		_, err := deliverer.DeliverDue(context.Background())
+		require.NoError(t, err)
+	}
+	require.Equal(t, []string{db.DeliveryPending, db.DeliveryPending, db.DeliveryDead}, statuses)
+}
+
+func TestDeliverDueBatches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	webhook := randomWebhook(t, server.URL, util.RandomString(32))
+
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+			Return([]db.WebhookDelivery{randomDelivery(webhook, 0), randomDelivery(webhook, 0)}, nil),
+		store.EXPECT().ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+			Return([]db.WebhookDelivery{randomDelivery(webhook, 0)}, nil),
+	)
+	store.EXPECT().GetWebhook(gomock.Any(), gomock.Eq(webhook.ID)).
+		Times(3).
+		Return(webhook, nil)
+	store.EXPECT().RecordWebhookAttemptTx(gomock.Any(), gomock.Any()).
+		Times(3).
+		Return(db.RecordWebhookAttemptTxResult{}, nil)
+
+	deliverer := NewDeliverer(store, testSecretBox, time.Second, testRetryPolicy, time.Second, 2, discardLogger())
+	attempted, err := deliverer.DeliverDue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, attempted)
+}
+
+func TestDelivererRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		MinTimes(1).
+		DoAndReturn(func(ctx context.Context, arg db.ClaimWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+			cancel()
+			return []db.WebhookDelivery{}, nil
+		})
+
+	deliverer := NewDeliverer(store, testSecretBox, time.Second, testRetryPolicy, time.Millisecond, 10, discardLogger())
+
+	done := make(chan error, 1)
+	go func() { done <- deliverer.Run(ctx) }()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("deliverer did not stop after its context was canceled")
+	}
+}
@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/outbox"
+)
+
+// Dispatcher is an outbox.Publisher that queues a delivery of each event for
+// every active webhook subscribed to its type. The Deliverer sends them.
+type Dispatcher struct {
+	store db.Store
+}
+
+func NewDispatcher(store db.Store) *Dispatcher {
+	return &Dispatcher{store: store}
+}
+
+// Publish queues the event's deliveries. Queuing an event again does not
+// create more deliveries, so it is safe for the outbox to redeliver it.
+func (dispatcher *Dispatcher) Publish(ctx context.Context, event outbox.Event) error {
+	webhooks, err := dispatcher.store.ListWebhooksForEvent(ctx, event.Type)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range webhooks {
+		err = dispatcher.store.CreateWebhookDelivery(ctx, db.CreateWebhookDeliveryParams{
+			WebhookID: webhook.ID,
+			EventID:   event.ID,
+			EventType: event.Type,
+			Payload:   payload,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
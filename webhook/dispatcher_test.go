@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	mockdb "github.com/julkar-naim/simple-bank/db/mock"
+	db "github.com/julkar-naim/simple-bank/db/sqlc"
+	"github.com/julkar-naim/simple-bank/outbox"
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestDispatcherPublish(t *testing.T) {
+	event := outbox.Event{
+		ID:            7,
+		Type:          db.EventTransferCompleted,
+		AggregateType: db.AggregateTransfer,
+		AggregateID:   "3",
+		Payload:       json.RawMessage(`{"id":3,"amount":10,"currency":"USD"}`),
+		CreatedAt:     time.Now().UTC().Truncate(time.Second),
+	}
+	webhook1 := randomWebhook(t, "https://partner-one.example/hooks", util.RandomString(32))
+	webhook2 := randomWebhook(t, "https://partner-two.example/hooks", util.RandomString(32))
+	errDB := errors.New("db down")
+
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		err        error
+	}{
+		{
+			"OK",
+			func(store *mockdb.MockStore) {
+				store.EXPECT().ListWebhooksForEvent(gomock.Any(), gomock.Eq(db.EventTransferCompleted)).
+					Times(1).
+					Return([]db.Webhook{webhook1, webhook2}, nil)
+				for _, webhook := range []db.Webhook{webhook1, webhook2} {
+					store.EXPECT().CreateWebhookDelivery(gomock.Any(), gomock.Any()).
+						Times(1).
+						DoAndReturn(func(ctx context.Context, arg db.CreateWebhookDeliveryParams) error {
+							require.Equal(t, webhook.ID, arg.WebhookID)
+							require.Equal(t, event.ID, arg.EventID)
+							require.Equal(t, event.Type, arg.EventType)
+
+							var payload outbox.Event
+							require.NoError(t, json.Unmarshal(arg.Payload, &payload))
+							require.Equal(t, event.ID, payload.ID)
+							require.JSONEq(t, string(event.Payload), string(payload.Payload))
+							return nil
+						})
+				}
+			},
+			nil,
+		},
+		{
+			"NoSubscribers",
+			func(store *mockdb.MockStore) {
+				store.EXPECT().ListWebhooksForEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Webhook{}, nil)
+				store.EXPECT().CreateWebhookDelivery(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			nil,
+		},
+		{
+			"ListError",
+			func(store *mockdb.MockStore) {
+				store.EXPECT().ListWebhooksForEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, errDB)
+			},
+			errDB,
+		},
+		{
+			"CreateError",
+			func(store *mockdb.MockStore) {
+				store.EXPECT().ListWebhooksForEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Webhook{webhook1, webhook2}, nil)
+				store.EXPECT().CreateWebhookDelivery(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(errDB)
+			},
+			errDB,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			err := NewDispatcher(store).Publish(context.Background(), event)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// headers sent with every delivery
+const (
+	HeaderEventID    = "Webhook-Event-Id"
+	HeaderEventType  = "Webhook-Event-Type"
+	HeaderDeliveryID = "Webhook-Delivery-Id"
+	HeaderTimestamp  = "Webhook-Timestamp"
+	HeaderSignature  = "Webhook-Signature"
+)
+
+// signatureVersion prefixes the signature so the scheme can change without
+// breaking receivers
+const signatureVersion = "v1="
+
+var (
+	ErrMissingSignature = errors.New("missing webhook signature")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrStaleTimestamp   = errors.New("webhook timestamp outside tolerance")
+)
+
+// Sign returns the signature header for body sent at timestamp: the hex
+// HMAC-SHA256 of "<unix timestamp>.<body>" keyed by the webhook's secret
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return signatureVersion + computeSignature(secret, strconv.FormatInt(timestamp.Unix(), 10), body)
+}
+
+func computeSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the timestamp and signature headers of a delivery the way a
+// receiver should: the signature must match and the timestamp must be
+// within tolerance of now, which limits replays
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration, now time.Time) error {
+	if timestamp == "" || signature == "" {
+		return ErrMissingSignature
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	skew := now.Sub(time.Unix(seconds, 0))
+	if skew > tolerance || skew < -tolerance {
+		return ErrStaleTimestamp
+	}
+
+	expected := computeSignature(secret, timestamp, body)
+	actual := strings.TrimPrefix(signature, signatureVersion)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(actual))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
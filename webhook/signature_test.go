@@ -0,0 +1,58 @@
+package webhook
+
+import (
+	"github.com/julkar-naim/simple-bank/util"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	secret := util.RandomString(32)
+	body := []byte(`{"id":1,"type":"TransferCompleted"}`)
+	now := time.Unix(1700000000, 0)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	signature := Sign(secret, now, body)
+	require.Len(t, signature, len("v1=")+64)
+	require.NoError(t, Verify(secret, timestamp, signature, body, time.Minute, now))
+	require.NoError(t, Verify(secret, timestamp, signature, body, time.Minute, now.Add(59*time.Second)))
+
+	testCases := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      []byte
+		now       time.Time
+		err       error
+	}{
+		{"MissingSignature", secret, timestamp, "", body, now, ErrMissingSignature},
+		{"MissingTimestamp", secret, "", signature, body, now, ErrMissingSignature},
+		{"BadTimestamp", secret, "yesterday", signature, body, now, ErrInvalidSignature},
+		{"WrongSecret", util.RandomString(32), timestamp, signature, body, now, ErrInvalidSignature},
+		{"TamperedBody", secret, timestamp, signature, []byte(`{"id":2,"type":"TransferCompleted"}`), now, ErrInvalidSignature},
+		{"TamperedTimestamp", secret, "1700000001", signature, body, now, ErrInvalidSignature},
+		{"Stale", secret, timestamp, signature, body, now.Add(2 * time.Minute), ErrStaleTimestamp},
+		{"FromTheFuture", secret, timestamp, signature, body, now.Add(-2 * time.Minute), ErrStaleTimestamp},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Verify(tc.secret, tc.timestamp, tc.signature, tc.body, time.Minute, tc.now)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	require.Equal(t, time.Second, policy.Delay(1))
+	require.Equal(t, 2*time.Second, policy.Delay(2))
+	require.Equal(t, 4*time.Second, policy.Delay(3))
+	require.Equal(t, 8*time.Second, policy.Delay(4))
+	require.Equal(t, 10*time.Second, policy.Delay(5))
+	require.Equal(t, 10*time.Second, policy.Delay(64))
+}